@@ -7,24 +7,23 @@ require (
 	github.com/artnikel/ProfileService v0.0.0-20240119122408-1f6e2576bba3
 	github.com/artnikel/TradingService v0.0.0-20240116152142-90ccd9622510
 	github.com/caarlos0/env v3.5.0+incompatible
+	github.com/garyburd/redigo v1.6.4
 	github.com/go-playground/validator/v10 v10.15.0
+	github.com/golang-jwt/jwt v3.2.2+incompatible
 	github.com/google/uuid v1.3.0
 	github.com/labstack/echo/v4 v4.11.1
 	github.com/shopspring/decimal v1.3.1
 	github.com/sirupsen/logrus v1.9.3
 	github.com/stretchr/testify v1.8.4
+	go.etcd.io/bbolt v1.3.9
 	golang.org/x/crypto v0.12.0
+	golang.org/x/sync v0.5.0
 	google.golang.org/grpc v1.57.0
 	google.golang.org/protobuf v1.32.0
-	gopkg.in/boj/redistore.v1 v1.0.0-20160128113310-fc113767cd6b
 )
 
 require (
-	github.com/garyburd/redigo v1.6.4 // indirect
-	github.com/golang-jwt/jwt v3.2.2+incompatible // indirect
-	github.com/gorilla/context v1.1.1 // indirect
-	github.com/gorilla/securecookie v1.1.2 // indirect
-	github.com/gorilla/sessions v1.2.2 // indirect
+	github.com/kr/pretty v0.3.1 // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 )
 
@@ -34,7 +33,6 @@ require (
 	github.com/go-playground/locales v0.14.1 // indirect
 	github.com/go-playground/universal-translator v0.18.1 // indirect
 	github.com/golang/protobuf v1.5.3 // indirect
-	github.com/labstack/echo-contrib v0.15.0
 	github.com/labstack/gommon v0.4.0 // indirect
 	github.com/leodido/go-urn v1.2.4 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
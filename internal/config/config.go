@@ -1,16 +1,78 @@
 // Package config with environment variables
 package config
 
-import "github.com/caarlos0/env"
+import (
+	"time"
+
+	"github.com/caarlos0/env"
+	"github.com/shopspring/decimal"
+)
 
 // Variables is a struct with environment variables
 type Variables struct {
-	HashKey           string `env:"HASH_KEY"`
-	APIPort           int    `env:"API_PORT"`
-	RedisPriceAddress string `env:"REDIS_PRICE_ADDRESS"`
-	TradingAddress    string `env:"TRADING_ADDRESS"`
-	ProfileAddress    string `env:"PROFILE_ADDRESS"`
-	BalanceAddress    string `env:"BALANCE_ADDRESS"`
+	HashKey                        string          `env:"HASH_KEY"`
+	APIPort                        int             `env:"API_PORT"`
+	RedisPriceAddress              string          `env:"REDIS_PRICE_ADDRESS"`
+	TradingAddress                 string          `env:"TRADING_ADDRESS"`
+	ProfileAddress                 string          `env:"PROFILE_ADDRESS"`
+	BalanceAddress                 string          `env:"BALANCE_ADDRESS"`
+	TokenSignKey                   string          `env:"TOKEN_SIGN_KEY"`
+	AccessTokenTTL                 time.Duration   `env:"ACCESS_TOKEN_TTL" envDefault:"15m"`
+	RefreshTokenTTL                time.Duration   `env:"REFRESH_TOKEN_TTL" envDefault:"720h"`
+	SessionStore                   string          `env:"SESSION_STORE" envDefault:"redis"` // redis or memory
+	SessionCookieName              string          `env:"SESSION_COOKIE_NAME" envDefault:"SESSION_ID"`
+	SessionIdleTimeout             time.Duration   `env:"SESSION_IDLE_TIMEOUT" envDefault:"30m"`
+	SessionAbsoluteTimeout         time.Duration   `env:"SESSION_ABSOLUTE_TIMEOUT" envDefault:"12h"`
+	SessionRememberIdleTimeout     time.Duration   `env:"SESSION_REMEMBER_IDLE_TIMEOUT" envDefault:"168h"`
+	SessionRememberAbsoluteTimeout time.Duration   `env:"SESSION_REMEMBER_ABSOLUTE_TIMEOUT" envDefault:"720h"`
+	SessionSecure                  bool            `env:"SESSION_SECURE"`
+	SessionHTTPOnly                bool            `env:"SESSION_HTTP_ONLY" envDefault:"true"`
+	SessionSameSite                string          `env:"SESSION_SAME_SITE" envDefault:"lax"` // lax, strict or none
+	SessionDomain                  string          `env:"SESSION_DOMAIN"`
+	LoginAttemptStore              string          `env:"LOGIN_ATTEMPT_STORE" envDefault:"redis"` // redis or memory
+	LoginMaxAttempts               int             `env:"LOGIN_MAX_ATTEMPTS" envDefault:"5"`
+	LoginIPMaxAttempts             int             `env:"LOGIN_IP_MAX_ATTEMPTS" envDefault:"20"`
+	LoginAttemptWindow             time.Duration   `env:"LOGIN_ATTEMPT_WINDOW" envDefault:"15m"`
+	LoginBackoffBase               time.Duration   `env:"LOGIN_BACKOFF_BASE" envDefault:"1s"`
+	LoginLockoutDuration           time.Duration   `env:"LOGIN_LOCKOUT_DURATION" envDefault:"15m"`
+	TrustedProxies                 []string        `env:"TRUSTED_PROXIES" envSeparator:","` // CIDR ranges, empty means that clients connect directly
+	PriceCacheTTL                  time.Duration   `env:"PRICE_CACHE_TTL" envDefault:"1s"`
+	PriceCacheStaleTTL             time.Duration   `env:"PRICE_CACHE_STALE_TTL" envDefault:"30s"`
+	PriceCacheFetchTimeout         time.Duration   `env:"PRICE_CACHE_FETCH_TIMEOUT" envDefault:"5s"`
+	PriceStreamInterval            time.Duration   `env:"PRICE_STREAM_INTERVAL" envDefault:"1500ms"`
+	PriceStreamHeartbeat           time.Duration   `env:"PRICE_STREAM_HEARTBEAT" envDefault:"15s"`
+	PriceStreamBuffer              int             `env:"PRICE_STREAM_BUFFER" envDefault:"4"`
+	PriceStreamWriteTimeout        time.Duration   `env:"PRICE_STREAM_WRITE_TIMEOUT" envDefault:"10s"`
+	CSRFCookieName                 string          `env:"CSRF_COOKIE_NAME" envDefault:"CSRF_TOKEN"`
+	DataPath                       string          `env:"DATA_PATH" envDefault:"apiservice.db"`
+	PriceRecordInterval            time.Duration   `env:"PRICE_RECORD_INTERVAL" envDefault:"10s"`
+	PriceRawRetention              time.Duration   `env:"PRICE_RAW_RETENTION" envDefault:"24h"`
+	PriceDownsampleInterval        time.Duration   `env:"PRICE_DOWNSAMPLE_INTERVAL" envDefault:"1m"`
+	PriceRetention                 time.Duration   `env:"PRICE_RETENTION" envDefault:"2160h"`
+	PriceCompactInterval           time.Duration   `env:"PRICE_COMPACT_INTERVAL" envDefault:"10m"`
+	IdempotencyStore               string          `env:"IDEMPOTENCY_STORE" envDefault:"redis"` // redis or memory
+	IdempotencyTTL                 time.Duration   `env:"IDEMPOTENCY_TTL" envDefault:"24h"`
+	IdempotencyLockTTL             time.Duration   `env:"IDEMPOTENCY_LOCK_TTL" envDefault:"30s"` // lock is extended while request runs
+	IdempotencyWaitTimeout         time.Duration   `env:"IDEMPOTENCY_WAIT_TIMEOUT" envDefault:"10s"`
+	LockStore                      string          `env:"LOCK_STORE" envDefault:"redis"`     // redis or memory
+	ProfileLockTTL                 time.Duration   `env:"PROFILE_LOCK_TTL" envDefault:"30s"` // lock is extended while operation runs
+	ProfileLockWaitTimeout         time.Duration   `env:"PROFILE_LOCK_WAIT_TIMEOUT" envDefault:"10s"`
+	UserOperationStore             string          `env:"USER_OPERATION_STORE" envDefault:"redis"` // redis or memory
+	WithdrawalMin                  decimal.Decimal `env:"WITHDRAWAL_MIN" envDefault:"1"`           // zero disables limit, as well as for caps below
+	WithdrawalMax                  decimal.Decimal `env:"WITHDRAWAL_MAX" envDefault:"10000"`
+	WithdrawalDailyCap             decimal.Decimal `env:"WITHDRAWAL_DAILY_CAP" envDefault:"20000"`
+	WithdrawalWeeklyCap            decimal.Decimal `env:"WITHDRAWAL_WEEKLY_CAP" envDefault:"50000"`
+	WithdrawalMonthlyCap           decimal.Decimal `env:"WITHDRAWAL_MONTHLY_CAP" envDefault:"100000"`
+	BalanceOperationsPerHour       int             `env:"BALANCE_OPERATIONS_PER_HOUR" envDefault:"60"`
+	DepositCoolOff                 time.Duration   `env:"DEPOSIT_COOL_OFF" envDefault:"10m"`
+	MaxPositionNotional            decimal.Decimal `env:"MAX_POSITION_NOTIONAL" envDefault:"50000"` // zero disables limit, as well as for exposure below
+	MaxCompanyExposure             decimal.Decimal `env:"MAX_COMPANY_EXPOSURE" envDefault:"100000"`
+	OrderStore                     string          `env:"ORDER_STORE" envDefault:"redis"` // redis or bolt, bolt is only for one instance
+	EngineLeaderTTL                time.Duration   `env:"ENGINE_LEADER_TTL" envDefault:"10s"`
+	OrderCheckInterval             time.Duration   `env:"ORDER_CHECK_INTERVAL" envDefault:"1s"`
+	MaxPendingOrders               int             `env:"MAX_PENDING_ORDERS" envDefault:"50"`     // zero disables limit
+	TrailingStopStore              string          `env:"TRAILING_STOP_STORE" envDefault:"redis"` // redis or bolt, bolt is only for one instance
+	TrailingStopInterval           time.Duration   `env:"TRAILING_STOP_INTERVAL" envDefault:"1s"`
 }
 
 // New returns parsed object of config
@@ -12,12 +12,75 @@ const (
 	PurchasePriceOut = "PURCHASE_PRICE_OUT"
 	// NotEnoughMoney is error code if user don`t have enough money
 	NotEnoughMoney = "NOT_ENOUGH_MONEY"
+	// InvalidRequest is error code if request of client can`t be read or validated
+	InvalidRequest = "INVALID_REQUEST"
+	// Unauthorized is error code if client isn`t authenticated
+	Unauthorized = "UNAUTHORIZED"
+	// NotFound is error code if requested entity doesn`t exist
+	NotFound = "NOT_FOUND"
+	// PermissionDenied is error code if user has no rights for the operation
+	PermissionDenied = "PERMISSION_DENIED"
+	// MethodNotAllowed is error code if resource doesn`t support method of request
+	MethodNotAllowed = "METHOD_NOT_ALLOWED"
+	// Conflict is error code if request conflicts with the current state of resource
+	Conflict = "CONFLICT"
+	// RequestTooLarge is error code if body of request is larger than the limit
+	RequestTooLarge = "REQUEST_TOO_LARGE"
+	// UnsupportedMediaType is error code if body of request has unsupported content type
+	UnsupportedMediaType = "UNSUPPORTED_MEDIA_TYPE"
+	// TooManyRequests is error code if client sent too many requests and is rate limited
+	TooManyRequests = "TOO_MANY_REQUESTS"
+	// Unavailable is error code if one of backend services is unavailable
+	Unavailable = "UNAVAILABLE"
+	// DeadlineExceeded is error code if backend service didn`t answer in time
+	DeadlineExceeded = "DEADLINE_EXCEEDED"
+	// TooManyLoginAttempts is error code if logging in is temporarily blocked after failed attempts
+	TooManyLoginAttempts = "TOO_MANY_LOGIN_ATTEMPTS"
+	// IdempotencyKeyReused is error code if idempotency key was already used for another request
+	IdempotencyKeyReused = "IDEMPOTENCY_KEY_REUSED"
+	// RequestInProgress is error code if request with the same idempotency key is still being processed
+	RequestInProgress = "REQUEST_IN_PROGRESS"
+	// ProfileBusy is error code if another operation with balance of profile didn`t finish in time
+	ProfileBusy = "PROFILE_BUSY"
+	// WithdrawalBelowMinimum is error code if sum of withdrawal is less than the minimum
+	WithdrawalBelowMinimum = "WITHDRAWAL_BELOW_MINIMUM"
+	// WithdrawalAboveMaximum is error code if sum of withdrawal is more than the maximum
+	WithdrawalAboveMaximum = "WITHDRAWAL_ABOVE_MAXIMUM"
+	// DailyWithdrawalCap is error code if withdrawals of the last day exceed the cap
+	DailyWithdrawalCap = "DAILY_WITHDRAWAL_CAP"
+	// WeeklyWithdrawalCap is error code if withdrawals of the last week exceed the cap
+	WeeklyWithdrawalCap = "WEEKLY_WITHDRAWAL_CAP"
+	// MonthlyWithdrawalCap is error code if withdrawals of the last month exceed the cap
+	MonthlyWithdrawalCap = "MONTHLY_WITHDRAWAL_CAP"
+	// TooManyBalanceOperations is error code if user made too many operations with balance in the last hour
+	TooManyBalanceOperations = "TOO_MANY_BALANCE_OPERATIONS"
+	// DepositCoolOff is error code if withdrawal is made too soon after deposit
+	DepositCoolOff = "DEPOSIT_COOL_OFF"
+	// UnknownCompany is error code if there is no price of company of position
+	UnknownCompany = "UNKNOWN_COMPANY"
+	// StopLossWrongSide is error code if stop loss isn`t on the loss side of the current price
+	StopLossWrongSide = "STOP_LOSS_WRONG_SIDE"
+	// TakeProfitWrongSide is error code if take profit isn`t on the profit side of the current price
+	TakeProfitWrongSide = "TAKE_PROFIT_WRONG_SIDE"
+	// PositionSizeLimit is error code if cost of position is more than the limit of one position
+	PositionSizeLimit = "POSITION_SIZE_LIMIT"
+	// CompanyExposureLimit is error code if cost of opened positions of company would be more than the limit
+	CompanyExposureLimit = "COMPANY_EXPOSURE_LIMIT"
+	// OrderNotPending is error code if order was already triggered, cancelled or expired
+	OrderNotPending = "ORDER_NOT_PENDING"
+	// TooManyOrders is error code if user has too many pending orders
+	TooManyOrders = "TOO_MANY_ORDERS"
+	// TrailingStopClosing is error code if trailing stop is closing its position and can`t be changed
+	TrailingStopClosing = "TRAILING_STOP_CLOSING"
+	// Internal is error code for all unexpected errors
+	Internal = "INTERNAL"
 )
 
 // BusinessError is struct for business errors
 type BusinessError struct {
 	Code    string
 	Message string
+	Err     error // original error of backend if there is one
 }
 
 // New is constructor for manage business errors
@@ -27,5 +90,13 @@ func New(code, message string) *BusinessError {
 
 // Error is method for creating business errors
 func (bs *BusinessError) Error() string {
+	if bs.Err != nil {
+		return fmt.Sprintf("code: %s, message: %s, cause: %v", bs.Code, bs.Message, bs.Err)
+	}
 	return fmt.Sprintf("code: %s, message: %s", bs.Code, bs.Message)
 }
+
+// Unwrap returns original error of backend
+func (bs *BusinessError) Unwrap() error {
+	return bs.Err
+}
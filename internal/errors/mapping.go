@@ -0,0 +1,155 @@
+package errors
+
+import (
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Rule describes how the error with some code is shown to the client
+type Rule struct {
+	Status    int    // HTTP status code of response
+	Message   string // default message for the client
+	Retryable bool   // true if the same request may succeed later
+}
+
+// Lookup returns rule of the mapping table for the given error code
+func Lookup(code string) Rule {
+	switch code {
+	case LoginAlreadyExist:
+		return Rule{Status: http.StatusConflict, Message: "Login is occupied by another user"}
+	case UserDoesntExists:
+		return Rule{Status: http.StatusNotFound, Message: "User doesnt exist"}
+	case PurchasePriceOut:
+		return Rule{Status: http.StatusUnprocessableEntity, Message: "Purchase price out of stoploss/takeprofit"}
+	case NotEnoughMoney:
+		return Rule{Status: http.StatusUnprocessableEntity, Message: "Not enough money"}
+	case InvalidRequest:
+		return Rule{Status: http.StatusBadRequest, Message: "Invalid request"}
+	case Unauthorized:
+		return Rule{Status: http.StatusUnauthorized, Message: "Unauthorized"}
+	case NotFound:
+		return Rule{Status: http.StatusNotFound, Message: "Not found"}
+	case PermissionDenied:
+		return Rule{Status: http.StatusForbidden, Message: "Permission denied"}
+	case MethodNotAllowed:
+		return Rule{Status: http.StatusMethodNotAllowed, Message: "Method not allowed"}
+	case Conflict:
+		return Rule{Status: http.StatusConflict, Message: "Conflict"}
+	case RequestTooLarge:
+		return Rule{Status: http.StatusRequestEntityTooLarge, Message: "Request is too large"}
+	case UnsupportedMediaType:
+		return Rule{Status: http.StatusUnsupportedMediaType, Message: "Unsupported media type"}
+	case TooManyRequests:
+		return Rule{Status: http.StatusTooManyRequests, Message: "Too many requests", Retryable: true}
+	case Unavailable:
+		return Rule{Status: http.StatusServiceUnavailable, Message: "Service is temporarily unavailable", Retryable: true}
+	case DeadlineExceeded:
+		return Rule{Status: http.StatusGatewayTimeout, Message: "Service didn`t answer in time", Retryable: true}
+	case TooManyLoginAttempts:
+		return Rule{Status: http.StatusTooManyRequests, Message: "Too many login attempts", Retryable: true}
+	case IdempotencyKeyReused:
+		return Rule{Status: http.StatusUnprocessableEntity, Message: "Idempotency key was used for another request"}
+	case RequestInProgress:
+		return Rule{Status: http.StatusConflict, Message: "Request with the same idempotency key is in progress", Retryable: true}
+	case WithdrawalBelowMinimum:
+		return Rule{Status: http.StatusUnprocessableEntity, Message: "Sum of withdrawal is less than the minimum"}
+	case WithdrawalAboveMaximum:
+		return Rule{Status: http.StatusUnprocessableEntity, Message: "Sum of withdrawal is more than the maximum"}
+	case DailyWithdrawalCap:
+		return Rule{Status: http.StatusUnprocessableEntity, Message: "Daily withdrawal cap is reached", Retryable: true}
+	case WeeklyWithdrawalCap:
+		return Rule{Status: http.StatusUnprocessableEntity, Message: "Weekly withdrawal cap is reached", Retryable: true}
+	case MonthlyWithdrawalCap:
+		return Rule{Status: http.StatusUnprocessableEntity, Message: "Monthly withdrawal cap is reached", Retryable: true}
+	case TooManyBalanceOperations:
+		return Rule{Status: http.StatusTooManyRequests, Message: "Too many operations with balance", Retryable: true}
+	case DepositCoolOff:
+		return Rule{Status: http.StatusUnprocessableEntity, Message: "Withdrawal isn't allowed so soon after deposit", Retryable: true}
+	case ProfileBusy:
+		return Rule{Status: http.StatusConflict, Message: "Another operation with balance is in progress", Retryable: true}
+	case UnknownCompany:
+		return Rule{Status: http.StatusUnprocessableEntity, Message: "There is no such company"}
+	case StopLossWrongSide:
+		return Rule{Status: http.StatusUnprocessableEntity, Message: "Stop loss is on the wrong side of the price"}
+	case TakeProfitWrongSide:
+		return Rule{Status: http.StatusUnprocessableEntity, Message: "Take profit is on the wrong side of the price"}
+	case PositionSizeLimit:
+		return Rule{Status: http.StatusUnprocessableEntity, Message: "Position is too large"}
+	case CompanyExposureLimit:
+		return Rule{Status: http.StatusUnprocessableEntity, Message: "Exposure to company is too large"}
+	case OrderNotPending:
+		return Rule{Status: http.StatusConflict, Message: "Order isn't pending"}
+	case TooManyOrders:
+		return Rule{Status: http.StatusUnprocessableEntity, Message: "Too many pending orders"}
+	case TrailingStopClosing:
+		return Rule{Status: http.StatusConflict, Message: "Trailing stop is closing position"}
+	default:
+		return Rule{Status: http.StatusInternalServerError, Message: "Internal error"}
+	}
+}
+
+// isBusinessCode reports if backend service sent one of the known business codes as a message of status
+func isBusinessCode(message string) bool {
+	switch message {
+	case LoginAlreadyExist, UserDoesntExists, PurchasePriceOut, NotEnoughMoney:
+		return true
+	default:
+		return false
+	}
+}
+
+// grpcCode returns error code for the given gRPC status code or empty string if there is no such code
+func grpcCode(code codes.Code) string {
+	switch code {
+	case codes.NotFound:
+		return NotFound
+	case codes.Unavailable:
+		return Unavailable
+	case codes.DeadlineExceeded:
+		return DeadlineExceeded
+	case codes.PermissionDenied:
+		return PermissionDenied
+	case codes.Unauthenticated:
+		return Unauthorized
+	case codes.InvalidArgument:
+		return InvalidRequest
+	default:
+		return ""
+	}
+}
+
+// FromGRPC translates error of gRPC client to BusinessError using the mapping table.
+// Errors which aren`t known to the table are returned as is.
+func FromGRPC(err error) error {
+	grpcStatus, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	code := grpcStatus.Message()
+	if !isBusinessCode(code) {
+		code = grpcCode(grpcStatus.Code())
+		if code == "" {
+			return err
+		}
+	}
+	return &BusinessError{Code: code, Message: Lookup(code).Message, Err: err}
+}
+
+// Translate finds business error in the chain of err and returns its code, message and rule.
+// Errors without business code are treated as Internal and get the given fallback message.
+func Translate(err error, fallback string) (code, message string, rule Rule) {
+	var e *BusinessError
+	if errors.As(err, &e) {
+		return e.Code, e.Message, Lookup(e.Code)
+	}
+	return Internal, fallback, Lookup(Internal)
+}
+
+// HTTPStatus returns HTTP status code for the given error
+func HTTPStatus(err error) int {
+	_, _, rule := Translate(err, "")
+	return rule.Status
+}
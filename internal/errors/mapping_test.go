@@ -0,0 +1,47 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFromGRPC(t *testing.T) {
+	testCases := []struct {
+		err       error
+		code      string
+		status    int
+		retryable bool
+	}{
+		{status.Error(codes.Unknown, NotEnoughMoney), NotEnoughMoney, http.StatusUnprocessableEntity, false},
+		{status.Error(codes.Unknown, LoginAlreadyExist), LoginAlreadyExist, http.StatusConflict, false},
+		{status.Error(codes.Unknown, PurchasePriceOut), PurchasePriceOut, http.StatusUnprocessableEntity, false},
+		{status.Error(codes.Unknown, UserDoesntExists), UserDoesntExists, http.StatusNotFound, false},
+		{status.Error(codes.NotFound, "no rows"), NotFound, http.StatusNotFound, false},
+		{status.Error(codes.Unavailable, "connection refused"), Unavailable, http.StatusServiceUnavailable, true},
+		{status.Error(codes.DeadlineExceeded, "timeout"), DeadlineExceeded, http.StatusGatewayTimeout, true},
+		{status.Error(codes.PermissionDenied, "denied"), PermissionDenied, http.StatusForbidden, false},
+	}
+	for _, tc := range testCases {
+		err := fmt.Errorf("repository %w", FromGRPC(tc.err))
+		code, _, rule := Translate(err, "fallback")
+		require.Equal(t, tc.code, code)
+		require.Equal(t, tc.status, rule.Status)
+		require.Equal(t, tc.retryable, rule.Retryable)
+		require.ErrorIs(t, err, tc.err)
+	}
+}
+
+func TestTranslateUnknownError(t *testing.T) {
+	err := FromGRPC(status.Error(codes.Internal, "panic"))
+	code, message, rule := Translate(err, "fallback")
+	require.Equal(t, Internal, code)
+	require.Equal(t, "fallback", message)
+	require.Equal(t, http.StatusInternalServerError, rule.Status)
+	require.Equal(t, http.StatusInternalServerError, HTTPStatus(errors.New("plain")))
+}
@@ -0,0 +1,649 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// authResponse is a body of response after successful sign up or log in
+type authResponse struct {
+	ID     uuid.UUID        `json:"id"`
+	Login  string           `json:"login"`
+	Tokens *model.TokenPair `json:"tokens"`
+}
+
+// refreshRequest is a body of request for refreshing or revoking tokens
+type refreshRequest struct {
+	RefreshToken string `json:"refreshtoken"`
+}
+
+// balanceResponse is a body of response with current balance of user
+type balanceResponse struct {
+	Balance decimal.Decimal `json:"balance"`
+}
+
+// operationResponse is a body of response after deposit or withdraw
+type operationResponse struct {
+	ID        uuid.UUID       `json:"id"`
+	Operation decimal.Decimal `json:"operation"`
+}
+
+// positionRequest is a body of request for opening a new position
+type positionRequest struct {
+	Company     string          `json:"company" validate:"required"`
+	SharesCount decimal.Decimal `json:"sharescount"`
+	StopLoss    decimal.Decimal `json:"stoploss"`
+	TakeProfit  decimal.Decimal `json:"takeprofit"`
+}
+
+// positionResponse is a body of response after opening a new position
+type positionResponse struct {
+	Company  string `json:"company"`
+	Strategy string `json:"strategy"`
+}
+
+// closePositionResponse is a body of response after closing a position
+type closePositionResponse struct {
+	DealID uuid.UUID       `json:"dealid"`
+	Profit decimal.Decimal `json:"profit"`
+}
+
+// orderRequest is a body of request for placing a new entry order, zero expiry means that order never expires
+type orderRequest struct {
+	Type         string          `json:"type" validate:"required,oneof=limit stop"`
+	Company      string          `json:"company" validate:"required"`
+	SharesCount  decimal.Decimal `json:"sharescount"`
+	TriggerPrice decimal.Decimal `json:"triggerprice"`
+	StopLoss     decimal.Decimal `json:"stoploss"`
+	TakeProfit   decimal.Decimal `json:"takeprofit"`
+	ExpiresAt    time.Time       `json:"expiresat"`
+}
+
+// trailingStopRequest is a body of request for attaching trailing stop to position
+type trailingStopRequest struct {
+	Type     string          `json:"type" validate:"required,oneof=absolute percent"`
+	Distance decimal.Decimal `json:"distance"`
+}
+
+// sessionResponse is an active session of user without its secret id
+type sessionResponse struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdat"`
+	LastSeen  time.Time `json:"lastseen"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"useragent"`
+	Current   bool      `json:"current"`
+}
+
+// revokeSessionsResponse is a body of response after revoking sessions
+type revokeSessionsResponse struct {
+	Revoked int `json:"revoked"`
+}
+
+// bearerToken returns access token from Authorization header
+func bearerToken(c echo.Context) (string, bool) {
+	const prefix = "Bearer "
+	header := c.Request().Header.Get(echo.HeaderAuthorization)
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", false
+	}
+	return header[len(prefix):], true
+}
+
+// APISignUp registers a new user and starts his session
+func (h *Handler) APISignUp(c echo.Context) error {
+	var creds credentials
+	if errBind := c.Bind(&creds); errBind != nil {
+		return apiBadRequest(c, "Failed to read fields")
+	}
+	user := creds.User
+	password := user.Password
+	if errValidate := h.validate.StructCtx(c.Request().Context(), user); errValidate != nil {
+		return apiBadRequest(c, "Invalid fields! The fields have not been validated")
+	}
+	if errSignUp := h.userService.SignUp(c.Request().Context(), &user); errSignUp != nil {
+		logrus.Errorf("apiSignUp: %v", errSignUp)
+		return apiError(c, errSignUp, "Failed to sign up")
+	}
+	user.Password = password
+	userID, err := h.userService.GetByLogin(c.Request().Context(), &user)
+	if err != nil {
+		logrus.Errorf("apiSignUp: %v", err)
+		return apiError(c, err, "Failed to log in")
+	}
+	if err = h.startSession(c, userID, user.Login, creds.Remember); err != nil {
+		logrus.Errorf("apiSignUp: %v", err)
+		return apiError(c, err, "Error saving session")
+	}
+	tokens, err := h.tokenService.GenerateTokens(c.Request().Context(), userID)
+	if err != nil {
+		logrus.Errorf("apiSignUp: %v", err)
+		return apiError(c, err, "Failed to generate tokens")
+	}
+	return c.JSON(http.StatusCreated, authResponse{ID: userID, Login: user.Login, Tokens: tokens})
+}
+
+// APILogin checks credentials of user and starts his session
+func (h *Handler) APILogin(c echo.Context) error {
+	var creds credentials
+	if errBind := c.Bind(&creds); errBind != nil {
+		return apiBadRequest(c, "Failed to read fields")
+	}
+	user := creds.User
+	if errValidate := h.validate.StructCtx(c.Request().Context(), user); errValidate != nil {
+		return apiBadRequest(c, "Invalid fields! The fields have not been validated")
+	}
+	userID, err := h.checkCredentials(c, &user)
+	if err != nil {
+		logrus.WithField("Login", user.Login).Errorf("apiLogin: %v", err)
+		return apiError(c, err, "Failed to log in")
+	}
+	if err = h.startSession(c, userID, user.Login, creds.Remember); err != nil {
+		logrus.Errorf("apiLogin: %v", err)
+		return apiError(c, err, "Error saving session")
+	}
+	tokens, err := h.tokenService.GenerateTokens(c.Request().Context(), userID)
+	if err != nil {
+		logrus.Errorf("apiLogin: %v", err)
+		return apiError(c, err, "Failed to generate tokens")
+	}
+	return c.JSON(http.StatusOK, authResponse{ID: userID, Login: user.Login, Tokens: tokens})
+}
+
+// APIRefreshTokens exchanges refresh token to a new pair of tokens
+func (h *Handler) APIRefreshTokens(c echo.Context) error {
+	var req refreshRequest
+	if errBind := c.Bind(&req); errBind != nil || req.RefreshToken == "" {
+		return apiBadRequest(c, "Refresh token is required")
+	}
+	tokens, err := h.tokenService.Refresh(c.Request().Context(), req.RefreshToken)
+	if err != nil {
+		logrus.Errorf("apiRefreshTokens: %v", err)
+		return apiError(c, err, "Failed to refresh tokens")
+	}
+	return c.JSON(http.StatusOK, tokens)
+}
+
+// APIRevokeTokens revokes access token from Authorization header and refresh token from the body
+func (h *Handler) APIRevokeTokens(c echo.Context) error {
+	var req refreshRequest
+	if errBind := c.Bind(&req); errBind != nil {
+		return apiBadRequest(c, "Failed to read fields")
+	}
+	accessToken, _ := bearerToken(c)
+	if accessToken == "" && req.RefreshToken == "" {
+		return apiBadRequest(c, "Access or refresh token is required")
+	}
+	if err := h.tokenService.Revoke(c.Request().Context(), accessToken, req.RefreshToken); err != nil {
+		logrus.Errorf("apiRevokeTokens: %v", err)
+		return apiError(c, err, "Failed to revoke tokens")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// APIGetBalance returns current balance of user
+func (h *Handler) APIGetBalance(c echo.Context) error {
+	profileID, err := getProfileID(c)
+	if err != nil {
+		return err
+	}
+	balance, err := h.balanceService.GetBalance(c.Request().Context(), profileID)
+	if err != nil {
+		logrus.Errorf("apiGetBalance: %v", err)
+		return apiError(c, err, "Failed to get balance")
+	}
+	return c.JSON(http.StatusOK, balanceResponse{Balance: balance})
+}
+
+// APIDeposit adds money to the balance of user
+func (h *Handler) APIDeposit(c echo.Context) error {
+	return h.apiBalanceOperation(c, false)
+}
+
+// APIWithdraw takes money from the balance of user
+func (h *Handler) APIWithdraw(c echo.Context) error {
+	return h.apiBalanceOperation(c, true)
+}
+
+// apiBalanceOperation makes deposit or withdraw depending on the given flag
+func (h *Handler) apiBalanceOperation(c echo.Context, withdraw bool) error {
+	profileID, err := getProfileID(c)
+	if err != nil {
+		return err
+	}
+	var balance model.Balance
+	if errBind := c.Bind(&balance); errBind != nil {
+		return apiBadRequest(c, "Invalid sum of money")
+	}
+	balance.ProfileID = profileID
+	if !balance.Operation.IsPositive() {
+		return apiBadRequest(c, "Invalid sum of money")
+	}
+	if withdraw {
+		balance.Operation = balance.Operation.Neg()
+	}
+	operation, err := h.balanceService.BalanceOperation(c.Request().Context(), &balance)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"ProfileId": balance.ProfileID,
+			"Operation": balance.Operation,
+		}).Errorf("apiBalanceOperation: %v", err)
+		return apiError(c, err, "Failed to made balance operation")
+	}
+	return c.JSON(http.StatusOK, operationResponse{ID: balance.BalanceID, Operation: operation})
+}
+
+// APICreatePosition opens a new long or short position
+func (h *Handler) APICreatePosition(c echo.Context) error {
+	profileID, err := getProfileID(c)
+	if err != nil {
+		return err
+	}
+	var req positionRequest
+	if errBind := c.Bind(&req); errBind != nil {
+		return apiBadRequest(c, "Failed to read fields")
+	}
+	if errValidate := h.validate.StructCtx(c.Request().Context(), req); errValidate != nil {
+		return apiBadRequest(c, "Invalid fields! The fields have not been validated")
+	}
+	if !req.SharesCount.IsPositive() || !req.StopLoss.IsPositive() || !req.TakeProfit.IsPositive() {
+		return apiBadRequest(c, "Shares count, stop loss and take profit must be positive")
+	}
+	strategy := "long"
+	if req.StopLoss.Cmp(req.TakeProfit) == 1 {
+		strategy = "short"
+	}
+	deal := &model.Deal{
+		ProfileID:   profileID,
+		SharesCount: req.SharesCount,
+		Company:     req.Company,
+		StopLoss:    req.StopLoss,
+		TakeProfit:  req.TakeProfit,
+	}
+	if err = h.tradingService.CreatePosition(c.Request().Context(), deal); err != nil {
+		logrus.Errorf("apiCreatePosition: %v", err)
+		return apiError(c, err, "Failed to create position")
+	}
+	return c.JSON(http.StatusCreated, positionResponse{Company: deal.Company, Strategy: strategy})
+}
+
+// APIPlaceOrder places entry order which opens position when price reaches the trigger price
+func (h *Handler) APIPlaceOrder(c echo.Context) error {
+	profileID, err := getProfileID(c)
+	if err != nil {
+		return err
+	}
+	var req orderRequest
+	if errBind := c.Bind(&req); errBind != nil {
+		return apiBadRequest(c, "Failed to read fields")
+	}
+	if errValidate := h.validate.StructCtx(c.Request().Context(), req); errValidate != nil {
+		return apiBadRequest(c, "Invalid fields! The fields have not been validated")
+	}
+	order := &model.Order{
+		ProfileID:    profileID,
+		Type:         req.Type,
+		Company:      req.Company,
+		SharesCount:  req.SharesCount,
+		TriggerPrice: req.TriggerPrice,
+		StopLoss:     req.StopLoss,
+		TakeProfit:   req.TakeProfit,
+		ExpiresAt:    req.ExpiresAt.UTC(),
+	}
+	if err = h.orders.PlaceOrder(c.Request().Context(), order); err != nil {
+		logrus.Errorf("apiPlaceOrder: %v", err)
+		return apiError(c, err, "Failed to place order")
+	}
+	return c.JSON(http.StatusCreated, order)
+}
+
+// APIGetOrders returns entry orders of user from the newest, status from query parameter filters them
+func (h *Handler) APIGetOrders(c echo.Context) error {
+	profileID, err := getProfileID(c)
+	if err != nil {
+		return err
+	}
+	orders, err := h.orders.GetOrders(c.Request().Context(), profileID)
+	if err != nil {
+		logrus.Errorf("apiGetOrders: %v", err)
+		return apiError(c, err, "Failed to get orders")
+	}
+	status := c.QueryParam("status")
+	filtered := make([]*model.Order, 0, len(orders))
+	for _, order := range orders {
+		if status == "" || order.Status == status {
+			filtered = append(filtered, order)
+		}
+	}
+	return c.JSON(http.StatusOK, filtered)
+}
+
+// APICancelOrder cancels pending entry order of user by its id
+func (h *Handler) APICancelOrder(c echo.Context) error {
+	profileID, err := getProfileID(c)
+	if err != nil {
+		return err
+	}
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return apiBadRequest(c, "Invalid order ID")
+	}
+	order, err := h.orders.CancelOrder(c.Request().Context(), profileID, orderID)
+	if err != nil {
+		logrus.Errorf("apiCancelOrder: %v", err)
+		return apiError(c, err, "Failed to cancel order")
+	}
+	return c.JSON(http.StatusOK, order)
+}
+
+// APIAttachTrailingStop attaches trailing stop to open position of user or changes its distance
+func (h *Handler) APIAttachTrailingStop(c echo.Context) error {
+	profileID, err := getProfileID(c)
+	if err != nil {
+		return err
+	}
+	dealUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return apiBadRequest(c, "Invalid deal ID")
+	}
+	var req trailingStopRequest
+	if errBind := c.Bind(&req); errBind != nil {
+		return apiBadRequest(c, "Failed to read fields")
+	}
+	if errValidate := h.validate.StructCtx(c.Request().Context(), req); errValidate != nil {
+		return apiBadRequest(c, "Invalid fields! The fields have not been validated")
+	}
+	stop := &model.TrailingStop{ProfileID: profileID, DealID: dealUUID, Type: req.Type, Distance: req.Distance}
+	if err = h.trailingStops.Attach(c.Request().Context(), stop); err != nil {
+		logrus.Errorf("apiAttachTrailingStop: %v", err)
+		return apiError(c, err, "Failed to attach trailing stop")
+	}
+	return c.JSON(http.StatusOK, stop)
+}
+
+// APIDetachTrailingStop removes active trailing stop from position of user
+func (h *Handler) APIDetachTrailingStop(c echo.Context) error {
+	profileID, err := getProfileID(c)
+	if err != nil {
+		return err
+	}
+	dealUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return apiBadRequest(c, "Invalid deal ID")
+	}
+	stop, err := h.trailingStops.Detach(c.Request().Context(), profileID, dealUUID)
+	if err != nil {
+		logrus.Errorf("apiDetachTrailingStop: %v", err)
+		return apiError(c, err, "Failed to remove trailing stop")
+	}
+	return c.JSON(http.StatusOK, stop)
+}
+
+// APIGetTrailingStops returns trailing stops of user, status from query parameter filters them
+func (h *Handler) APIGetTrailingStops(c echo.Context) error {
+	profileID, err := getProfileID(c)
+	if err != nil {
+		return err
+	}
+	stops, err := h.trailingStops.GetTrailingStops(c.Request().Context(), profileID)
+	if err != nil {
+		logrus.Errorf("apiGetTrailingStops: %v", err)
+		return apiError(c, err, "Failed to get trailing stops")
+	}
+	status := c.QueryParam("status")
+	filtered := make([]*model.TrailingStop, 0, len(stops))
+	for _, stop := range stops {
+		if status == "" || stop.Status == status {
+			filtered = append(filtered, stop)
+		}
+	}
+	return c.JSON(http.StatusOK, filtered)
+}
+
+// APIClosePosition closes position of user by id of deal
+func (h *Handler) APIClosePosition(c echo.Context) error {
+	profileID, err := getProfileID(c)
+	if err != nil {
+		return err
+	}
+	dealUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return apiBadRequest(c, "Invalid deal ID")
+	}
+	profit, err := h.tradingService.ClosePositionManually(c.Request().Context(), dealUUID, profileID)
+	if err != nil {
+		logrus.Errorf("apiClosePosition: %v", err)
+		return apiError(c, err, "Failed to close position")
+	}
+	return c.JSON(http.StatusOK, closePositionResponse{DealID: dealUUID, Profit: profit})
+}
+
+// APIGetUnclosedPositions returns opened positions of user
+func (h *Handler) APIGetUnclosedPositions(c echo.Context) error {
+	profileID, err := getProfileID(c)
+	if err != nil {
+		return err
+	}
+	filter, err := parseDealFilter(c)
+	if err != nil {
+		return apiError(c, err, "Invalid filter of positions")
+	}
+	page, err := h.tradingService.FindUnclosedPositions(c.Request().Context(), profileID, filter)
+	if err != nil {
+		logrus.Errorf("apiGetUnclosedPositions: %v", err)
+		return apiError(c, err, "Failed to get positions")
+	}
+	return dealPage(c, page)
+}
+
+// APIGetPortfolio returns open positions of user valued with current prices, totals per company and equity
+func (h *Handler) APIGetPortfolio(c echo.Context) error {
+	profileID, err := getProfileID(c)
+	if err != nil {
+		return err
+	}
+	portfolio, err := h.portfolio.GetPortfolio(c.Request().Context(), profileID)
+	if err != nil {
+		logrus.Errorf("apiGetPortfolio: %v", err)
+		return apiError(c, err, "Failed to get portfolio")
+	}
+	return c.JSON(http.StatusOK, portfolio)
+}
+
+// APIGetAnalytics returns performance figures of positions closed in range of time from query parameters,
+// optionally only for one company
+func (h *Handler) APIGetAnalytics(c echo.Context) error {
+	profileID, err := getProfileID(c)
+	if err != nil {
+		return err
+	}
+	from, err := parseTimeParam(c, "from")
+	if err != nil {
+		return apiBadRequest(c, "From must be a time in RFC 3339 format")
+	}
+	to, err := parseTimeParam(c, "to")
+	if err != nil {
+		return apiBadRequest(c, "To must be a time in RFC 3339 format")
+	}
+	analytics, err := h.analytics.GetAnalytics(c.Request().Context(), profileID, from, to, c.QueryParam("company"))
+	if err != nil {
+		logrus.Errorf("apiGetAnalytics: %v", err)
+		return apiError(c, err, "Failed to get analytics")
+	}
+	return c.JSON(http.StatusOK, analytics)
+}
+
+// APIGetStatement returns page of statement of balance operations made in range of time from query parameters
+func (h *Handler) APIGetStatement(c echo.Context) error {
+	profileID, err := getProfileID(c)
+	if err != nil {
+		return err
+	}
+	from, err := parseTimeParam(c, "from")
+	if err != nil {
+		return apiBadRequest(c, "From must be a time in RFC 3339 format")
+	}
+	to, err := parseTimeParam(c, "to")
+	if err != nil {
+		return apiBadRequest(c, "To must be a time in RFC 3339 format")
+	}
+	limit := 0
+	if value := c.QueryParam("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil {
+			return apiBadRequest(c, "Limit must be a number")
+		}
+	}
+	statement, err := h.ledger.GetStatement(c.Request().Context(), profileID, from, to, limit, c.QueryParam("cursor"))
+	if err != nil {
+		logrus.Errorf("apiGetStatement: %v", err)
+		return apiError(c, err, "Failed to get statement")
+	}
+	return c.JSON(http.StatusOK, statement)
+}
+
+// APIGetClosedPositions returns closed positions of user
+func (h *Handler) APIGetClosedPositions(c echo.Context) error {
+	profileID, err := getProfileID(c)
+	if err != nil {
+		return err
+	}
+	filter, err := parseDealFilter(c)
+	if err != nil {
+		return apiError(c, err, "Invalid filter of positions")
+	}
+	page, err := h.tradingService.FindClosedPositions(c.Request().Context(), profileID, filter)
+	if err != nil {
+		logrus.Errorf("apiGetClosedPositions: %v", err)
+		return apiError(c, err, "Failed to get positions")
+	}
+	return dealPage(c, page)
+}
+
+// APIGetPrices returns current prices of all shares
+func (h *Handler) APIGetPrices(c echo.Context) error {
+	snapshot, err := h.tradingService.GetPriceSnapshot(c.Request().Context())
+	if err != nil {
+		logrus.Errorf("apiGetPrices: %v", err)
+		return apiError(c, err, "Failed to get shares")
+	}
+	if notModified(c, snapshot) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	return c.JSON(http.StatusOK, snapshot.Shares)
+}
+
+// APIGetCandles returns OHLC candles of share for range of time from query parameters in RFC 3339 format
+func (h *Handler) APIGetCandles(c echo.Context) error {
+	from, err := parseTimeParam(c, "from")
+	if err != nil {
+		return apiBadRequest(c, "From must be a time in RFC 3339 format")
+	}
+	to, err := parseTimeParam(c, "to")
+	if err != nil {
+		return apiBadRequest(c, "To must be a time in RFC 3339 format")
+	}
+	interval := c.QueryParam("interval")
+	if interval == "" {
+		interval = "1m"
+	}
+	candles, err := h.priceHistory.GetCandles(c.Request().Context(), c.Param("company"), interval, from, to)
+	if err != nil {
+		logrus.Errorf("apiGetCandles: %v", err)
+		return apiError(c, err, "Failed to get candles")
+	}
+	return c.JSON(http.StatusOK, candles)
+}
+
+// parseTimeParam parses query parameter in RFC 3339 format, missing parameter is a zero time
+func parseTimeParam(c echo.Context, name string) (time.Time, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse %w", err)
+	}
+	return t, nil
+}
+
+// APIGetSessions returns active sessions of user, the newest first
+func (h *Handler) APIGetSessions(c echo.Context) error {
+	profileID, err := getProfileID(c)
+	if err != nil {
+		return err
+	}
+	sessions, err := h.sessionStore.List(c.Request().Context(), profileID)
+	if err != nil {
+		logrus.Errorf("apiGetSessions: %v", err)
+		return apiError(c, err, "Failed to get sessions")
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].IssuedAt.After(sessions[j].IssuedAt)
+	})
+	currentID := h.currentSessionID(c)
+	resp := make([]sessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		resp = append(resp, sessionResponse{
+			ID:        sessionHandle(session.ID),
+			CreatedAt: session.IssuedAt,
+			LastSeen:  session.LastSeen,
+			IP:        session.IP,
+			UserAgent: session.UserAgent,
+			Current:   session.ID == currentID,
+		})
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// APIRevokeSession logs out one of sessions of user by its public id
+func (h *Handler) APIRevokeSession(c echo.Context) error {
+	profileID, err := getProfileID(c)
+	if err != nil {
+		return err
+	}
+	sessions, err := h.sessionStore.List(c.Request().Context(), profileID)
+	if err != nil {
+		logrus.Errorf("apiRevokeSession: %v", err)
+		return apiError(c, err, "Failed to get sessions")
+	}
+	for _, session := range sessions {
+		if sessionHandle(session.ID) != c.Param("id") {
+			continue
+		}
+		if err = h.sessionStore.Delete(c.Request().Context(), session.ID); err != nil {
+			logrus.Errorf("apiRevokeSession: %v", err)
+			return apiError(c, err, "Failed to revoke session")
+		}
+		if session.ID == h.currentSessionID(c) {
+			c.SetCookie(h.sessionCookie("", -1))
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+	return apiError(c, berrors.New(berrors.NotFound, "Session not found"), "")
+}
+
+// APIRevokeOtherSessions logs out all sessions of user except the current one
+func (h *Handler) APIRevokeOtherSessions(c echo.Context) error {
+	profileID, err := getProfileID(c)
+	if err != nil {
+		return err
+	}
+	revoked, err := h.revokeSessions(c.Request().Context(), profileID, h.currentSessionID(c))
+	if err != nil {
+		logrus.Errorf("apiRevokeOtherSessions: %v", err)
+		return apiError(c, err, "Failed to revoke sessions")
+	}
+	return c.JSON(http.StatusOK, revokeSessionsResponse{Revoked: revoked})
+}
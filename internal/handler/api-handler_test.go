@@ -0,0 +1,364 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/handler/mocks"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIGetPrices(t *testing.T) {
+	srv := new(mocks.TradingService)
+	hndl := NewHandler(Dependencies{TradingService: srv}, v, cfg)
+	testShares := []model.Share{testShare}
+	srv.On("GetPriceSnapshot", mock.Anything).Return(&model.PriceSnapshot{Shares: testShares}, nil).Once()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/prices", http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := hndl.APIGetPrices(c)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var shares []model.Share
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &shares))
+	require.Equal(t, testShares, shares)
+	srv.AssertExpectations(t)
+}
+
+func TestAPISignUpInvalidFields(t *testing.T) {
+	srv := new(mocks.UserService)
+	hndl := NewHandler(Dependencies{UserService: srv}, v, cfg)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/signup", strings.NewReader(`{"login":"test","password":"short"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := hndl.APISignUp(c)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	var resp errorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, berrors.InvalidRequest, resp.Code)
+	require.False(t, resp.Retryable)
+	srv.AssertExpectations(t)
+}
+
+func TestAPIDepositUnauthorized(t *testing.T) {
+	srv := new(mocks.BalanceService)
+	hndl := NewHandler(Dependencies{BalanceService: srv}, v, cfg)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/deposits", strings.NewReader(`{"operation":100}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := hndl.APIDeposit(c)
+	require.ErrorIs(t, err, echo.ErrUnauthorized)
+	srv.AssertExpectations(t)
+}
+
+func TestAPIGetPricesUnavailable(t *testing.T) {
+	srv := new(mocks.TradingService)
+	hndl := NewHandler(Dependencies{TradingService: srv}, v, cfg)
+	srv.On("GetPriceSnapshot", mock.Anything).Return(nil, berrors.New(berrors.Unavailable, "Service is temporarily unavailable")).Once()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/prices", http.NoBody)
+	req.Header.Set(echo.HeaderXRequestID, "test-request")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := hndl.APIGetPrices(c)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	var resp errorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, berrors.Unavailable, resp.Code)
+	require.Equal(t, "test-request", resp.RequestID)
+	require.True(t, resp.Retryable)
+	srv.AssertExpectations(t)
+}
+
+func TestAPILoginBruteForce(t *testing.T) {
+	srv := new(mocks.UserService)
+	guard := new(mocks.LoginGuard)
+	hndl := NewHandler(Dependencies{UserService: srv, LoginGuard: guard}, v, cfg)
+	body := `{"login":"testLogin","password":"wrongPassword"}`
+	guard.On("Check", mock.Anything, testUser.Login, mock.AnythingOfType("string")).Return(nil).Once()
+	srv.On("GetByLogin", mock.Anything, mock.AnythingOfType("*model.User")).
+		Return(uuid.Nil, berrors.New(berrors.UserDoesntExists, "User doesnt exist")).Once()
+	guard.On("Fail", mock.Anything, testUser.Login, mock.AnythingOfType("string")).Return(nil).Once()
+	guard.On("Check", mock.Anything, testUser.Login, mock.AnythingOfType("string")).
+		Return(berrors.New(berrors.TooManyLoginAttempts, "Too many login attempts, try again in 2 seconds")).Once()
+
+	e := echo.New()
+	for _, expected := range []struct {
+		status int
+		code   string
+	}{
+		{http.StatusUnauthorized, berrors.Unauthorized},
+		{http.StatusTooManyRequests, berrors.TooManyLoginAttempts},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		err := hndl.APILogin(e.NewContext(req, rec))
+		require.NoError(t, err)
+		require.Equal(t, expected.status, rec.Code)
+		var resp errorResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		require.Equal(t, expected.code, resp.Code)
+	}
+	srv.AssertExpectations(t)
+	guard.AssertExpectations(t)
+}
+
+func TestAPIGetPricesNotModified(t *testing.T) {
+	srv := new(mocks.TradingService)
+	hndl := NewHandler(Dependencies{TradingService: srv}, v, cfg)
+	snapshot := &model.PriceSnapshot{
+		Shares:    []model.Share{testShare},
+		ETag:      `"testETag"`,
+		UpdatedAt: time.Date(2023, time.October, 1, 12, 0, 0, 0, time.UTC),
+	}
+	srv.On("GetPriceSnapshot", mock.Anything).Return(snapshot, nil)
+
+	testCases := []struct {
+		name     string
+		header   string
+		value    string
+		expected int
+	}{
+		{"no validators", "", "", http.StatusOK},
+		{"same etag", "If-None-Match", `W/"other", "testETag"`, http.StatusNotModified},
+		{"changed etag", "If-None-Match", `"other"`, http.StatusOK},
+		{"not modified since", echo.HeaderIfModifiedSince, snapshot.UpdatedAt.Format(http.TimeFormat), http.StatusNotModified},
+		{"modified since", echo.HeaderIfModifiedSince, snapshot.UpdatedAt.Add(-time.Second).Format(http.TimeFormat), http.StatusOK},
+	}
+	e := echo.New()
+	for _, tc := range testCases {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/prices", http.NoBody)
+		if tc.header != "" {
+			req.Header.Set(tc.header, tc.value)
+		}
+		rec := httptest.NewRecorder()
+		err := hndl.APIGetPrices(e.NewContext(req, rec))
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.expected, rec.Code, tc.name)
+		require.Equal(t, snapshot.ETag, rec.Header().Get("ETag"), tc.name)
+		require.Equal(t, "Sun, 01 Oct 2023 12:00:00 GMT", rec.Header().Get(echo.HeaderLastModified), tc.name)
+	}
+}
+
+func TestAPIGetCandles(t *testing.T) {
+	history := new(mocks.PriceHistory)
+	hndl := NewHandler(Dependencies{PriceHistory: history}, v, cfg)
+	from := time.Date(2023, time.October, 1, 12, 0, 0, 0, time.UTC)
+	candles := []model.Candle{{Time: from, Open: decimal.NewFromInt(1), High: decimal.NewFromInt(3),
+		Low: decimal.RequireFromString("0.5"), Close: decimal.NewFromInt(2)}}
+	history.On("GetCandles", mock.Anything, testShare.Company, "5m", from, time.Time{}).Return(candles, nil).Once()
+	history.On("GetCandles", mock.Anything, testShare.Company, "2m", time.Time{}, time.Time{}).
+		Return(nil, berrors.New(berrors.InvalidRequest, "Interval must be one of 1m, 5m, 1h, 1d")).Once()
+
+	e := echo.New()
+	testCases := []struct {
+		name     string
+		query    string
+		expected int
+	}{
+		{"candles", "?interval=5m&from=2023-10-01T12:00:00Z", http.StatusOK},
+		{"invalid interval", "?interval=2m", http.StatusBadRequest},
+		{"invalid time", "?interval=5m&from=yesterday", http.StatusBadRequest},
+	}
+	for _, tc := range testCases {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/prices/"+testShare.Company+"/candles"+tc.query, http.NoBody)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("company")
+		c.SetParamValues(testShare.Company)
+		require.NoError(t, hndl.APIGetCandles(c), tc.name)
+		require.Equal(t, tc.expected, rec.Code, tc.name)
+	}
+	history.AssertExpectations(t)
+}
+
+func TestAPIGetPortfolio(t *testing.T) {
+	srv := new(mocks.PortfolioService)
+	hndl := NewHandler(Dependencies{Portfolio: srv}, v, cfg)
+	profileID := uuid.New()
+	portfolio := &model.Portfolio{Balance: decimal.NewFromInt(100), Equity: decimal.NewFromInt(150)}
+	srv.On("GetPortfolio", mock.Anything, profileID).Return(portfolio, nil).Once()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/portfolio", http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(profileIDKey, profileID)
+	require.NoError(t, hndl.APIGetPortfolio(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp model.Portfolio
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, "150", resp.Equity.String())
+	srv.AssertExpectations(t)
+}
+
+func TestAPIGetAnalytics(t *testing.T) {
+	srv := new(mocks.AnalyticsService)
+	hndl := NewHandler(Dependencies{Analytics: srv}, v, cfg)
+	profileID := uuid.New()
+	from := time.Date(2023, time.October, 1, 0, 0, 0, 0, time.UTC)
+	analytics := &model.TradeAnalytics{From: from, Total: &model.TradeStats{Trades: 2}}
+	srv.On("GetAnalytics", mock.Anything, profileID, from, time.Time{}, testShare.Company).Return(analytics, nil).Once()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/analytics?from=2023-10-01T00:00:00Z&company="+testShare.Company, http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(profileIDKey, profileID)
+	require.NoError(t, hndl.APIGetAnalytics(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp model.TradeAnalytics
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, 2, resp.Total.Trades)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/analytics?to=tomorrow", http.NoBody)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+	c.Set(profileIDKey, profileID)
+	require.NoError(t, hndl.APIGetAnalytics(c))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	srv.AssertExpectations(t)
+}
+
+func TestAPIGetStatement(t *testing.T) {
+	ledger := new(mocks.Ledger)
+	hndl := NewHandler(Dependencies{Ledger: ledger}, v, cfg)
+	profileID := uuid.New()
+	from := time.Date(2023, time.October, 1, 0, 0, 0, 0, time.UTC)
+	statement := &model.Statement{From: from, OpeningBalance: decimal.NewFromInt(10), Lines: []*model.StatementLine{}}
+	ledger.On("GetStatement", mock.Anything, profileID, from, time.Time{}, 20, "testCursor").Return(statement, nil).Once()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/statement?from=2023-10-01T00:00:00Z&limit=20&cursor=testCursor", http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(profileIDKey, profileID)
+	require.NoError(t, hndl.APIGetStatement(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp model.Statement
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, "10", resp.OpeningBalance.String())
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/statement?limit=all", http.NoBody)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+	c.Set(profileIDKey, profileID)
+	require.NoError(t, hndl.APIGetStatement(c))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	ledger.AssertExpectations(t)
+}
+
+func TestAPIPlaceOrder(t *testing.T) {
+	srv := new(mocks.OrderService)
+	hndl := NewHandler(Dependencies{Orders: srv}, v, cfg)
+	profileID := uuid.New()
+	srv.On("PlaceOrder", mock.Anything, mock.MatchedBy(func(order *model.Order) bool {
+		return order.ProfileID == profileID && order.Type == "limit" && order.TriggerPrice.String() == "180"
+	})).Return(nil).Once()
+
+	e := echo.New()
+	body := `{"type":"limit","company":"Apple","sharescount":"10","triggerprice":"180","stoploss":"170","takeprofit":"200"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/orders", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(profileIDKey, profileID)
+	require.NoError(t, hndl.APIPlaceOrder(c))
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	body = `{"type":"market","company":"Apple","sharescount":"10","triggerprice":"180","stoploss":"170","takeprofit":"200"}`
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/orders", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+	c.Set(profileIDKey, profileID)
+	require.NoError(t, hndl.APIPlaceOrder(c))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	srv.AssertExpectations(t)
+}
+
+func TestAPICancelOrder(t *testing.T) {
+	srv := new(mocks.OrderService)
+	hndl := NewHandler(Dependencies{Orders: srv}, v, cfg)
+	profileID, orderID := uuid.New(), uuid.New()
+	srv.On("CancelOrder", mock.Anything, profileID, orderID).
+		Return(nil, berrors.New(berrors.OrderNotPending, "Order is already filled")).Once()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/orders/"+orderID.String(), http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(orderID.String())
+	c.Set(profileIDKey, profileID)
+	require.NoError(t, hndl.APICancelOrder(c))
+	require.Equal(t, http.StatusConflict, rec.Code)
+	var resp errorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, berrors.OrderNotPending, resp.Code)
+	srv.AssertExpectations(t)
+}
+
+func TestAPIAttachTrailingStop(t *testing.T) {
+	srv := new(mocks.TrailingStops)
+	hndl := NewHandler(Dependencies{TrailingStops: srv}, v, cfg)
+	profileID, dealID := uuid.New(), uuid.New()
+	srv.On("Attach", mock.Anything, mock.MatchedBy(func(stop *model.TrailingStop) bool {
+		return stop.ProfileID == profileID && stop.DealID == dealID && stop.Type == "percent" && stop.Distance.String() == "2.5"
+	})).Run(func(args mock.Arguments) {
+		args.Get(1).(*model.TrailingStop).EffectiveStop = decimal.RequireFromString("195")
+	}).Return(nil).Once()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/positions/"+dealID.String()+"/trailing-stop",
+		strings.NewReader(`{"type":"percent","distance":"2.5"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(dealID.String())
+	c.Set(profileIDKey, profileID)
+	require.NoError(t, hndl.APIAttachTrailingStop(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp model.TrailingStop
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, "195", resp.EffectiveStop.String())
+
+	req = httptest.NewRequest(http.MethodPut, "/api/v1/positions/"+dealID.String()+"/trailing-stop",
+		strings.NewReader(`{"type":"points","distance":"2.5"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(dealID.String())
+	c.Set(profileIDKey, profileID)
+	require.NoError(t, hndl.APIAttachTrailingStop(c))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	srv.AssertExpectations(t)
+}
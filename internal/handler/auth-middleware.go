@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// profileIDKey is a key of echo context where Authenticate middleware stores id of profile
+const profileIDKey = "profileID"
+
+// Authenticate is middleware that resolves id of profile from bearer token or session once per request
+// and stores it in the context. Browsers without session are redirected to auth page, API clients get 401.
+func (h *Handler) Authenticate(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		profileID, err := h.resolveProfileID(c)
+		if err != nil {
+			logrus.Errorf("authenticate: %v", err)
+			if wantsJSON(c) {
+				return err
+			}
+			return c.Redirect(http.StatusSeeOther, "/")
+		}
+		c.Set(profileIDKey, profileID)
+		return next(c)
+	}
+}
+
+// wantsJSON uses content negotiation to decide if client expects JSON instead of HTML page
+func wantsJSON(c echo.Context) bool {
+	if strings.HasPrefix(c.Request().URL.Path, "/api/") {
+		return true
+	}
+	if _, ok := bearerToken(c); ok {
+		return true
+	}
+	accept := c.Request().Header.Get(echo.HeaderAccept)
+	return strings.Contains(accept, echo.MIMEApplicationJSON) && !strings.Contains(accept, echo.MIMETextHTML)
+}
+
+// resolveProfileID gets id of profile from bearer token or from session if there is no token
+func (h *Handler) resolveProfileID(c echo.Context) (uuid.UUID, error) {
+	if token, ok := bearerToken(c); ok {
+		profileID, err := h.tokenService.ParseAccessToken(c.Request().Context(), token)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		return profileID, nil
+	}
+	session, err := h.loadSession(c)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if session == nil || session.ProfileID == uuid.Nil {
+		return uuid.Nil, echo.ErrUnauthorized
+	}
+	if errTouch := h.touchSession(c.Request().Context(), session); errTouch != nil {
+		logrus.Errorf("resolveProfileID: %v", errTouch)
+	}
+	return session.ProfileID, nil
+}
+
+// getProfileID returns id of profile which was resolved by Authenticate middleware
+func getProfileID(c echo.Context) (uuid.UUID, error) {
+	profileID, ok := c.Get(profileIDKey).(uuid.UUID)
+	if !ok || profileID == uuid.Nil {
+		return uuid.Nil, echo.ErrUnauthorized
+	}
+	return profileID, nil
+}
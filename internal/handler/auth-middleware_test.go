@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/artnikel/APIService/internal/handler/mocks"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticateWithBearerToken(t *testing.T) {
+	bsrv := new(mocks.BalanceService)
+	tokenSrv := new(mocks.TokenService)
+	hndl := NewHandler(Dependencies{BalanceService: bsrv, TokenService: tokenSrv}, v, cfg)
+	tokenSrv.On("ParseAccessToken", mock.Anything, "testAccessToken").Return(testBalance.ProfileID, nil).Once()
+	bsrv.On("GetBalance", mock.Anything, testBalance.ProfileID).Return(testBalance.Operation, nil).Once()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/balance", http.NoBody)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer testAccessToken")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := hndl.Authenticate(hndl.APIGetBalance)(c)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp balanceResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.True(t, testBalance.Operation.Equal(resp.Balance))
+	tokenSrv.AssertExpectations(t)
+	bsrv.AssertExpectations(t)
+}
+
+func TestAuthenticateRedirectsBrowser(t *testing.T) {
+	hndl := NewHandler(Dependencies{}, v, cfg)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/index", http.NoBody)
+	req.Header.Set(echo.HeaderAccept, "text/html,application/xhtml+xml")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := hndl.Authenticate(hndl.Index)(c)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusSeeOther, rec.Code)
+	require.Equal(t, "/", rec.Header().Get(echo.HeaderLocation))
+}
+
+func TestAuthenticateRejectsAPIClient(t *testing.T) {
+	hndl := NewHandler(Dependencies{}, v, cfg)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/getunclosed", http.NoBody)
+	req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := hndl.Authenticate(hndl.GetUnclosedPositions)(c)
+	require.ErrorIs(t, err, echo.ErrUnauthorized)
+}
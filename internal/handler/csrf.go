@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// csrfFormField is a name of hidden field of forms with CSRF token
+	csrfFormField = "csrf"
+	// csrfHeader is a header with CSRF token for requests sent by scripts
+	csrfHeader = "X-CSRF-Token"
+)
+
+// CSRF is middleware that checks CSRF token of every request with unsafe method.
+// Token of authenticated browser is kept in its session, before log in token is compared with CSRF cookie.
+// Requests authenticated by bearer token are exempt because browsers never attach it automatically,
+// as well as API requests without session which a foreign page can't send as a simple form.
+func (h *Handler) CSRF(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		switch c.Request().Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+			return next(c)
+		}
+		if _, ok := bearerToken(c); ok {
+			return next(c)
+		}
+		session, err := h.loadSession(c)
+		if err != nil {
+			logrus.Errorf("csrf: %v", err)
+			return echo.ErrInternalServerError
+		}
+		if session == nil && strings.HasPrefix(c.Request().URL.Path, "/api/") &&
+			strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON) {
+			return next(c)
+		}
+		expected, err := h.expectedCSRFToken(c)
+		if err != nil {
+			logrus.Errorf("csrf: %v", err)
+			return echo.ErrInternalServerError
+		}
+		token := c.Request().Header.Get(csrfHeader)
+		if token == "" {
+			token = c.FormValue(csrfFormField)
+		}
+		if expected == "" || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			logrus.WithField("Path", c.Request().URL.Path).Error("csrf: invalid token")
+			return echo.NewHTTPError(http.StatusForbidden, "Invalid CSRF token")
+		}
+		return next(c)
+	}
+}
+
+// expectedCSRFToken returns token of the session or token of CSRF cookie if there is no session
+func (h *Handler) expectedCSRFToken(c echo.Context) (string, error) {
+	session, err := h.loadSession(c)
+	if err != nil {
+		return "", fmt.Errorf("loadSession %w", err)
+	}
+	if session != nil {
+		return session.CSRFToken, nil
+	}
+	cookie, err := c.Cookie(h.cfg.CSRFCookieName)
+	if err != nil {
+		return "", nil
+	}
+	return cookie.Value, nil
+}
+
+// csrfToken returns token which pages put into their forms, creating CSRF cookie for browsers without session
+func (h *Handler) csrfToken(c echo.Context) (string, error) {
+	token, err := h.expectedCSRFToken(c)
+	if err != nil {
+		return "", fmt.Errorf("expectedCSRFToken %w", err)
+	}
+	if token != "" {
+		return token, nil
+	}
+	token, err = newSessionID()
+	if err != nil {
+		return "", fmt.Errorf("newSessionID %w", err)
+	}
+	cookie := h.sessionCookie(token, 0)
+	cookie.Name = h.cfg.CSRFCookieName
+	c.SetCookie(cookie)
+	return token, nil
+}
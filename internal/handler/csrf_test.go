@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/artnikel/APIService/internal/repository"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSRF(t *testing.T) {
+	store := repository.NewMemorySessionRepository()
+	hndl := NewHandler(Dependencies{SessionStore: store}, v, cfg)
+	session := &model.Session{ID: "testSession", ProfileID: testUser.ID, CSRFToken: "sessionToken",
+		LastSeen: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}
+	require.NoError(t, store.Save(context.Background(), session, time.Hour))
+	sessionCookie := &http.Cookie{Name: cfg.SessionCookieName, Value: session.ID}
+	anonymousCookie := &http.Cookie{Name: cfg.CSRFCookieName, Value: "anonymousToken"}
+
+	testCases := []struct {
+		name     string
+		method   string
+		path     string
+		cookie   *http.Cookie
+		form     string
+		header   map[string]string
+		expected int
+	}{
+		{name: "safe method", method: http.MethodGet, path: "/index", cookie: sessionCookie, expected: http.StatusOK},
+		{name: "session without token", method: http.MethodPost, path: "/deposit", cookie: sessionCookie,
+			expected: http.StatusForbidden},
+		{name: "session with wrong token", method: http.MethodPost, path: "/delete", cookie: sessionCookie,
+			form: "anonymousToken", expected: http.StatusForbidden},
+		{name: "session with form token", method: http.MethodPost, path: "/withdraw", cookie: sessionCookie,
+			form: "sessionToken", expected: http.StatusOK},
+		{name: "session with header token", method: http.MethodDelete, path: "/api/v1/sessions", cookie: sessionCookie,
+			header: map[string]string{csrfHeader: "sessionToken"}, expected: http.StatusOK},
+		{name: "session api json without token", method: http.MethodPost, path: "/api/v1/deposits", cookie: sessionCookie,
+			header: map[string]string{echo.HeaderContentType: echo.MIMEApplicationJSON}, expected: http.StatusForbidden},
+		{name: "log in without cookie", method: http.MethodPost, path: "/login", form: "anonymousToken",
+			expected: http.StatusForbidden},
+		{name: "log in with cookie", method: http.MethodPost, path: "/login", cookie: anonymousCookie,
+			form: "anonymousToken", expected: http.StatusOK},
+		{name: "bearer token", method: http.MethodPost, path: "/api/v1/deposits", cookie: sessionCookie,
+			header: map[string]string{echo.HeaderAuthorization: "Bearer token"}, expected: http.StatusOK},
+		{name: "api json without session", method: http.MethodPost, path: "/api/v1/auth/login",
+			header: map[string]string{echo.HeaderContentType: echo.MIMEApplicationJSON}, expected: http.StatusOK},
+		{name: "api form without session", method: http.MethodPost, path: "/api/v1/auth/login",
+			form: "", expected: http.StatusForbidden},
+	}
+	e := echo.New()
+	next := func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}
+	for _, tc := range testCases {
+		formData := url.Values{}
+		if tc.form != "" {
+			formData.Set(csrfFormField, tc.form)
+		}
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(formData.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		for key, value := range tc.header {
+			req.Header.Set(key, value)
+		}
+		if tc.cookie != nil {
+			req.AddCookie(tc.cookie)
+		}
+		rec := httptest.NewRecorder()
+		err := hndl.CSRF(next)(e.NewContext(req, rec))
+		if tc.expected == http.StatusForbidden {
+			var he *echo.HTTPError
+			require.ErrorAs(t, err, &he, tc.name)
+			require.Equal(t, http.StatusForbidden, he.Code, tc.name)
+			continue
+		}
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.expected, rec.Code, tc.name)
+	}
+}
+
+func TestAuthPageSetsCSRFCookie(t *testing.T) {
+	hndl := NewHandler(Dependencies{SessionStore: repository.NewMemorySessionRepository()}, v, cfg)
+	originalDir, err := os.Getwd()
+	require.NoError(t, err)
+	defer func() {
+		errCh := os.Chdir(originalDir)
+		require.NoError(t, errCh)
+	}()
+	err = os.Chdir("../..")
+	require.NoError(t, err)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	rec := httptest.NewRecorder()
+	err = hndl.Auth(e.NewContext(req, rec))
+	require.NoError(t, err)
+
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	require.Equal(t, cfg.CSRFCookieName, cookies[0].Name)
+	require.Contains(t, rec.Body.String(), `name="csrf" value="`+cookies[0].Value+`"`)
+}
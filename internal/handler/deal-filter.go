@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/labstack/echo/v4"
+)
+
+// nextCursorHeader is a header of response with cursor of the next page of deals
+const nextCursorHeader = "X-Next-Cursor"
+
+// parseDealFilter reads filter of deals from query parameters:
+// company, from, to, closedfrom, closedto (RFC 3339), profit (positive, negative, zero),
+// sort with optional "-" prefix for descending order, limit and cursor
+func parseDealFilter(c echo.Context) (*model.DealFilter, error) {
+	filter := &model.DealFilter{
+		Company: c.QueryParam("company"),
+		Profit:  c.QueryParam("profit"),
+		Cursor:  c.QueryParam("cursor"),
+	}
+	bounds := []struct {
+		name   string
+		target *time.Time
+	}{
+		{"from", &filter.From},
+		{"to", &filter.To},
+		{"closedfrom", &filter.ClosedFrom},
+		{"closedto", &filter.ClosedTo},
+	}
+	var err error
+	for _, bound := range bounds {
+		if *bound.target, err = parseTimeParam(c, bound.name); err != nil {
+			return nil, berrors.New(berrors.InvalidRequest, "Parameter "+bound.name+" must be a time in RFC 3339 format")
+		}
+	}
+	sortBy := c.QueryParam("sort")
+	filter.Descending = strings.HasPrefix(sortBy, "-")
+	filter.SortBy = strings.TrimPrefix(sortBy, "-")
+	if limit := c.QueryParam("limit"); limit != "" {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil {
+			return nil, berrors.New(berrors.InvalidRequest, "Limit must be a number")
+		}
+	}
+	return filter, nil
+}
+
+// dealPage writes deals of page, cursor of the next page is sent in header so body stays a list of deals
+func dealPage(c echo.Context, page *model.DealPage) error {
+	if page.NextCursor != "" {
+		c.Response().Header().Set(nextCursorHeader, page.NextCursor)
+	}
+	return c.JSON(http.StatusOK, page.Deals)
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/artnikel/APIService/internal/handler/mocks"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIGetClosedPositionsFilter(t *testing.T) {
+	tsrv := new(mocks.TradingService)
+	hndl := NewHandler(Dependencies{TradingService: tsrv}, v, cfg)
+	profileID := uuid.New()
+	filter := &model.DealFilter{
+		Company:    testShare.Company,
+		ClosedFrom: time.Date(2023, time.October, 1, 0, 0, 0, 0, time.UTC),
+		Profit:     "negative",
+		SortBy:     "profit",
+		Descending: true,
+		Limit:      1,
+		Cursor:     "testCursor",
+	}
+	page := &model.DealPage{Deals: []*model.Deal{&testDeal}, NextCursor: "nextCursor"}
+	tsrv.On("FindClosedPositions", mock.Anything, profileID, filter).Return(page, nil).Once()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/positions/closed?company="+testShare.Company+
+		"&closedfrom=2023-10-01T00:00:00Z&profit=negative&sort=-profit&limit=1&cursor=testCursor", http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(profileIDKey, profileID)
+	require.NoError(t, hndl.APIGetClosedPositions(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "nextCursor", rec.Header().Get(nextCursorHeader))
+	tsrv.AssertExpectations(t)
+
+	for _, query := range []string{"limit=many", "from=yesterday"} {
+		req = httptest.NewRequest(http.MethodGet, "/api/v1/positions/closed?"+query, http.NoBody)
+		rec = httptest.NewRecorder()
+		c = e.NewContext(req, rec)
+		c.Set(profileIDKey, profileID)
+		require.NoError(t, hndl.APIGetClosedPositions(c), query)
+		require.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
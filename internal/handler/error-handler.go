@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// errorResponse is a body of response for every failed API request
+type errorResponse struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"requestid"`
+	Retryable bool   `json:"retryable"`
+}
+
+// requestID returns id of request set by RequestID middleware
+func requestID(c echo.Context) string {
+	id := c.Response().Header().Get(echo.HeaderXRequestID)
+	if id == "" {
+		id = c.Request().Header.Get(echo.HeaderXRequestID)
+	}
+	return id
+}
+
+// apiError writes error envelope with status, code and message taken from the mapping table of business errors.
+// Errors without business code are hidden behind the given message.
+func apiError(c echo.Context, err error, message string) error {
+	code, msg, rule := berrors.Translate(err, message)
+	return c.JSON(rule.Status, errorResponse{
+		Code:      code,
+		Message:   msg,
+		RequestID: requestID(c),
+		Retryable: rule.Retryable,
+	})
+}
+
+// apiBadRequest writes error envelope for request which can`t be read or validated
+func apiBadRequest(c echo.Context, message string) error {
+	return apiError(c, berrors.New(berrors.InvalidRequest, message), message)
+}
+
+// codeForStatus returns error code of the mapping table for HTTP status of echo error,
+// other client errors are reported as invalid request so they are never taken for faults of server
+func codeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return berrors.InvalidRequest
+	case http.StatusUnauthorized:
+		return berrors.Unauthorized
+	case http.StatusForbidden:
+		return berrors.PermissionDenied
+	case http.StatusNotFound:
+		return berrors.NotFound
+	case http.StatusMethodNotAllowed:
+		return berrors.MethodNotAllowed
+	case http.StatusConflict:
+		return berrors.Conflict
+	case http.StatusRequestEntityTooLarge:
+		return berrors.RequestTooLarge
+	case http.StatusUnsupportedMediaType:
+		return berrors.UnsupportedMediaType
+	case http.StatusTooManyRequests:
+		return berrors.TooManyRequests
+	case http.StatusServiceUnavailable:
+		return berrors.Unavailable
+	case http.StatusGatewayTimeout:
+		return berrors.DeadlineExceeded
+	}
+	if status >= http.StatusBadRequest && status < http.StatusInternalServerError {
+		return berrors.InvalidRequest
+	}
+	return berrors.Internal
+}
+
+// HTTPErrorHandler writes error envelope for failed requests of API and uses default echo handler for the pages
+func HTTPErrorHandler(err error, c echo.Context) {
+	if !strings.HasPrefix(c.Request().URL.Path, "/api/") {
+		c.Echo().DefaultHTTPErrorHandler(err, c)
+		return
+	}
+	if c.Response().Committed {
+		return
+	}
+	var errWrite error
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		message, ok := he.Message.(string)
+		if !ok {
+			message = http.StatusText(he.Code)
+		}
+		code := codeForStatus(he.Code)
+		errWrite = c.JSON(he.Code, errorResponse{
+			Code:      code,
+			Message:   message,
+			RequestID: requestID(c),
+			Retryable: berrors.Lookup(code).Retryable,
+		})
+	} else {
+		errWrite = apiError(c, err, "Internal error")
+	}
+	if errWrite != nil {
+		logrus.Errorf("httpErrorHandler: %v", errWrite)
+	}
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPErrorHandlerMapsStatuses(t *testing.T) {
+	testCases := []struct {
+		err       error
+		status    int
+		code      string
+		retryable bool
+	}{
+		{echo.ErrBadRequest, http.StatusBadRequest, berrors.InvalidRequest, false},
+		{echo.ErrNotFound, http.StatusNotFound, berrors.NotFound, false},
+		{echo.ErrMethodNotAllowed, http.StatusMethodNotAllowed, berrors.MethodNotAllowed, false},
+		{echo.NewHTTPError(http.StatusConflict), http.StatusConflict, berrors.Conflict, false},
+		{echo.ErrStatusRequestEntityTooLarge, http.StatusRequestEntityTooLarge, berrors.RequestTooLarge, false},
+		{echo.ErrUnsupportedMediaType, http.StatusUnsupportedMediaType, berrors.UnsupportedMediaType, false},
+		{echo.ErrTooManyRequests, http.StatusTooManyRequests, berrors.TooManyRequests, true},
+		{echo.NewHTTPError(http.StatusTeapot), http.StatusTeapot, berrors.InvalidRequest, false},
+		{echo.ErrInternalServerError, http.StatusInternalServerError, berrors.Internal, false},
+	}
+	for _, tc := range testCases {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/deposits", http.NoBody)
+		rec := httptest.NewRecorder()
+		HTTPErrorHandler(tc.err, e.NewContext(req, rec))
+		require.Equal(t, tc.status, rec.Code, tc.code)
+		var resp errorResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		require.Equal(t, tc.code, resp.Code)
+		require.Equal(t, tc.retryable, resp.Retryable, tc.code)
+	}
+}
@@ -0,0 +1,316 @@
+package handler
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// exportFlushRows is a count of rows after which written part of export is flushed to client
+const exportFlushRows = 100
+
+// tradeColumns are columns of exported trades in order of fields of model.TradeRow
+var tradeColumns = []string{ // nolint gochecknoglobals
+	"dealid", "status", "company", "direction", "sharescount", "purchaseprice",
+	"stoploss", "takeprofit", "dealtime", "enddealtime", "currentprice", "profit",
+}
+
+// tradeWriter encodes trades into file of export
+type tradeWriter interface {
+	WriteRow(row *model.TradeRow) error
+	Flush() error
+	Close() error
+}
+
+// exportFormats contains content types and constructors of writers of export formats
+var exportFormats = map[string]struct { // nolint gochecknoglobals
+	contentType string
+	newWriter   func(w io.Writer) (tradeWriter, error)
+}{
+	"csv":   {"text/csv; charset=utf-8", newCSVTradeWriter},
+	"jsonl": {"application/x-ndjson", newJSONLTradeWriter},
+	"xlsx":  {"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", newXLSXTradeWriter},
+}
+
+// APIExportTrades streams trade history of user as file of format from query parameter: csv, jsonl or xlsx.
+// Deals are selected by the same parameters as list of closed positions except limit and cursor, file always
+// contains all selected deals. Parameter open=true adds open deals valued with current prices
+func (h *Handler) APIExportTrades(c echo.Context) error {
+	profileID, err := getProfileID(c)
+	if err != nil {
+		return err
+	}
+	format := c.QueryParam("format")
+	if format == "" {
+		format = "csv"
+	}
+	exportFormat, ok := exportFormats[format]
+	if !ok {
+		return apiBadRequest(c, "Format must be csv, jsonl or xlsx")
+	}
+	if c.QueryParam("limit") != "" || c.QueryParam("cursor") != "" {
+		return apiBadRequest(c, "Export contains all selected positions, limit and cursor aren't supported")
+	}
+	filter, err := parseDealFilter(c)
+	if err != nil {
+		return apiError(c, err, "Invalid filter of positions")
+	}
+	includeOpen := c.QueryParam("open") == "true"
+	var writer tradeWriter
+	rows := 0
+	err = h.exports.ExportTrades(c.Request().Context(), profileID, filter, includeOpen, func(row *model.TradeRow) error {
+		if writer == nil {
+			var errStart error
+			if writer, errStart = startExport(c, exportFormat.contentType, exportFilename(filter, format), exportFormat.newWriter); errStart != nil {
+				return errStart
+			}
+		}
+		if errWrite := writer.WriteRow(row); errWrite != nil {
+			return fmt.Errorf("writeRow %w", errWrite)
+		}
+		if rows++; rows%exportFlushRows == 0 {
+			if errFlush := writer.Flush(); errFlush != nil {
+				return fmt.Errorf("flush %w", errFlush)
+			}
+			c.Response().Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		logrus.Errorf("apiExportTrades: %v", err)
+		if writer == nil {
+			return apiError(c, err, "Failed to export trades")
+		}
+		// headers were already sent, so client gets incomplete file instead of error
+		return nil
+	}
+	if writer == nil {
+		if writer, err = startExport(c, exportFormat.contentType, exportFilename(filter, format), exportFormat.newWriter); err != nil {
+			logrus.Errorf("apiExportTrades: %v", err)
+			return nil
+		}
+	}
+	if err = writer.Close(); err != nil {
+		logrus.Errorf("apiExportTrades: %v", err)
+	}
+	return nil
+}
+
+// startExport sends headers of export and creates writer of its body, nothing is sent before the first row
+// so errors of getting deals can still be returned as JSON
+func startExport(c echo.Context, contentType, filename string, newWriter func(w io.Writer) (tradeWriter, error)) (tradeWriter, error) {
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, contentType)
+	header.Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	header.Set(echo.HeaderCacheControl, "no-store")
+	c.Response().WriteHeader(http.StatusOK)
+	writer, err := newWriter(c.Response())
+	if err != nil {
+		return nil, fmt.Errorf("newWriter %w", err)
+	}
+	return writer, nil
+}
+
+// exportFilename returns name of file of export with range of entry time if it is set
+func exportFilename(filter *model.DealFilter, format string) string {
+	name := "trades"
+	if !filter.From.IsZero() {
+		name += "-from-" + filter.From.UTC().Format("2006-01-02")
+	}
+	if !filter.To.IsZero() {
+		name += "-to-" + filter.To.UTC().Format("2006-01-02")
+	}
+	return name + "." + format
+}
+
+// tradeValues returns values of row in order of tradeColumns, decimals keep all their digits
+func tradeValues(row *model.TradeRow) []string {
+	var endDealTime, currentPrice string
+	if row.EndDealTime != nil {
+		endDealTime = row.EndDealTime.UTC().Format(time.RFC3339)
+	}
+	if row.CurrentPrice != nil {
+		currentPrice = row.CurrentPrice.String()
+	}
+	return []string{
+		row.DealID.String(), row.Status, row.Company, row.Direction, row.SharesCount.String(), row.PurchasePrice.String(),
+		row.StopLoss.String(), row.TakeProfit.String(), row.DealTime.UTC().Format(time.RFC3339), endDealTime, currentPrice,
+		row.Profit.String(),
+	}
+}
+
+// csvTradeWriter writes trades as CSV with header
+type csvTradeWriter struct {
+	w *csv.Writer
+}
+
+func newCSVTradeWriter(w io.Writer) (tradeWriter, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(tradeColumns); err != nil {
+		return nil, fmt.Errorf("write %w", err)
+	}
+	return &csvTradeWriter{w: cw}, nil
+}
+
+func (cw *csvTradeWriter) WriteRow(row *model.TradeRow) error {
+	values := tradeValues(row)
+	// company is a text of user, cell which starts with formula symbol is quoted for spreadsheets
+	if company := values[2]; company != "" && strings.ContainsRune("=+-@", rune(company[0])) {
+		values[2] = "'" + company
+	}
+	if err := cw.w.Write(values); err != nil {
+		return fmt.Errorf("write %w", err)
+	}
+	return nil
+}
+
+func (cw *csvTradeWriter) Flush() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+func (cw *csvTradeWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// jsonlTradeWriter writes every trade as JSON object on its own line
+type jsonlTradeWriter struct {
+	enc *json.Encoder
+}
+
+func newJSONLTradeWriter(w io.Writer) (tradeWriter, error) {
+	return &jsonlTradeWriter{enc: json.NewEncoder(w)}, nil
+}
+
+func (jw *jsonlTradeWriter) WriteRow(row *model.TradeRow) error {
+	if err := jw.enc.Encode(row); err != nil {
+		return fmt.Errorf("encode %w", err)
+	}
+	return nil
+}
+
+func (jw *jsonlTradeWriter) Flush() error {
+	return nil
+}
+
+func (jw *jsonlTradeWriter) Close() error {
+	return nil
+}
+
+// xlsxTradeWriter writes trades into the only sheet of XLSX workbook. Parts of workbook are written into zip archive
+// one after another and the sheet is written row by row, so workbook is never kept in memory
+type xlsxTradeWriter struct {
+	zw    *zip.Writer
+	sheet io.Writer
+	rows  int
+}
+
+// xlsxParts are parts of workbook which are written before the sheet
+var xlsxParts = []struct { // nolint gochecknoglobals
+	name    string
+	content string
+}{
+	{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Trades" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+// xlsxNumberColumns are indexes of columns of tradeColumns which are written as numbers
+var xlsxNumberColumns = map[int]bool{4: true, 5: true, 6: true, 7: true, 10: true, 11: true} // nolint gochecknoglobals
+
+func newXLSXTradeWriter(w io.Writer) (tradeWriter, error) {
+	zw := zip.NewWriter(w)
+	for _, part := range xlsxParts {
+		pw, err := zw.Create(part.name)
+		if err != nil {
+			return nil, fmt.Errorf("create %w", err)
+		}
+		if _, err = io.WriteString(pw, part.content); err != nil {
+			return nil, fmt.Errorf("writeString %w", err)
+		}
+	}
+	sheet, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, fmt.Errorf("create %w", err)
+	}
+	xw := &xlsxTradeWriter{zw: zw, sheet: sheet}
+	if _, err = io.WriteString(sheet, xml.Header+`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`); err != nil {
+		return nil, fmt.Errorf("writeString %w", err)
+	}
+	if err = xw.writeCells(tradeColumns, false); err != nil {
+		return nil, err
+	}
+	return xw, nil
+}
+
+func (xw *xlsxTradeWriter) WriteRow(row *model.TradeRow) error {
+	return xw.writeCells(tradeValues(row), true)
+}
+
+// writeCells writes row of sheet, decimals of number columns are written as numbers with all their digits
+func (xw *xlsxTradeWriter) writeCells(values []string, numbers bool) error {
+	xw.rows++
+	var b strings.Builder
+	fmt.Fprintf(&b, `<row r="%d">`, xw.rows)
+	for i, value := range values {
+		switch {
+		case value == "":
+			continue
+		case numbers && xlsxNumberColumns[i]:
+			fmt.Fprintf(&b, `<c r="%s%d"><v>%s</v></c>`, xlsxColumn(i), xw.rows, value)
+		default:
+			fmt.Fprintf(&b, `<c r="%s%d" t="inlineStr"><is><t>`, xlsxColumn(i), xw.rows)
+			if err := xml.EscapeText(&b, []byte(value)); err != nil {
+				return fmt.Errorf("escapeText %w", err)
+			}
+			b.WriteString(`</t></is></c>`)
+		}
+	}
+	b.WriteString(`</row>`)
+	if _, err := io.WriteString(xw.sheet, b.String()); err != nil {
+		return fmt.Errorf("writeString %w", err)
+	}
+	return nil
+}
+
+func (xw *xlsxTradeWriter) Flush() error {
+	return xw.zw.Flush()
+}
+
+func (xw *xlsxTradeWriter) Close() error {
+	if _, err := io.WriteString(xw.sheet, `</sheetData></worksheet>`); err != nil {
+		return fmt.Errorf("writeString %w", err)
+	}
+	if err := xw.zw.Close(); err != nil {
+		return fmt.Errorf("close %w", err)
+	}
+	return nil
+}
+
+// xlsxColumn returns letter of column by its index, export has less than 26 columns
+func xlsxColumn(i int) string {
+	return string(rune('A' + i))
+}
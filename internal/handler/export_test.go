@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/handler/mocks"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func testTradeRows() []*model.TradeRow {
+	end := time.Date(2023, time.October, 2, 12, 0, 0, 0, time.UTC)
+	price := decimal.RequireFromString("101.125")
+	return []*model.TradeRow{
+		{
+			DealID: uuid.New(), Status: "closed", Company: "=Apple", Direction: "long",
+			SharesCount: decimal.RequireFromString("0.333"), PurchasePrice: decimal.RequireFromString("100.1"),
+			StopLoss: decimal.NewFromInt(90), TakeProfit: decimal.NewFromInt(150), DealTime: end.Add(-time.Hour),
+			EndDealTime: &end, Profit: decimal.RequireFromString("-0.0333"),
+		},
+		{
+			DealID: uuid.New(), Status: "open", Company: "Tesla & Co", Direction: "short",
+			SharesCount: decimal.NewFromInt(2), PurchasePrice: decimal.NewFromInt(110),
+			StopLoss: decimal.NewFromInt(120), TakeProfit: decimal.NewFromInt(90), DealTime: end,
+			CurrentPrice: &price, Profit: decimal.RequireFromString("17.75"),
+		},
+	}
+}
+
+func exportTrades(t *testing.T, query string, rows []*model.TradeRow, exportErr error) *httptest.ResponseRecorder {
+	srv := new(mocks.ExportService)
+	hndl := NewHandler(Dependencies{Exports: srv}, v, cfg)
+	profileID := uuid.New()
+	srv.On("ExportTrades", mock.Anything, profileID, mock.AnythingOfType("*model.DealFilter"), strings.Contains(query, "open=true"),
+		mock.Anything).Return(func(_ context.Context, _ uuid.UUID, _ *model.DealFilter, _ bool, write func(*model.TradeRow) error) error {
+		for _, row := range rows {
+			if err := write(row); err != nil {
+				return err
+			}
+		}
+		return exportErr
+	}).Maybe()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/exports/trades?"+query, http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(profileIDKey, profileID)
+	require.NoError(t, hndl.APIExportTrades(c))
+	return rec
+}
+
+func TestAPIExportTradesCSV(t *testing.T) {
+	rows := testTradeRows()
+	rec := exportTrades(t, "format=csv&from=2023-10-01T00:00:00Z&open=true", rows, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, `attachment; filename="trades-from-2023-10-01.csv"`, rec.Header().Get(echo.HeaderContentDisposition))
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	require.Len(t, lines, 3)
+	require.Equal(t, strings.Join(tradeColumns, ","), lines[0])
+	require.Contains(t, lines[1], ",'=Apple,long,0.333,100.1,90,150,2023-10-02T11:00:00Z,2023-10-02T12:00:00Z,,-0.0333")
+	require.Contains(t, lines[2], ",open,Tesla & Co,short,2,110,120,90,2023-10-02T12:00:00Z,,101.125,17.75")
+}
+
+func TestAPIExportTradesJSONL(t *testing.T) {
+	rows := testTradeRows()
+	rec := exportTrades(t, "format=jsonl", rows, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, `attachment; filename="trades.jsonl"`, rec.Header().Get(echo.HeaderContentDisposition))
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	require.Len(t, lines, 2)
+	var row model.TradeRow
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &row))
+	require.Equal(t, "101.125", row.CurrentPrice.String())
+	require.Nil(t, row.EndDealTime)
+}
+
+func TestAPIExportTradesXLSX(t *testing.T) {
+	rec := exportTrades(t, "format=xlsx", testTradeRows(), nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	archive, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	require.NoError(t, err)
+	var sheet []byte
+	for _, file := range archive.File {
+		if file.Name == "xl/worksheets/sheet1.xml" {
+			r, errOpen := file.Open()
+			require.NoError(t, errOpen)
+			sheet, err = io.ReadAll(r)
+			require.NoError(t, err)
+		}
+	}
+	require.Len(t, archive.File, 5)
+	require.Contains(t, string(sheet), `<c r="E2"><v>0.333</v></c>`)
+	require.Contains(t, string(sheet), `<c r="L2"><v>-0.0333</v></c>`)
+	require.Contains(t, string(sheet), `<t>Tesla &amp; Co</t>`)
+	require.Contains(t, string(sheet), `<row r="3">`)
+}
+
+func TestAPIExportTradesErrors(t *testing.T) {
+	rec := exportTrades(t, "format=pdf", nil, nil)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = exportTrades(t, "format=csv&limit=10", testTradeRows(), nil)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Empty(t, rec.Header().Get(echo.HeaderContentDisposition))
+
+	rec = exportTrades(t, "format=csv&cursor=abc", testTradeRows(), nil)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = exportTrades(t, "format=csv", nil, berrors.New(berrors.Unavailable, "Service is temporarily unavailable"))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.Empty(t, rec.Header().Get(echo.HeaderContentDisposition))
+
+	rec = exportTrades(t, "format=csv", nil, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, strings.Join(tradeColumns, ",")+"\n", rec.Body.String())
+}
@@ -5,10 +5,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
-	"strconv"
+	"strings"
 	"text/template"
+	"time"
 
 	"github.com/artnikel/APIService/internal/config"
 	berrors "github.com/artnikel/APIService/internal/errors"
@@ -18,7 +18,6 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/shopspring/decimal"
 	"github.com/sirupsen/logrus"
-	"gopkg.in/boj/redistore.v1"
 )
 
 // UserService is an interface that defines the methods on User entity.
@@ -30,17 +29,95 @@ type UserService interface {
 
 // BalanceService is an interface that defines the methods on Balance entity.
 type BalanceService interface {
-	BalanceOperation(ctx context.Context, balance *model.Balance) (float64, error)
-	GetBalance(ctx context.Context, profileid uuid.UUID) (float64, error)
+	BalanceOperation(ctx context.Context, balance *model.Balance) (decimal.Decimal, error)
+	GetBalance(ctx context.Context, profileid uuid.UUID) (decimal.Decimal, error)
 }
 
 // TradingService is an interface that defines the method on Trading entity.
 type TradingService interface {
 	CreatePosition(ctx context.Context, deal *model.Deal) error
-	ClosePositionManually(ctx context.Context, dealid, profileid uuid.UUID) (float64, error)
+	ClosePositionManually(ctx context.Context, dealid, profileid uuid.UUID) (decimal.Decimal, error)
 	GetUnclosedPositions(ctx context.Context, profileid uuid.UUID) ([]*model.Deal, error)
 	GetClosedPositions(ctx context.Context, profileid uuid.UUID) ([]*model.Deal, error)
+	FindUnclosedPositions(ctx context.Context, profileid uuid.UUID, filter *model.DealFilter) (*model.DealPage, error)
+	FindClosedPositions(ctx context.Context, profileid uuid.UUID, filter *model.DealFilter) (*model.DealPage, error)
 	GetPrices(ctx context.Context) ([]model.Share, error)
+	GetPriceSnapshot(ctx context.Context) (*model.PriceSnapshot, error)
+}
+
+// TokenService is an interface that defines the methods for issuing and checking tokens of API clients.
+type TokenService interface {
+	GenerateTokens(ctx context.Context, profileID uuid.UUID) (*model.TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (*model.TokenPair, error)
+	ParseAccessToken(ctx context.Context, accessToken string) (uuid.UUID, error)
+	Revoke(ctx context.Context, accessToken, refreshToken string) error
+}
+
+// SessionStore is an interface that defines the methods for keeping sessions of browsers.
+type SessionStore interface {
+	Get(ctx context.Context, id string) (*model.Session, error)
+	Save(ctx context.Context, session *model.Session, ttl time.Duration) error
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context, profileID uuid.UUID) ([]*model.Session, error)
+}
+
+// LoginGuard is an interface that defines the methods for protection of log in against guessing passwords.
+type LoginGuard interface {
+	Check(ctx context.Context, login, ip string) error
+	Fail(ctx context.Context, login, ip string) error
+	Succeed(ctx context.Context, login, ip string) error
+	Release(ctx context.Context, login, ip string) error
+}
+
+// PriceStream is an interface that defines the method for subscribing to updates of prices.
+type PriceStream interface {
+	Subscribe(lastEventID uint64) (<-chan model.PriceEvent, func())
+}
+
+// PriceHistory is an interface that defines the method for getting history of prices.
+type PriceHistory interface {
+	GetCandles(ctx context.Context, company, interval string, from, to time.Time) ([]model.Candle, error)
+}
+
+// PortfolioService is an interface that defines the method for valuing open positions of user.
+type PortfolioService interface {
+	GetPortfolio(ctx context.Context, profileid uuid.UUID) (*model.Portfolio, error)
+}
+
+// AnalyticsService is an interface that defines the method for calculating performance of closed positions.
+type AnalyticsService interface {
+	GetAnalytics(ctx context.Context, profileid uuid.UUID, from, to time.Time, company string) (*model.TradeAnalytics, error)
+}
+
+// Ledger is an interface that defines the method for getting statement of balance operations.
+type Ledger interface {
+	GetStatement(ctx context.Context, profileID uuid.UUID, from, to time.Time, limit int, cursor string) (*model.Statement, error)
+}
+
+// Idempotency is an interface that defines the methods for replaying responses to retried requests.
+type Idempotency interface {
+	Begin(ctx context.Context, key string) (*model.IdempotentResponse, func(), error)
+	Complete(ctx context.Context, key string, resp *model.IdempotentResponse) error
+}
+
+// OrderService is an interface that defines the methods for managing pending entry orders of user.
+type OrderService interface {
+	PlaceOrder(ctx context.Context, order *model.Order) error
+	GetOrders(ctx context.Context, profileID uuid.UUID) ([]*model.Order, error)
+	CancelOrder(ctx context.Context, profileID, orderID uuid.UUID) (*model.Order, error)
+}
+
+// TrailingStops is an interface that defines the methods for managing trailing stops of open positions.
+type TrailingStops interface {
+	Attach(ctx context.Context, stop *model.TrailingStop) error
+	Detach(ctx context.Context, profileID, dealID uuid.UUID) (*model.TrailingStop, error)
+	GetTrailingStops(ctx context.Context, profileID uuid.UUID) ([]*model.TrailingStop, error)
+}
+
+// ExportService is an interface that defines the method for exporting trade history of user.
+type ExportService interface {
+	ExportTrades(ctx context.Context, profileid uuid.UUID, filter *model.DealFilter, includeOpen bool,
+		write func(row *model.TradeRow) error) error
 }
 
 // Handler is responsible for handling HTTP requests related to entities.
@@ -48,54 +125,68 @@ type Handler struct {
 	userService    UserService
 	balanceService BalanceService
 	tradingService TradingService
+	tokenService   TokenService
+	sessionStore   SessionStore
+	loginGuard     LoginGuard
+	priceStream    PriceStream
+	priceHistory   PriceHistory
+	portfolio      PortfolioService
+	analytics      AnalyticsService
+	exports        ExportService
+	ledger         Ledger
+	idempotency    Idempotency
+	orders         OrderService
+	trailingStops  TrailingStops
 	validate       *validator.Validate
 	cfg            config.Variables
 }
 
-// NewHandler creates a new instance of the Handler struct.
-func NewHandler(userService UserService, balanceService BalanceService, tradingService TradingService, v *validator.Validate, cfg *config.Variables) *Handler {
-	return &Handler{
-		userService:    userService,
-		balanceService: balanceService,
-		tradingService: tradingService,
-		validate:       v,
-		cfg:            *cfg,
-	}
+// credentials is a body of sign up and log in requests
+type credentials struct {
+	model.User
+	Remember bool `json:"remember" form:"remember"` // keep session after browser is closed and extend its timeouts
 }
 
-// NewRedisStore creates a new Redis storage instance for sessions
-func NewRedisStore(cfg *config.Variables) *redistore.RediStore {
-	// nolint gonmd
-	store, err := redistore.NewRediStore(10, "tcp", cfg.RedisPriceAddress, "", []byte(cfg.HashKey))
-	if err != nil {
-		log.Fatalf("failed to create redis store: %v", err)
-	}
-	return store
+// Dependencies contains services which are used by Handler. Service may be nil if its endpoints aren`t routed
+type Dependencies struct {
+	UserService    UserService
+	BalanceService BalanceService
+	TradingService TradingService
+	TokenService   TokenService
+	SessionStore   SessionStore
+	LoginGuard     LoginGuard
+	PriceStream    PriceStream
+	PriceHistory   PriceHistory
+	Portfolio      PortfolioService
+	Analytics      AnalyticsService
+	Exports        ExportService
+	Ledger         Ledger
+	Idempotency    Idempotency
+	Orders         OrderService
+	TrailingStops  TrailingStops
 }
 
-// getProfileID is method for getting id of profile from session
-func (h *Handler) getProfileID(c echo.Context) (uuid.UUID, error) {
-	cookie, err := c.Cookie("SESSION_ID")
-	if err != nil {
-		logrus.Errorf("getProfileID: %v", err)
-		return uuid.Nil, c.Redirect(http.StatusSeeOther, "/")
-	}
-	store := NewRedisStore(&h.cfg)
-	session, err := store.Get(c.Request(), cookie.Name)
-	if err != nil {
-		logrus.Errorf("getProfileID: %v", err)
-		return uuid.Nil, echo.ErrNotFound
-	}
-	if len(session.Values) == 0 {
-		return uuid.Nil, c.Redirect(http.StatusSeeOther, "/")
-	}
-	profileid := session.Values["id"].(string)
-	profileUUID, err := uuid.Parse(profileid)
-	if err != nil {
-		logrus.Errorf("getProfileID: %v", err)
-		return uuid.Nil, echo.ErrInternalServerError
+// NewHandler creates a new instance of the Handler struct.
+func NewHandler(deps Dependencies, v *validator.Validate, cfg *config.Variables) *Handler {
+	return &Handler{
+		userService:    deps.UserService,
+		balanceService: deps.BalanceService,
+		tradingService: deps.TradingService,
+		tokenService:   deps.TokenService,
+		sessionStore:   deps.SessionStore,
+		loginGuard:     deps.LoginGuard,
+		priceStream:    deps.PriceStream,
+		priceHistory:   deps.PriceHistory,
+		portfolio:      deps.Portfolio,
+		analytics:      deps.Analytics,
+		exports:        deps.Exports,
+		ledger:         deps.Ledger,
+		idempotency:    deps.Idempotency,
+		orders:         deps.Orders,
+		trailingStops:  deps.TrailingStops,
+		validate:       v,
+		cfg:            *cfg,
 	}
-	return profileUUID, nil
 }
 
 // Auth is endpoint for auth page
@@ -104,7 +195,20 @@ func (h *Handler) Auth(c echo.Context) error {
 	if err != nil {
 		return echo.ErrNotFound
 	}
-	return tmpl.ExecuteTemplate(c.Response().Writer, "auth", nil)
+	return h.renderAuth(c, tmpl, "")
+}
+
+// renderAuth renders auth page with error message and CSRF token for its form
+func (h *Handler) renderAuth(c echo.Context, tmpl *template.Template, errorMsg string) error {
+	token, err := h.csrfToken(c)
+	if err != nil {
+		logrus.Errorf("renderAuth: %v", err)
+		return echo.ErrInternalServerError
+	}
+	return tmpl.ExecuteTemplate(c.Response().Writer, "auth", map[string]string{
+		"errorMsg": errorMsg,
+		"csrf":     token,
+	})
 }
 
 // Index is endpoint for main page
@@ -116,7 +220,7 @@ func (h *Handler) Index(c echo.Context) error {
 	if err != nil {
 		return echo.ErrNotFound
 	}
-	profileID, err := h.getProfileID(c)
+	profileID, err := getProfileID(c)
 	if err != nil {
 		return echo.ErrUnauthorized
 	}
@@ -130,12 +234,26 @@ func (h *Handler) Index(c echo.Context) error {
 		logrus.Errorf("index: %v", err)
 		return echo.ErrInternalServerError
 	}
+	token, err := h.csrfToken(c)
+	if err != nil {
+		logrus.Errorf("index: %v", err)
+		return echo.ErrInternalServerError
+	}
+	idempotencyKey, err := newSessionID()
+	if err != nil {
+		logrus.Errorf("index: %v", err)
+		return echo.ErrInternalServerError
+	}
 	return tmpl.ExecuteTemplate(c.Response().Writer, "index", struct {
-		Balance  float64
-		PageData PageData
+		Balance        decimal.Decimal
+		PageData       PageData
+		CSRFToken      string
+		IdempotencyKey string
 	}{
-		Balance:  balance,
-		PageData: PageData{Orders: orders},
+		Balance:        balance,
+		PageData:       PageData{Orders: orders},
+		CSRFToken:      token,
+		IdempotencyKey: idempotencyKey,
 	})
 }
 
@@ -145,54 +263,36 @@ func (h *Handler) SignUp(c echo.Context) error {
 	if err != nil {
 		return echo.ErrNotFound
 	}
-	var user model.User
-	if errBind := c.Bind(&user); errBind != nil {
-		return tmpl.ExecuteTemplate(c.Response().Writer, "auth", map[string]string{
-			"errorMsg": "Failed to read fields",
-		})
+	var creds credentials
+	if errBind := c.Bind(&creds); errBind != nil {
+		return h.renderAuth(c, tmpl, "Failed to read fields")
 	}
+	user := creds.User
 	tempPassword := user.Password
 	err = h.validate.StructCtx(c.Request().Context(), user)
 	if err != nil {
-		logrus.WithFields(logrus.Fields{
-			"Login":    user.Login,
-			"Password": user.Password,
-		}).Errorf("signUp: %v", err)
-		return tmpl.ExecuteTemplate(c.Response().Writer, "auth", map[string]string{
-			"errorMsg": "Invalid fields! The fields have not been validated",
-		})
+		logrus.WithField("Login", user.Login).Errorf("signUp: %v", err)
+		return h.renderAuth(c, tmpl, "Invalid fields! The fields have not been validated")
 	}
 	err = h.userService.SignUp(c.Request().Context(), &user)
 	if err != nil {
 		var e *berrors.BusinessError
 		if errors.As(err, &e) {
-			return tmpl.ExecuteTemplate(c.Response().Writer, "auth", map[string]string{
-				"errorMsg": e.Message,
-			})
+			return h.renderAuth(c, tmpl, e.Message)
 		}
 		logrus.Errorf("signUp: %v", err)
-		return tmpl.ExecuteTemplate(c.Response().Writer, "auth", map[string]string{
-			"errorMsg": "Failed to sign up",
-		})
+		return h.renderAuth(c, tmpl, "Failed to sign up")
 	}
 	user.Password = tempPassword
 	userID, err := h.userService.GetByLogin(c.Request().Context(), &user)
 	if err != nil {
 		logrus.Errorf("signUp: %v", err)
-		return tmpl.ExecuteTemplate(c.Response().Writer, "auth", map[string]string{
-			"errorMsg": "Failed to log in",
-		})
-	}
-	store := NewRedisStore(&h.cfg)
-	session, _ := store.Get(c.Request(), "SESSION_ID")
-	session.Values["id"] = userID.String()
-	session.Values["login"] = user.Login
-	session.Values["password"] = user.Password
-	if err = session.Save(c.Request(), c.Response()); err != nil {
+		return h.renderAuth(c, tmpl, "Failed to log in")
+	}
+	err = h.startSession(c, userID, user.Login, creds.Remember)
+	if err != nil {
 		logrus.Errorf("signUp: %v", err)
-		return tmpl.ExecuteTemplate(c.Response().Writer, "auth", map[string]string{
-			"errorMsg": "Error saving session",
-		})
+		return h.renderAuth(c, tmpl, "Error saving session")
 	}
 	return c.Redirect(http.StatusSeeOther, "/index")
 }
@@ -203,50 +303,63 @@ func (h *Handler) Login(c echo.Context) error {
 	if err != nil {
 		return echo.ErrNotFound
 	}
-	var user model.User
-	if errBind := c.Bind(&user); errBind != nil {
-		return tmpl.ExecuteTemplate(c.Response().Writer, "auth", map[string]string{
-			"errorMsg": "Failed to read fields",
-		})
+	var creds credentials
+	if errBind := c.Bind(&creds); errBind != nil {
+		return h.renderAuth(c, tmpl, "Failed to read fields")
 	}
+	user := creds.User
 	err = h.validate.StructCtx(c.Request().Context(), user)
 	if err != nil {
-		logrus.WithFields(logrus.Fields{
-			"Login":    user.Login,
-			"Password": user.Password,
-		}).Errorf("login: %v", err)
-		return tmpl.ExecuteTemplate(c.Response().Writer, "auth", map[string]string{
-			"errorMsg": "Invalid fields! The fields have not been validated",
-		})
+		logrus.WithField("Login", user.Login).Errorf("login: %v", err)
+		return h.renderAuth(c, tmpl, "Invalid fields! The fields have not been validated")
 	}
-	userID, err := h.userService.GetByLogin(c.Request().Context(), &user)
+	userID, err := h.checkCredentials(c, &user)
 	if err != nil {
-		logrus.Errorf("login: %v", err)
-		return tmpl.ExecuteTemplate(c.Response().Writer, "auth", map[string]string{
-			"errorMsg": "Wrong login or password",
-		})
+		logrus.WithField("Login", user.Login).Errorf("login: %v", err)
+		var e *berrors.BusinessError
+		if errors.As(err, &e) {
+			return h.renderAuth(c, tmpl, e.Message)
+		}
+		return h.renderAuth(c, tmpl, "Failed to log in")
 	}
-	store := NewRedisStore(&h.cfg)
-	session, err := store.Get(c.Request(), "SESSION_ID")
+	err = h.startSession(c, userID, user.Login, creds.Remember)
 	if err != nil {
 		logrus.Errorf("login: %v", err)
-		return echo.ErrNotFound
-	}
-	session.Values["id"] = userID.String()
-	session.Values["login"] = user.Login
-	session.Values["password"] = user.Password
-	if err = session.Save(c.Request(), c.Response().Writer); err != nil {
-		logrus.Errorf("login: %v", err)
-		return tmpl.ExecuteTemplate(c.Response().Writer, "auth", map[string]string{
-			"errorMsg": "Error saving session",
-		})
+		return h.renderAuth(c, tmpl, "Error saving session")
 	}
 	return c.Redirect(http.StatusSeeOther, "/index")
 }
 
+// checkCredentials checks login and password of user unless logging in is blocked after failed attempts.
+// Wrong credentials are reported by one business error, so it isn`t disclosed which of them is wrong
+func (h *Handler) checkCredentials(c echo.Context, user *model.User) (uuid.UUID, error) {
+	ctx := c.Request().Context()
+	if err := h.loginGuard.Check(ctx, user.Login, c.RealIP()); err != nil {
+		return uuid.Nil, fmt.Errorf("check %w", err)
+	}
+	userID, err := h.userService.GetByLogin(ctx, user)
+	if err != nil {
+		var e *berrors.BusinessError
+		if errors.As(err, &e) && berrors.Lookup(e.Code).Retryable {
+			if errRelease := h.loginGuard.Release(ctx, user.Login, c.RealIP()); errRelease != nil {
+				logrus.Errorf("checkCredentials: %v", errRelease)
+			}
+			return uuid.Nil, fmt.Errorf("getByLogin %w", err)
+		}
+		if errFail := h.loginGuard.Fail(ctx, user.Login, c.RealIP()); errFail != nil {
+			logrus.Errorf("checkCredentials: %v", errFail)
+		}
+		return uuid.Nil, berrors.New(berrors.Unauthorized, "Wrong login or password")
+	}
+	if errSucceed := h.loginGuard.Succeed(ctx, user.Login, c.RealIP()); errSucceed != nil {
+		logrus.Errorf("checkCredentials: %v", errSucceed)
+	}
+	return userID, nil
+}
+
 // DeleteAccount calls method of Service by handler
 func (h *Handler) DeleteAccount(c echo.Context) error {
-	profileID, err := h.getProfileID(c)
+	profileID, err := getProfileID(c)
 	if err != nil {
 		return echo.ErrUnauthorized
 	}
@@ -254,28 +367,33 @@ func (h *Handler) DeleteAccount(c echo.Context) error {
 	if err != nil {
 		var e *berrors.BusinessError
 		if errors.As(err, &e) {
-			return c.HTML(http.StatusBadRequest, `<script>alert('`+e.Message+`');
-			window.location.href = '/';</script>`)
+			return alertMessage(c, berrors.HTTPStatus(err), e.Message, "/")
 		}
 		logrus.WithFields(logrus.Fields{
 			"ID": profileID,
 		}).Errorf("deleteAccount: %v", err)
-		return c.HTML(http.StatusBadRequest, `<script>alert('Failed to delete your account');
+		return c.HTML(berrors.HTTPStatus(err), `<script>alert('Failed to delete your account');
 		window.location.href = '/index';</script>`)
 	}
+	if _, err = h.revokeSessions(c.Request().Context(), profileID, ""); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"ID": profileID,
+		}).Errorf("deleteAccount: %v", err)
+	}
+	c.SetCookie(h.sessionCookie("", -1))
 	return c.HTML(http.StatusOK, `<script>alert('Your account has been successfully deleted!');
 	 window.location.href = '/';</script>`)
 }
 
 // Deposit calls method of Service by handler
 func (h *Handler) Deposit(c echo.Context) error {
-	profileID, err := h.getProfileID(c)
+	profileID, err := getProfileID(c)
 	if err != nil {
 		return echo.ErrUnauthorized
 	}
-	sumOfMoney, err := strconv.ParseFloat(c.FormValue("operation"), 64)
-	if err != nil {
-		logrus.Errorf("deposit: %v", err)
+	sumOfMoney, err := decimal.NewFromString(c.FormValue("operation"))
+	if err != nil || !sumOfMoney.IsPositive() {
+		logrus.Errorf("deposit: invalid sum of money %q", c.FormValue("operation"))
 		return c.HTML(http.StatusBadRequest, `<script>alert('Invalid sum of money');
 		window.location.href = '/index';</script>`)
 	}
@@ -285,27 +403,31 @@ func (h *Handler) Deposit(c echo.Context) error {
 	}
 	_, err = h.balanceService.BalanceOperation(c.Request().Context(), &balance)
 	if err != nil {
+		var e *berrors.BusinessError
+		if errors.As(err, &e) {
+			return alertMessage(c, berrors.HTTPStatus(err), e.Message, "/index")
+		}
 		logrus.WithFields(logrus.Fields{
 			"BalanceId": balance.BalanceID,
 			"ProfileId": balance.ProfileID,
 			"Operation": balance.Operation,
 		}).Errorf("deposit: %v", err)
-		return c.HTML(http.StatusBadRequest, `<script>alert('Failed to made balance operation');
+		return c.HTML(berrors.HTTPStatus(err), `<script>alert('Failed to made balance operation');
 		 window.location.href = '/index';</script>`)
 	}
-	return c.HTML(http.StatusOK, `<script>alert('Deposit of `+fmt.Sprintf("%.2f", sumOfMoney)+
+	return c.HTML(http.StatusOK, `<script>alert('Deposit of `+sumOfMoney.StringFixed(model.USD.Places)+
 		`$ approved!'); window.location.href = '/index';</script>`)
 }
 
 // Withdraw calls method of Service by handler
 func (h *Handler) Withdraw(c echo.Context) error {
-	profileID, err := h.getProfileID(c)
+	profileID, err := getProfileID(c)
 	if err != nil {
 		return echo.ErrUnauthorized
 	}
-	sumOfMoney, err := strconv.ParseFloat(c.FormValue("operation"), 64)
-	if err != nil {
-		logrus.Errorf("withdraw: %v", err)
+	sumOfMoney, err := decimal.NewFromString(c.FormValue("operation"))
+	if err != nil || !sumOfMoney.IsPositive() {
+		logrus.Errorf("withdraw: invalid sum of money %q", c.FormValue("operation"))
 		return c.HTML(http.StatusBadRequest, `<script>alert('Invalid sum of money');
 		window.location.href = '/index';</script>`)
 	}
@@ -313,29 +435,28 @@ func (h *Handler) Withdraw(c echo.Context) error {
 		ProfileID: profileID,
 		Operation: sumOfMoney,
 	}
-	balance.Operation = -balance.Operation
+	balance.Operation = balance.Operation.Neg()
 	_, err = h.balanceService.BalanceOperation(c.Request().Context(), &balance)
 	if err != nil {
 		var e *berrors.BusinessError
 		if errors.As(err, &e) {
-			return c.HTML(http.StatusBadRequest, `<script>alert('`+e.Message+`');
-			window.location.href = '/index';</script>`)
+			return alertMessage(c, berrors.HTTPStatus(err), e.Message, "/index")
 		}
 		logrus.WithFields(logrus.Fields{
 			"BalanceId": balance.BalanceID,
 			"ProfileId": balance.ProfileID,
 			"Operation": balance.Operation,
 		}).Errorf("withdraw: %v", err)
-		return c.HTML(http.StatusBadRequest, `<script>alert('Failed to made balance operation');
+		return c.HTML(berrors.HTTPStatus(err), `<script>alert('Failed to made balance operation');
 		 window.location.href = '/index';</script>`)
 	}
-	return c.HTML(http.StatusOK, `<script>alert('Withdraw of `+fmt.Sprintf("%.2f", sumOfMoney)+
+	return c.HTML(http.StatusOK, `<script>alert('Withdraw of `+sumOfMoney.StringFixed(model.USD.Places)+
 		`$ approved!'); window.location.href = '/index';</script>`)
 }
 
 // CreatePosition calls method of Service by handler
 func (h *Handler) CreatePosition(c echo.Context) error {
-	profileID, err := h.getProfileID(c)
+	profileID, err := getProfileID(c)
 	if err != nil {
 		return echo.ErrUnauthorized
 	}
@@ -372,11 +493,10 @@ func (h *Handler) CreatePosition(c echo.Context) error {
 	if err != nil {
 		var e *berrors.BusinessError
 		if errors.As(err, &e) {
-			return c.HTML(http.StatusBadRequest, `<script>alert('`+e.Message+`');
-			window.location.href = '/index';</script>`)
+			return alertMessage(c, berrors.HTTPStatus(err), e.Message, "/index")
 		}
 		logrus.Errorf("createPosition: %v", err)
-		return c.HTML(http.StatusBadRequest, `<script>alert('Failed to create position');
+		return c.HTML(berrors.HTTPStatus(err), `<script>alert('Failed to create position');
 		 window.location.href = '/index';</script>`)
 	}
 	return c.HTML(http.StatusOK, `<script>alert('Position `+strategy+` created!');
@@ -385,7 +505,7 @@ func (h *Handler) CreatePosition(c echo.Context) error {
 
 // ClosePositionManually calls method of Service by handler
 func (h *Handler) ClosePositionManually(c echo.Context) error {
-	profileID, err := h.getProfileID(c)
+	profileID, err := getProfileID(c)
 	if err != nil {
 		return echo.ErrUnauthorized
 	}
@@ -398,73 +518,147 @@ func (h *Handler) ClosePositionManually(c echo.Context) error {
 	profit, err := h.tradingService.ClosePositionManually(c.Request().Context(), dealUUID, profileID)
 	if err != nil {
 		logrus.Errorf("closePositionManually: %v", err)
-		return c.HTML(http.StatusBadRequest, `<script>alert('Failed to close position');
+		return c.HTML(berrors.HTTPStatus(err), `<script>alert('Failed to close position');
+		 window.location.href = '/index';</script>`)
+	}
+	return c.HTML(http.StatusOK, `<script>alert('Position closed with profit `+profit.StringFixed(model.USD.Places)+`');
+	 window.location.href = '/index';</script>`)
+}
+
+// AttachTrailingStop attaches trailing stop with distance from the form to open position
+func (h *Handler) AttachTrailingStop(c echo.Context) error {
+	profileID, err := getProfileID(c)
+	if err != nil {
+		return echo.ErrUnauthorized
+	}
+	dealUUID, err := uuid.Parse(c.FormValue("dealid"))
+	if err != nil {
+		logrus.Errorf("attachTrailingStop: %v", err)
+		return c.HTML(http.StatusBadRequest, `<script>alert('Invalid deal ID');
+		 window.location.href = '/index';</script>`)
+	}
+	distance, err := decimal.NewFromString(c.FormValue("distance"))
+	if err != nil {
+		logrus.Errorf("attachTrailingStop: %v", err)
+		return c.HTML(http.StatusBadRequest, `<script>alert('Invalid distance value');
 		 window.location.href = '/index';</script>`)
 	}
-	return c.HTML(http.StatusOK, `<script>alert('Position closed with profit `+fmt.Sprintf("%.2f", profit)+`');
+	stop := &model.TrailingStop{ProfileID: profileID, DealID: dealUUID, Type: c.FormValue("type"), Distance: distance}
+	err = h.trailingStops.Attach(c.Request().Context(), stop)
+	if err != nil {
+		var e *berrors.BusinessError
+		if errors.As(err, &e) {
+			return alertMessage(c, berrors.HTTPStatus(err), e.Message, "/index")
+		}
+		logrus.Errorf("attachTrailingStop: %v", err)
+		return c.HTML(berrors.HTTPStatus(err), `<script>alert('Failed to attach trailing stop');
+		 window.location.href = '/index';</script>`)
+	}
+	return c.HTML(http.StatusOK, `<script>alert('Trailing stop attached, effective stop is `+
+		stop.EffectiveStop.StringFixed(model.PricePlaces)+`');
 	 window.location.href = '/index';</script>`)
 }
 
+// GetTrailingStops returns trailing stops of user for the dashboard
+func (h *Handler) GetTrailingStops(c echo.Context) error {
+	profileID, err := getProfileID(c)
+	if err != nil {
+		return echo.ErrUnauthorized
+	}
+	stops, err := h.trailingStops.GetTrailingStops(c.Request().Context(), profileID)
+	if err != nil {
+		logrus.Errorf("getTrailingStops: %v", err)
+		return c.HTML(berrors.HTTPStatus(err), `<script>alert('Failed to get trailing stops');
+		 window.location.href = '/index';</script>`)
+	}
+	return c.JSON(http.StatusOK, stops)
+}
+
 // GetUnclosedPositions calls method of Service by handler
 func (h *Handler) GetUnclosedPositions(c echo.Context) error {
-	profileID, err := h.getProfileID(c)
+	profileID, err := getProfileID(c)
 	if err != nil {
 		return echo.ErrUnauthorized
 	}
-	unclosedPositions, err := h.tradingService.GetUnclosedPositions(c.Request().Context(), profileID)
+	filter, err := parseDealFilter(c)
+	if err != nil {
+		return c.HTML(http.StatusBadRequest, `<script>alert('Invalid filter of positions');
+		 window.location.href = '/index';</script>`)
+	}
+	page, err := h.tradingService.FindUnclosedPositions(c.Request().Context(), profileID, filter)
 	if err != nil {
 		logrus.Errorf("getUnclosedPositions: %v", err)
-		return c.HTML(http.StatusBadRequest, `<script>alert('Failed to get positions');
+		return c.HTML(berrors.HTTPStatus(err), `<script>alert('Failed to get positions');
 		 window.location.href = '/index';</script>`)
 	}
-	return c.JSON(http.StatusOK, unclosedPositions)
+	return dealPage(c, page)
 }
 
 // GetClosedPositions calls method of Service by handler
 func (h *Handler) GetClosedPositions(c echo.Context) error {
-	profileID, err := h.getProfileID(c)
+	profileID, err := getProfileID(c)
 	if err != nil {
 		return echo.ErrUnauthorized
 	}
-	closedPositions, err := h.tradingService.GetClosedPositions(c.Request().Context(), profileID)
+	filter, err := parseDealFilter(c)
+	if err != nil {
+		return c.HTML(http.StatusBadRequest, `<script>alert('Invalid filter of positions');
+		 window.location.href = '/index';</script>`)
+	}
+	page, err := h.tradingService.FindClosedPositions(c.Request().Context(), profileID, filter)
 	if err != nil {
 		logrus.Errorf("getClosedPositions: %v", err)
-		return c.HTML(http.StatusBadRequest, `<script>alert('Failed to get positions');
+		return c.HTML(berrors.HTTPStatus(err), `<script>alert('Failed to get positions');
 		 window.location.href = '/index';</script>`)
 	}
-	return c.JSON(http.StatusOK, closedPositions)
+	return dealPage(c, page)
 }
 
 // GetPrices calls method of Service by handler
 func (h *Handler) GetPrices(c echo.Context) error {
-	shares, err := h.tradingService.GetPrices(c.Request().Context())
+	snapshot, err := h.tradingService.GetPriceSnapshot(c.Request().Context())
 	if err != nil {
 		logrus.Infof("getPrices: %v", err)
-		return c.HTML(http.StatusBadRequest, `<script>alert('Failed to get shares');
+		return c.HTML(berrors.HTTPStatus(err), `<script>alert('Failed to get shares');
 		 window.location.href = '/index';</script>`)
 	}
-	return c.JSON(http.StatusOK, shares)
+	if notModified(c, snapshot) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	return c.JSON(http.StatusOK, snapshot.Shares)
+}
+
+// alertMessage writes page which shows message in alert and goes to location. Message is escaped,
+// so it can contain quotes and any text without breaking the script
+func alertMessage(c echo.Context, status int, message, location string) error {
+	return c.HTML(status, `<script>alert('`+template.JSEscapeString(message)+`');
+	 window.location.href = '`+location+`';</script>`)
+}
+
+// notModified sets validators of prices to the response and checks if client already has the same prices
+func notModified(c echo.Context, snapshot *model.PriceSnapshot) bool {
+	header := c.Response().Header()
+	header.Set("ETag", snapshot.ETag)
+	header.Set(echo.HeaderLastModified, snapshot.UpdatedAt.Format(http.TimeFormat))
+	header.Set(echo.HeaderCacheControl, "no-cache")
+	if match := c.Request().Header.Get("If-None-Match"); match != "" {
+		for _, etag := range strings.Split(match, ",") {
+			etag = strings.TrimPrefix(strings.TrimSpace(etag), "W/")
+			if etag == snapshot.ETag || etag == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	since, err := http.ParseTime(c.Request().Header.Get(echo.HeaderIfModifiedSince))
+	return err == nil && !snapshot.UpdatedAt.After(since)
 }
 
 // Logout delete session of user
 func (h *Handler) Logout(c echo.Context) error {
-	store := NewRedisStore(&h.cfg)
-	cookie, err := c.Cookie("SESSION_ID")
-	if err != nil {
-		logrus.Errorf("logout %v", err)
-		return c.HTML(http.StatusInternalServerError, `<script>alert('Failed to get cookie');
-		window.location.href = '/';</script>`)
-	}
-	session, err := store.Get(c.Request(), cookie.Name)
-	if err != nil {
-		logrus.Errorf("logout %v", err)
-		return c.HTML(http.StatusBadRequest, `<script>alert('Failed to get your session');
-		window.location.href = '/';</script>`)
-	}
-	session.Options.MaxAge = -1
-	if err = session.Save(c.Request(), c.Response().Writer); err != nil {
+	if err := h.destroySession(c); err != nil {
 		logrus.Errorf("logout %v", err)
-		return c.HTML(http.StatusBadRequest, `<script>alert('Failed to log out');
+		return c.HTML(http.StatusInternalServerError, `<script>alert('Failed to log out');
 		 window.location.href = '/index';</script>`)
 	}
 	return c.Redirect(http.StatusSeeOther, "/")
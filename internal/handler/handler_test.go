@@ -8,11 +8,11 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"os"
-	"strconv"
 	"strings"
 	"testing"
 
 	"github.com/artnikel/APIService/internal/config"
+	berrors "github.com/artnikel/APIService/internal/errors"
 	"github.com/artnikel/APIService/internal/handler/mocks"
 	"github.com/artnikel/APIService/internal/model"
 	"github.com/go-playground/validator/v10"
@@ -32,7 +32,7 @@ var (
 	testBalance = model.Balance{
 		BalanceID: uuid.New(),
 		ProfileID: uuid.New(),
-		Operation: 637.81,
+		Operation: decimal.RequireFromString("637.81"),
 	}
 	testDeal = model.Deal{
 		SharesCount: decimal.NewFromFloat(1.5),
@@ -43,7 +43,7 @@ var (
 	}
 	testShare = model.Share{
 		Company: "Apple",
-		Price:   195.5,
+		Price:   decimal.RequireFromString("195.5"),
 	}
 	v   = validator.New()
 	cfg *config.Variables
@@ -61,7 +61,8 @@ func TestMain(m *testing.M) {
 
 func TestSignUp(t *testing.T) {
 	srv := new(mocks.UserService)
-	hndl := NewHandler(srv, nil, nil, v, cfg)
+	store := new(mocks.SessionStore)
+	hndl := NewHandler(Dependencies{UserService: srv, SessionStore: store}, v, cfg)
 
 	formData := url.Values{}
 	formData.Set("login", testUser.Login)
@@ -76,11 +77,12 @@ func TestSignUp(t *testing.T) {
 	}()
 
 	formDataReader := strings.NewReader(formData.Encode())
-	err = os.Chdir("../../../APIService")
+	err = os.Chdir("../..")
 	require.NoError(t, err)
 
 	srv.On("SignUp", mock.Anything, mock.AnythingOfType("*model.User")).Return(nil).Once()
 	srv.On("GetByLogin", mock.Anything, mock.AnythingOfType("*model.User")).Return(testUser.ID, nil).Once()
+	store.On("Save", mock.Anything, mock.AnythingOfType("*model.Session"), cfg.SessionIdleTimeout).Return(nil).Once()
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodPost, "/signup", formDataReader)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
@@ -91,11 +93,18 @@ func TestSignUp(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, http.StatusSeeOther, rec.Code)
 	srv.AssertExpectations(t)
+	store.AssertExpectations(t)
 }
 
 func TestLogin(t *testing.T) {
 	srv := new(mocks.UserService)
-	hndl := NewHandler(srv, nil, nil, v, cfg)
+	store := new(mocks.SessionStore)
+	guard := new(mocks.LoginGuard)
+	hndl := NewHandler(Dependencies{
+		UserService:  srv,
+		SessionStore: store,
+		LoginGuard:   guard,
+	}, v, cfg)
 
 	formData := url.Values{}
 	formData.Set("login", testUser.Login)
@@ -110,10 +119,13 @@ func TestLogin(t *testing.T) {
 	}()
 
 	formDataReader := strings.NewReader(formData.Encode())
-	err = os.Chdir("../../../APIService")
+	err = os.Chdir("../..")
 	require.NoError(t, err)
 
+	guard.On("Check", mock.Anything, testUser.Login, mock.AnythingOfType("string")).Return(nil).Once()
+	guard.On("Succeed", mock.Anything, testUser.Login, mock.AnythingOfType("string")).Return(nil).Once()
 	srv.On("GetByLogin", mock.Anything, mock.AnythingOfType("*model.User")).Return(testUser.ID, nil).Once()
+	store.On("Save", mock.Anything, mock.AnythingOfType("*model.Session"), cfg.SessionIdleTimeout).Return(nil).Once()
 	e := echo.New()
 
 	req := httptest.NewRequest(http.MethodPost, "/login", formDataReader)
@@ -125,32 +137,42 @@ func TestLogin(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, http.StatusSeeOther, rec.Code)
 	srv.AssertExpectations(t)
+	store.AssertExpectations(t)
+	guard.AssertExpectations(t)
 }
 
 func TestDeleteAccount(t *testing.T) {
 	usrv := new(mocks.UserService)
 	bsrv := new(mocks.BalanceService)
-	hndl := NewHandler(usrv, bsrv, nil, v, cfg)
+	store := new(mocks.SessionStore)
+	hndl := NewHandler(Dependencies{
+		UserService:    usrv,
+		BalanceService: bsrv,
+		SessionStore:   store,
+	}, v, cfg)
 	jsonData, err := json.Marshal(testBalance.ProfileID)
 	require.NoError(t, err)
 	usrv.On("DeleteAccount", mock.Anything, mock.AnythingOfType("uuid.UUID")).Return(testUser.ID.String(), nil).Once()
+	store.On("List", mock.Anything, testBalance.ProfileID).Return([]*model.Session{{ID: "testSession"}}, nil).Once()
+	store.On("Delete", mock.Anything, "testSession").Return(nil).Once()
 	e := echo.New()
 
 	req := httptest.NewRequest(http.MethodDelete, "/delete", bytes.NewReader(jsonData))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
+	c.Set(profileIDKey, testBalance.ProfileID)
 
 	err = hndl.DeleteAccount(c)
 	require.NoError(t, err)
 	usrv.AssertExpectations(t)
 	bsrv.AssertExpectations(t)
+	store.AssertExpectations(t)
 }
 
 func TestDeposit(t *testing.T) {
 	srv := new(mocks.BalanceService)
-	hndl := NewHandler(nil, srv, nil, v, cfg)
-	store := NewRedisStore(cfg)
+	hndl := NewHandler(Dependencies{BalanceService: srv}, v, cfg)
 
 	srv.On("BalanceOperation", mock.Anything, mock.AnythingOfType("*model.Balance")).Return(testBalance.Operation, nil).Once()
 
@@ -158,16 +180,14 @@ func TestDeposit(t *testing.T) {
 	req := httptest.NewRequest(http.MethodPost, "/deposit", http.NoBody)
 	req.Header.Set("Content-Type", "application/json")
 	req.Form = url.Values{}
-	req.Form.Add("operation", strconv.FormatFloat(testBalance.Operation, 'f', -1, 64))
-	session, err := store.Get(req, "SESSION_ID")
-	require.NoError(t, err)
-	req.AddCookie(&http.Cookie{Name: "SESSION_ID", Value: session.ID})
+	req.Form.Add("operation", testBalance.Operation.String())
 
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
+	c.Set(profileIDKey, testBalance.ProfileID)
 
-	strOperation := strconv.FormatFloat(testBalance.Operation, 'f', -1, 64)
-	err = hndl.Deposit(c)
+	strOperation := testBalance.Operation.String()
+	err := hndl.Deposit(c)
 	require.NoError(t, err)
 	require.Contains(t, rec.Body.String(), strOperation)
 	srv.AssertExpectations(t)
@@ -175,8 +195,7 @@ func TestDeposit(t *testing.T) {
 
 func TestWithdraw(t *testing.T) {
 	srv := new(mocks.BalanceService)
-	hndl := NewHandler(nil, srv, nil, v, cfg)
-	store := NewRedisStore(cfg)
+	hndl := NewHandler(Dependencies{BalanceService: srv}, v, cfg)
 
 	srv.On("BalanceOperation", mock.Anything, mock.AnythingOfType("*model.Balance")).Return(testBalance.Operation, nil).Once()
 
@@ -184,16 +203,14 @@ func TestWithdraw(t *testing.T) {
 	req := httptest.NewRequest(http.MethodPost, "/withdraw", http.NoBody)
 	req.Header.Set("Content-Type", "application/json")
 	req.Form = url.Values{}
-	req.Form.Add("operation", strconv.FormatFloat(testBalance.Operation, 'f', -1, 64))
-	session, err := store.Get(req, "SESSION_ID")
-	require.NoError(t, err)
-	req.AddCookie(&http.Cookie{Name: "SESSION_ID", Value: session.ID})
+	req.Form.Add("operation", testBalance.Operation.String())
 
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
+	c.Set(profileIDKey, testBalance.ProfileID)
 
-	strOperation := strconv.FormatFloat(testBalance.Operation, 'f', -1, 64)
-	err = hndl.Withdraw(c)
+	strOperation := testBalance.Operation.String()
+	err := hndl.Withdraw(c)
 	require.NoError(t, err)
 	require.Contains(t, rec.Body.String(), strOperation)
 	srv.AssertExpectations(t)
@@ -201,8 +218,7 @@ func TestWithdraw(t *testing.T) {
 
 func TestCreatePosition(t *testing.T) {
 	srv := new(mocks.TradingService)
-	hndl := NewHandler(nil, nil, srv, v, cfg)
-	store := NewRedisStore(cfg)
+	hndl := NewHandler(Dependencies{TradingService: srv}, v, cfg)
 
 	srv.On("CreatePosition", mock.Anything, mock.AnythingOfType("*model.Deal")).Return(nil).Once()
 
@@ -214,38 +230,81 @@ func TestCreatePosition(t *testing.T) {
 	req.Form.Add("sharescount", testDeal.SharesCount.String())
 	req.Form.Add("stoploss", testDeal.StopLoss.String())
 	req.Form.Add("takeprofit", testDeal.TakeProfit.String())
-	session, err := store.Get(req, "SESSION_ID")
-	require.NoError(t, err)
-	req.AddCookie(&http.Cookie{Name: "SESSION_ID", Value: session.ID})
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
+	c.Set(profileIDKey, testBalance.ProfileID)
 
-	err = hndl.CreatePosition(c)
+	err := hndl.CreatePosition(c)
 	require.NoError(t, err)
 	srv.AssertExpectations(t)
 }
 
+func TestCreatePositionEscapesBusinessError(t *testing.T) {
+	srv := new(mocks.TradingService)
+	hndl := NewHandler(Dependencies{TradingService: srv}, v, cfg)
+
+	srv.On("CreatePosition", mock.Anything, mock.AnythingOfType("*model.Deal")).
+		Return(berrors.New(berrors.InvalidRequest, "Stop loss and take profit can't be equal</script>")).Once()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/long", http.NoBody)
+	req.Form = url.Values{}
+	req.Form.Add("company", "');alert(document.cookie)//")
+	req.Form.Add("sharescount", testDeal.SharesCount.String())
+	req.Form.Add("stoploss", testDeal.StopLoss.String())
+	req.Form.Add("takeprofit", testDeal.StopLoss.String())
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(profileIDKey, testBalance.ProfileID)
+
+	require.NoError(t, hndl.CreatePosition(c))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), `alert('Stop loss and take profit can\'t be equal\u003C/script\u003E');`)
+	require.Contains(t, rec.Body.String(), `window.location.href = '/index';`)
+	srv.AssertExpectations(t)
+}
+
+func TestAttachTrailingStopEscapesBusinessError(t *testing.T) {
+	srv := new(mocks.TrailingStops)
+	hndl := NewHandler(Dependencies{TrailingStops: srv}, v, cfg)
+
+	srv.On("Attach", mock.Anything, mock.AnythingOfType("*model.TrailingStop")).
+		Return(berrors.New(berrors.InvalidRequest, "Distance must be positive and can't have more than 4 decimal places")).Once()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/trailing-stop", http.NoBody)
+	req.Form = url.Values{}
+	req.Form.Add("dealid", uuid.NewString())
+	req.Form.Add("type", "absolute")
+	req.Form.Add("distance", "0.00001")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(profileIDKey, testBalance.ProfileID)
+
+	require.NoError(t, hndl.AttachTrailingStop(c))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), `alert('Distance must be positive and can\'t have more than 4 decimal places');`)
+	srv.AssertExpectations(t)
+}
+
 func TestClosePositionManually(t *testing.T) {
 	tsrv := new(mocks.TradingService)
 	bsrv := new(mocks.BalanceService)
-	hndl := NewHandler(nil, bsrv, tsrv, v, cfg)
-	store := NewRedisStore(cfg)
+	hndl := NewHandler(Dependencies{BalanceService: bsrv, TradingService: tsrv}, v, cfg)
 
 	tsrv.On("ClosePositionManually", mock.Anything, mock.AnythingOfType("uuid.UUID"), mock.AnythingOfType("uuid.UUID")).
-		Return(testDeal.Profit.InexactFloat64(), nil).Once()
+		Return(testDeal.Profit, nil).Once()
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodPost, "/closeposition", http.NoBody)
 	req.Header.Set("Content-Type", "application/json")
 	req.Form = url.Values{}
 	req.Form.Add("dealid", testDeal.DealID.String())
-	session, err := store.Get(req, "SESSION_ID")
-	require.NoError(t, err)
-	req.AddCookie(&http.Cookie{Name: "SESSION_ID", Value: session.ID})
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
+	c.Set(profileIDKey, testBalance.ProfileID)
 
-	err = hndl.ClosePositionManually(c)
+	err := hndl.ClosePositionManually(c)
 	require.NoError(t, err)
 	bsrv.AssertExpectations(t)
 	tsrv.AssertExpectations(t)
@@ -254,17 +313,19 @@ func TestClosePositionManually(t *testing.T) {
 func TestGetUnclosedPositions(t *testing.T) {
 	tsrv := new(mocks.TradingService)
 	bsrv := new(mocks.BalanceService)
-	hndl := NewHandler(nil, bsrv, tsrv, v, cfg)
+	hndl := NewHandler(Dependencies{BalanceService: bsrv, TradingService: tsrv}, v, cfg)
 
 	var testDeals []*model.Deal
 	testDeals = append(testDeals, &testDeal)
-	tsrv.On("GetUnclosedPositions", mock.Anything, mock.AnythingOfType("uuid.UUID")).Return(testDeals, nil).Once()
+	tsrv.On("FindUnclosedPositions", mock.Anything, mock.AnythingOfType("uuid.UUID"), &model.DealFilter{}).
+		Return(&model.DealPage{Deals: testDeals}, nil).Once()
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/getunclosed", http.NoBody)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
+	c.Set(profileIDKey, testBalance.ProfileID)
 
 	err := hndl.GetUnclosedPositions(c)
 	require.NoError(t, err)
@@ -275,17 +336,19 @@ func TestGetUnclosedPositions(t *testing.T) {
 func TestGetClosedPositions(t *testing.T) {
 	tsrv := new(mocks.TradingService)
 	bsrv := new(mocks.BalanceService)
-	hndl := NewHandler(nil, bsrv, tsrv, v, cfg)
+	hndl := NewHandler(Dependencies{BalanceService: bsrv, TradingService: tsrv}, v, cfg)
 
 	var testDeals []*model.Deal
 	testDeals = append(testDeals, &testDeal)
-	tsrv.On("GetClosedPositions", mock.Anything, mock.AnythingOfType("uuid.UUID")).Return(testDeals, nil).Once()
+	tsrv.On("FindClosedPositions", mock.Anything, mock.AnythingOfType("uuid.UUID"), &model.DealFilter{}).
+		Return(&model.DealPage{Deals: testDeals}, nil).Once()
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/getclosed", http.NoBody)
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
+	c.Set(profileIDKey, testBalance.ProfileID)
 
 	err := hndl.GetClosedPositions(c)
 	require.NoError(t, err)
@@ -295,10 +358,10 @@ func TestGetClosedPositions(t *testing.T) {
 
 func TestGetPrices(t *testing.T) {
 	srv := new(mocks.TradingService)
-	hndl := NewHandler(nil, nil, srv, v, cfg)
+	hndl := NewHandler(Dependencies{TradingService: srv}, v, cfg)
 	var testShares []model.Share
 	testShares = append(testShares, testShare)
-	srv.On("GetPrices", mock.Anything).Return(testShares, nil).Once()
+	srv.On("GetPriceSnapshot", mock.Anything).Return(&model.PriceSnapshot{Shares: testShares}, nil).Once()
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/getprices", http.NoBody)
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// idempotencyHeader is a header with idempotency key of request sent by API clients
+	idempotencyHeader = "Idempotency-Key"
+	// idempotencyFormField is a name of hidden field of forms with idempotency key
+	idempotencyFormField = "idempotency_key"
+	// idempotencyReplayedHeader marks response which was saved for the first request with the same key
+	idempotencyReplayedHeader = "Idempotent-Replayed"
+	// maxIdempotencyKeyLength is a max length of idempotency key
+	maxIdempotencyKeyLength = 255
+)
+
+// responseRecorder passes response to the client and keeps a copy of its body
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write writes data to the client and to the copy of body
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// Idempotent is middleware that executes request with idempotency key only once. Response of the first request
+// is saved and replayed to retries with the same key, duplicates which come while it is processed wait for it.
+// Key is taken from Idempotency-Key header or from hidden field of the form, requests without key aren`t tracked.
+func (h *Handler) Idempotent(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		key := c.Request().Header.Get(idempotencyHeader)
+		if key == "" {
+			key = c.FormValue(idempotencyFormField)
+		}
+		if key == "" {
+			return next(c)
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return idempotencyError(c, berrors.New(berrors.InvalidRequest, "Idempotency key is too long"))
+		}
+		profileID, err := getProfileID(c)
+		if err != nil {
+			return err
+		}
+		fingerprint, err := requestFingerprint(c)
+		if err != nil {
+			logrus.Errorf("idempotent: %v", err)
+			return idempotencyError(c, err)
+		}
+		scope := sha256.Sum256([]byte(profileID.String() + "\n" + c.Request().Method + " " + c.Path() + "\n" + key))
+		scopeKey := hex.EncodeToString(scope[:])
+		saved, release, err := h.idempotency.Begin(c.Request().Context(), scopeKey)
+		if err != nil {
+			logrus.Errorf("idempotent: %v", err)
+			return idempotencyError(c, err)
+		}
+		if saved != nil {
+			return replayResponse(c, saved, fingerprint)
+		}
+		defer release()
+		recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+		c.Response().Writer = recorder
+		err = next(c)
+		c.Response().Writer = recorder.ResponseWriter
+		status := c.Response().Status
+		// failures of server and responses which weren`t written aren`t saved, so retry executes request again
+		if err != nil || !c.Response().Committed || status >= http.StatusInternalServerError ||
+			status == http.StatusTooManyRequests {
+			return err
+		}
+		resp := &model.IdempotentResponse{
+			Fingerprint: fingerprint,
+			Status:      status,
+			Header:      map[string]string{echo.HeaderContentType: c.Response().Header().Get(echo.HeaderContentType)},
+			Body:        recorder.body.Bytes(),
+		}
+		// response is saved even if client has gone, so its retry gets the result
+		if errComplete := h.idempotency.Complete(context.Background(), scopeKey, resp); errComplete != nil {
+			logrus.Errorf("idempotent: %v", errComplete)
+		}
+		return nil
+	}
+}
+
+// requestFingerprint returns hash of method, path and body of request, fields of parsed form are hashed
+// instead of body which was already read. CSRF token isn`t a part of fingerprint because it changes with session
+func requestFingerprint(c echo.Context) (string, error) {
+	req := c.Request()
+	hash := sha256.New()
+	hash.Write([]byte(req.Method + " " + req.URL.Path + "\n"))
+	if req.PostForm != nil {
+		form := make(url.Values, len(req.PostForm))
+		for name, values := range req.PostForm {
+			if name != csrfFormField {
+				form[name] = values
+			}
+		}
+		hash.Write([]byte(form.Encode()))
+		return hex.EncodeToString(hash.Sum(nil)), nil
+	}
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return "", fmt.Errorf("readAll %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		hash.Write(body)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// replayResponse writes response saved for the first request with the same key,
+// request with another method, path or body can`t reuse the key
+func replayResponse(c echo.Context, saved *model.IdempotentResponse, fingerprint string) error {
+	if saved.Fingerprint != fingerprint {
+		return idempotencyError(c, berrors.New(berrors.IdempotencyKeyReused,
+			"Idempotency key was used for another request"))
+	}
+	for name, value := range saved.Header {
+		c.Response().Header().Set(name, value)
+	}
+	c.Response().Header().Set(idempotencyReplayedHeader, "true")
+	return c.Blob(saved.Status, saved.Header[echo.HeaderContentType], saved.Body)
+}
+
+// idempotencyError writes error envelope for API clients and alert for the pages
+func idempotencyError(c echo.Context, err error) error {
+	if wantsJSON(c) {
+		return apiError(c, err, "Failed to process request")
+	}
+	message := "Failed to process request"
+	var e *berrors.BusinessError
+	if errors.As(err, &e) {
+		message = e.Message
+	}
+	return alertMessage(c, berrors.HTTPStatus(err), message, "/index")
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/handler/mocks"
+	"github.com/artnikel/APIService/internal/repository"
+	"github.com/artnikel/APIService/internal/service"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newIdempotentHandler(bsrv BalanceService) *Handler {
+	idempotency := service.NewIdempotencyService(repository.NewMemoryIdempotencyRepository(),
+		repository.NewMemoryLockRepository(), cfg)
+	return NewHandler(Dependencies{BalanceService: bsrv, Idempotency: idempotency}, v, cfg)
+}
+
+func idempotentDeposit(hndl *Handler, key, body string) (*httptest.ResponseRecorder, error) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/deposits", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(idempotencyHeader, key)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetPath("/api/v1/deposits")
+	c.Set(profileIDKey, testBalance.ProfileID)
+	return rec, hndl.Idempotent(hndl.APIDeposit)(c)
+}
+
+func TestIdempotentReplaysResponse(t *testing.T) {
+	bsrv := new(mocks.BalanceService)
+	hndl := newIdempotentHandler(bsrv)
+	bsrv.On("BalanceOperation", mock.Anything, mock.Anything).Return(testBalance.Operation, nil).Once()
+
+	first, err := idempotentDeposit(hndl, "testKey", `{"operation":637.81}`)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, first.Code)
+	require.Empty(t, first.Header().Get(idempotencyReplayedHeader))
+
+	replay, err := idempotentDeposit(hndl, "testKey", `{"operation":637.81}`)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, replay.Code)
+	require.Equal(t, "true", replay.Header().Get(idempotencyReplayedHeader))
+	require.Equal(t, first.Body.String(), replay.Body.String())
+
+	reused, err := idempotentDeposit(hndl, "testKey", `{"operation":1}`)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnprocessableEntity, reused.Code)
+	require.Contains(t, reused.Body.String(), berrors.IdempotencyKeyReused)
+	bsrv.AssertExpectations(t)
+}
+
+func TestIdempotentSerializesDuplicates(t *testing.T) {
+	bsrv := new(mocks.BalanceService)
+	hndl := newIdempotentHandler(bsrv)
+	bsrv.On("BalanceOperation", mock.Anything, mock.Anything).After(100*time.Millisecond).
+		Return(testBalance.Operation, nil).Once()
+
+	const duplicates = 5
+	var wg sync.WaitGroup
+	bodies := make([]string, duplicates)
+	for i := 0; i < duplicates; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rec, err := idempotentDeposit(hndl, "testKey", `{"operation":637.81}`)
+			require.NoError(t, err)
+			require.Equal(t, http.StatusOK, rec.Code)
+			bodies[i] = rec.Body.String()
+		}(i)
+	}
+	wg.Wait()
+	for _, body := range bodies {
+		require.Equal(t, bodies[0], body)
+	}
+	bsrv.AssertExpectations(t)
+}
+
+func TestIdempotentWithoutKey(t *testing.T) {
+	bsrv := new(mocks.BalanceService)
+	hndl := newIdempotentHandler(bsrv)
+	bsrv.On("BalanceOperation", mock.Anything, mock.Anything).Return(testBalance.Operation, nil).Twice()
+
+	for i := 0; i < 2; i++ {
+		rec, err := idempotentDeposit(hndl, "", `{"operation":637.81}`)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rec.Code)
+	}
+	bsrv.AssertExpectations(t)
+}
+
+func TestIdempotencyErrorEscapesMessage(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/deposit", http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	require.NoError(t, idempotencyError(c, berrors.New(berrors.RequestInProgress, "Request isn't finished yet')//")))
+	require.Equal(t, http.StatusConflict, rec.Code)
+	require.Contains(t, rec.Body.String(), `alert('Request isn\'t finished yet\')//');`)
+}
@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/artnikel/APIService/internal/config"
+	"github.com/labstack/echo/v4"
+)
+
+// NewIPExtractor returns extractor of ip address of client which is used for throttling of log in and in sessions.
+// Without trusted proxies address of connection is used, so clients can`t choose their ip by headers. Behind proxies
+// X-Forwarded-For is read only up to the first address which doesn`t belong to trusted ranges
+func NewIPExtractor(cfg *config.Variables) (echo.IPExtractor, error) {
+	if len(cfg.TrustedProxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, proxy := range cfg.TrustedProxies {
+		_, ipRange, err := net.ParseCIDR(strings.TrimSpace(proxy))
+		if err != nil {
+			return nil, fmt.Errorf("parseCIDR %w", err)
+		}
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
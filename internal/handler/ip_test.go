@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artnikel/APIService/internal/config"
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/handler/mocks"
+	"github.com/artnikel/APIService/internal/repository"
+	"github.com/artnikel/APIService/internal/service"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForgedForwardedForDoesntResetLoginThrottle(t *testing.T) {
+	guardCfg := *cfg
+	guardCfg.LoginIPMaxAttempts = 2
+	guardCfg.LoginBackoffBase = time.Minute
+	guard := service.NewLoginGuardService(repository.NewMemoryLoginAttemptRepository(), &guardCfg)
+	srv := new(mocks.UserService)
+	srv.On("GetByLogin", mock.Anything, mock.AnythingOfType("*model.User")).
+		Return(uuid.Nil, berrors.New(berrors.UserDoesntExists, "User doesnt exist")).Once()
+	hndl := NewHandler(Dependencies{UserService: srv, LoginGuard: guard}, v, &guardCfg)
+
+	e := echo.New()
+	var err error
+	e.IPExtractor, err = NewIPExtractor(&config.Variables{})
+	require.NoError(t, err)
+	e.POST("/api/v1/auth/login", hndl.APILogin)
+	for i, expected := range []int{http.StatusUnauthorized, http.StatusTooManyRequests, http.StatusTooManyRequests} {
+		body := `{"login":"` + []string{"first", "second", "third"}[i] + `","password":"wrongPassword"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderXForwardedFor, []string{"203.0.113.1", "203.0.113.2", "203.0.113.3"}[i])
+		req.Header.Set(echo.HeaderXRealIP, "198.51.100.7")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		require.Equal(t, expected, rec.Code)
+	}
+	srv.AssertExpectations(t)
+}
+
+func TestIPExtractorTrustsOnlyConfiguredProxies(t *testing.T) {
+	extractor, err := NewIPExtractor(&config.Variables{TrustedProxies: []string{"10.0.0.0/8"}})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	req.RemoteAddr = "10.1.2.3:4567"
+	req.Header.Set(echo.HeaderXForwardedFor, "203.0.113.9, 198.51.100.7")
+	require.Equal(t, "198.51.100.7", extractor(req))
+
+	req.RemoteAddr = "192.0.2.10:4567"
+	require.Equal(t, "192.0.2.10", extractor(req))
+
+	_, err = NewIPExtractor(&config.Variables{TrustedProxies: []string{"proxy"}})
+	require.Error(t, err)
+}
@@ -7,6 +7,8 @@ import (
 
 	mock "github.com/stretchr/testify/mock"
 
+	decimal "github.com/shopspring/decimal"
+
 	model "github.com/artnikel/APIService/internal/model"
 
 	uuid "github.com/google/uuid"
@@ -18,14 +20,14 @@ type BalanceService struct {
 }
 
 // BalanceOperation provides a mock function with given fields: ctx, balance
-func (_m *BalanceService) BalanceOperation(ctx context.Context, balance *model.Balance) (float64, error) {
+func (_m *BalanceService) BalanceOperation(ctx context.Context, balance *model.Balance) (decimal.Decimal, error) {
 	ret := _m.Called(ctx, balance)
 
-	var r0 float64
-	if rf, ok := ret.Get(0).(func(context.Context, *model.Balance) float64); ok {
+	var r0 decimal.Decimal
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Balance) decimal.Decimal); ok {
 		r0 = rf(ctx, balance)
 	} else {
-		r0 = ret.Get(0).(float64)
+		r0 = ret.Get(0).(decimal.Decimal)
 	}
 
 	var r1 error
@@ -39,14 +41,14 @@ func (_m *BalanceService) BalanceOperation(ctx context.Context, balance *model.B
 }
 
 // GetBalance provides a mock function with given fields: ctx, profileid
-func (_m *BalanceService) GetBalance(ctx context.Context, profileid uuid.UUID) (float64, error) {
+func (_m *BalanceService) GetBalance(ctx context.Context, profileid uuid.UUID) (decimal.Decimal, error) {
 	ret := _m.Called(ctx, profileid)
 
-	var r0 float64
-	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) float64); ok {
+	var r0 decimal.Decimal
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) decimal.Decimal); ok {
 		r0 = rf(ctx, profileid)
 	} else {
-		r0 = ret.Get(0).(float64)
+		r0 = ret.Get(0).(decimal.Decimal)
 	}
 
 	var r1 error
@@ -7,6 +7,8 @@ import (
 
 	mock "github.com/stretchr/testify/mock"
 
+	decimal "github.com/shopspring/decimal"
+
 	model "github.com/artnikel/APIService/internal/model"
 
 	uuid "github.com/google/uuid"
@@ -18,14 +20,14 @@ type TradingService struct {
 }
 
 // ClosePositionManually provides a mock function with given fields: ctx, dealid, profileid
-func (_m *TradingService) ClosePositionManually(ctx context.Context, dealid uuid.UUID, profileid uuid.UUID) (float64, error) {
+func (_m *TradingService) ClosePositionManually(ctx context.Context, dealid uuid.UUID, profileid uuid.UUID) (decimal.Decimal, error) {
 	ret := _m.Called(ctx, dealid, profileid)
 
-	var r0 float64
-	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID) float64); ok {
+	var r0 decimal.Decimal
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID) decimal.Decimal); ok {
 		r0 = rf(ctx, dealid, profileid)
 	} else {
-		r0 = ret.Get(0).(float64)
+		r0 = ret.Get(0).(decimal.Decimal)
 	}
 
 	var r1 error
@@ -52,6 +54,52 @@ func (_m *TradingService) CreatePosition(ctx context.Context, deal *model.Deal)
 	return r0
 }
 
+// FindClosedPositions provides a mock function with given fields: ctx, profileid, filter
+func (_m *TradingService) FindClosedPositions(ctx context.Context, profileid uuid.UUID, filter *model.DealFilter) (*model.DealPage, error) {
+	ret := _m.Called(ctx, profileid, filter)
+
+	var r0 *model.DealPage
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, *model.DealFilter) *model.DealPage); ok {
+		r0 = rf(ctx, profileid, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.DealPage)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, *model.DealFilter) error); ok {
+		r1 = rf(ctx, profileid, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindUnclosedPositions provides a mock function with given fields: ctx, profileid, filter
+func (_m *TradingService) FindUnclosedPositions(ctx context.Context, profileid uuid.UUID, filter *model.DealFilter) (*model.DealPage, error) {
+	ret := _m.Called(ctx, profileid, filter)
+
+	var r0 *model.DealPage
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, *model.DealFilter) *model.DealPage); ok {
+		r0 = rf(ctx, profileid, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.DealPage)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, *model.DealFilter) error); ok {
+		r1 = rf(ctx, profileid, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetClosedPositions provides a mock function with given fields: ctx, profileid
 func (_m *TradingService) GetClosedPositions(ctx context.Context, profileid uuid.UUID) ([]*model.Deal, error) {
 	ret := _m.Called(ctx, profileid)
@@ -75,6 +123,29 @@ func (_m *TradingService) GetClosedPositions(ctx context.Context, profileid uuid
 	return r0, r1
 }
 
+// GetPriceSnapshot provides a mock function with given fields: ctx
+func (_m *TradingService) GetPriceSnapshot(ctx context.Context) (*model.PriceSnapshot, error) {
+	ret := _m.Called(ctx)
+
+	var r0 *model.PriceSnapshot
+	if rf, ok := ret.Get(0).(func(context.Context) *model.PriceSnapshot); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.PriceSnapshot)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetPrices provides a mock function with given fields: ctx
 func (_m *TradingService) GetPrices(ctx context.Context) ([]model.Share, error) {
 	ret := _m.Called(ctx)
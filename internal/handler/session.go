@@ -0,0 +1,225 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// sessionIDLength is a count of random bytes in id of session
+	sessionIDLength = 32
+	// sessionHandleLength is a count of bytes of hash which identifies session in API without disclosing its id
+	sessionHandleLength = 16
+	// sessionTouchInterval is a minimal time between updates of the last request time of session
+	sessionTouchInterval = time.Minute
+)
+
+// sessionKey is a key of echo context where session of the request is cached after loading
+const sessionKey = "session"
+
+// newSessionID generates random id of session, it is also used for other secret tokens
+func newSessionID() (string, error) {
+	b := make([]byte, sessionIDLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("read %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// sessionHandle returns public identifier of session, id of session itself is a secret of cookie
+func sessionHandle(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:sessionHandleLength])
+}
+
+// sameSite converts value of config to SameSite mode of cookie
+func sameSite(mode string) http.SameSite {
+	switch strings.ToLower(mode) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
+
+// sessionCookie creates cookie of session with options from config, negative maxAge deletes cookie
+func (h *Handler) sessionCookie(value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     h.cfg.SessionCookieName,
+		Value:    value,
+		Path:     "/",
+		Domain:   h.cfg.SessionDomain,
+		MaxAge:   maxAge,
+		Secure:   h.cfg.SessionSecure,
+		HttpOnly: h.cfg.SessionHTTPOnly,
+		SameSite: sameSite(h.cfg.SessionSameSite),
+	}
+}
+
+// sessionTimeouts returns idle timeout and absolute lifetime of session
+func (h *Handler) sessionTimeouts(remember bool) (idle, absolute time.Duration) {
+	if remember {
+		return h.cfg.SessionRememberIdleTimeout, h.cfg.SessionRememberAbsoluteTimeout
+	}
+	return h.cfg.SessionIdleTimeout, h.cfg.SessionAbsoluteTimeout
+}
+
+// sessionTTL returns time for which session is kept in the store after the last activity,
+// it is idle timeout unless the absolute lifetime ends earlier
+func (h *Handler) sessionTTL(session *model.Session, now time.Time) time.Duration {
+	idle, _ := h.sessionTimeouts(session.Remember)
+	if left := session.ExpiresAt.Sub(now); left < idle {
+		return left
+	}
+	return idle
+}
+
+// loadSession returns session of the request or nil if there is no cookie or session is expired
+func (h *Handler) loadSession(c echo.Context) (*model.Session, error) {
+	if session, ok := c.Get(sessionKey).(*model.Session); ok {
+		return session, nil
+	}
+	cookie, err := c.Cookie(h.cfg.SessionCookieName)
+	if err != nil || cookie.Value == "" {
+		return nil, nil
+	}
+	session, err := h.sessionStore.Get(c.Request().Context(), cookie.Value)
+	if err != nil {
+		return nil, fmt.Errorf("get %w", err)
+	}
+	c.Set(sessionKey, session)
+	if session == nil {
+		return nil, nil
+	}
+	idle, _ := h.sessionTimeouts(session.Remember)
+	now := time.Now().UTC()
+	if now.After(session.ExpiresAt) || now.Sub(session.LastSeen) > idle {
+		if err = h.sessionStore.Delete(c.Request().Context(), session.ID); err != nil {
+			return nil, fmt.Errorf("delete %w", err)
+		}
+		c.Set(sessionKey, (*model.Session)(nil))
+		return nil, nil
+	}
+	return session, nil
+}
+
+// startSession starts session of authenticated user under a new id, so id known before log in can't be used
+// after it. Session which the request had before is deleted
+func (h *Handler) startSession(c echo.Context, profileID uuid.UUID, login string, remember bool) error {
+	if oldID := h.currentSessionID(c); oldID != "" {
+		if err := h.sessionStore.Delete(c.Request().Context(), oldID); err != nil {
+			return fmt.Errorf("delete %w", err)
+		}
+	}
+	id, err := newSessionID()
+	if err != nil {
+		return fmt.Errorf("newSessionID %w", err)
+	}
+	csrfToken, err := newSessionID()
+	if err != nil {
+		return fmt.Errorf("newSessionID %w", err)
+	}
+	now := time.Now().UTC()
+	_, absolute := h.sessionTimeouts(remember)
+	session := &model.Session{
+		ID:        id,
+		ProfileID: profileID,
+		Login:     login,
+		IssuedAt:  now,
+		LastSeen:  now,
+		ExpiresAt: now.Add(absolute),
+		Remember:  remember,
+		CSRFToken: csrfToken,
+		IP:        c.RealIP(),
+		UserAgent: c.Request().UserAgent(),
+	}
+	if err = h.sessionStore.Save(c.Request().Context(), session, h.sessionTTL(session, now)); err != nil {
+		return fmt.Errorf("save %w", err)
+	}
+	// cookie without max age lives until browser is closed
+	maxAge := 0
+	if remember {
+		maxAge = int(absolute.Seconds())
+	}
+	c.SetCookie(h.sessionCookie(session.ID, maxAge))
+	c.Set(sessionKey, session)
+	return nil
+}
+
+// destroySession deletes session of the request from the store and expires its cookie
+func (h *Handler) destroySession(c echo.Context) error {
+	cookie, err := c.Cookie(h.cfg.SessionCookieName)
+	if err != nil {
+		return fmt.Errorf("cookie %w", err)
+	}
+	if err = h.sessionStore.Delete(c.Request().Context(), cookie.Value); err != nil {
+		return fmt.Errorf("delete %w", err)
+	}
+	c.SetCookie(h.sessionCookie("", -1))
+	c.Set(sessionKey, (*model.Session)(nil))
+	return nil
+}
+
+// touchSession updates the last request time of session and slides its idle timeout,
+// session is never kept longer than its absolute lifetime
+func (h *Handler) touchSession(ctx context.Context, session *model.Session) error {
+	now := time.Now().UTC()
+	idle, _ := h.sessionTimeouts(session.Remember)
+	interval := sessionTouchInterval
+	if idle/2 < interval {
+		interval = idle / 2
+	}
+	if now.Sub(session.LastSeen) < interval {
+		return nil
+	}
+	ttl := h.sessionTTL(session, now)
+	if ttl <= 0 {
+		return nil
+	}
+	session.LastSeen = now
+	if err := h.sessionStore.Save(ctx, session, ttl); err != nil {
+		return fmt.Errorf("save %w", err)
+	}
+	return nil
+}
+
+// revokeSessions deletes all sessions of profile except session with id keepID, empty keepID deletes all sessions
+func (h *Handler) revokeSessions(ctx context.Context, profileID uuid.UUID, keepID string) (int, error) {
+	sessions, err := h.sessionStore.List(ctx, profileID)
+	if err != nil {
+		return 0, fmt.Errorf("list %w", err)
+	}
+	revoked := 0
+	for _, session := range sessions {
+		if session.ID == keepID {
+			continue
+		}
+		if err = h.sessionStore.Delete(ctx, session.ID); err != nil {
+			return revoked, fmt.Errorf("delete %w", err)
+		}
+		revoked++
+	}
+	return revoked, nil
+}
+
+// currentSessionID returns id of session from cookie of the request or empty string if there is no cookie
+func (h *Handler) currentSessionID(c echo.Context) string {
+	cookie, err := c.Cookie(h.cfg.SessionCookieName)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artnikel/APIService/internal/handler/mocks"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/artnikel/APIService/internal/repository"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionLifecycle(t *testing.T) {
+	hndl := NewHandler(Dependencies{SessionStore: repository.NewMemorySessionRepository()}, v, cfg)
+	e := echo.New()
+
+	req := httptest.NewRequest(http.MethodPost, "/login", http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	err := hndl.startSession(c, testUser.ID, testUser.Login, false)
+	require.NoError(t, err)
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	require.Equal(t, cfg.SessionCookieName, cookies[0].Name)
+	require.True(t, cookies[0].HttpOnly)
+
+	req = httptest.NewRequest(http.MethodGet, "/index", http.NoBody)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+	profileID, err := hndl.resolveProfileID(c)
+	require.NoError(t, err)
+	require.Equal(t, testUser.ID, profileID)
+
+	err = hndl.destroySession(c)
+	require.NoError(t, err)
+	require.Equal(t, -1, rec.Result().Cookies()[0].MaxAge)
+	_, err = hndl.resolveProfileID(c)
+	require.ErrorIs(t, err, echo.ErrUnauthorized)
+}
+
+func TestLoginKeepsPasswordOutOfSessionAndLogs(t *testing.T) {
+	srv := new(mocks.UserService)
+	store := new(mocks.SessionStore)
+	guard := new(mocks.LoginGuard)
+	hndl := NewHandler(Dependencies{
+		UserService:  srv,
+		SessionStore: store,
+		LoginGuard:   guard,
+	}, v, cfg)
+	logs := logtest.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+
+	originalDir, err := os.Getwd()
+	require.NoError(t, err)
+	defer func() {
+		errCh := os.Chdir(originalDir)
+		require.NoError(t, errCh)
+	}()
+	err = os.Chdir("../..")
+	require.NoError(t, err)
+
+	var saved []byte
+	guard.On("Check", mock.Anything, testUser.Login, mock.AnythingOfType("string")).Return(nil).Once()
+	guard.On("Succeed", mock.Anything, testUser.Login, mock.AnythingOfType("string")).Return(nil).Once()
+	srv.On("GetByLogin", mock.Anything, mock.AnythingOfType("*model.User")).Return(testUser.ID, nil).Once()
+	store.On("Save", mock.Anything, mock.AnythingOfType("*model.Session"), cfg.SessionIdleTimeout).
+		Run(func(args mock.Arguments) {
+			session := args.Get(1).(*model.Session)
+			require.Equal(t, testUser.ID, session.ProfileID)
+			saved, err = json.Marshal(session)
+			require.NoError(t, err)
+		}).Return(nil).Once()
+
+	e := echo.New()
+	for _, login := range []string{"bad", testUser.Login} {
+		formData := url.Values{}
+		formData.Set("login", login)
+		formData.Set("password", testUser.Password)
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(formData.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("User-Agent", "test-agent")
+		rec := httptest.NewRecorder()
+		err = hndl.Login(e.NewContext(req, rec))
+		require.NoError(t, err)
+	}
+
+	require.NotEmpty(t, saved)
+	require.NotContains(t, string(saved), testUser.Password)
+	require.Contains(t, string(saved), "test-agent")
+	require.NotEmpty(t, logs.AllEntries())
+	for _, entry := range logs.AllEntries() {
+		line, errString := entry.String()
+		require.NoError(t, errString)
+		require.NotContains(t, line, testUser.Password)
+	}
+	srv.AssertExpectations(t)
+	store.AssertExpectations(t)
+	guard.AssertExpectations(t)
+}
+
+func TestAPISessionsListAndRevoke(t *testing.T) {
+	hndl := NewHandler(Dependencies{SessionStore: repository.NewMemorySessionRepository()}, v, cfg)
+	e := echo.New()
+
+	cookies := make([]*http.Cookie, 0, 3)
+	for _, agent := range []string{"laptop", "phone", "tablet"} {
+		req := httptest.NewRequest(http.MethodPost, "/login", http.NoBody)
+		req.Header.Set("User-Agent", agent)
+		rec := httptest.NewRecorder()
+		err := hndl.startSession(e.NewContext(req, rec), testUser.ID, testUser.Login, false)
+		require.NoError(t, err)
+		cookies = append(cookies, rec.Result().Cookies()[0])
+	}
+
+	call := func(method, path string, handle echo.HandlerFunc, id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, http.NoBody)
+		req.AddCookie(cookies[0])
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+		c.Set(profileIDKey, testUser.ID)
+		require.NoError(t, handle(c))
+		return rec
+	}
+	list := func() []sessionResponse {
+		var sessions []sessionResponse
+		rec := call(http.MethodGet, "/api/v1/sessions", hndl.APIGetSessions, "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.NotContains(t, rec.Body.String(), cookies[1].Value)
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &sessions))
+		return sessions
+	}
+
+	sessions := list()
+	require.Len(t, sessions, 3)
+	var phone string
+	for _, session := range sessions {
+		require.Equal(t, session.UserAgent == "laptop", session.Current)
+		if session.UserAgent == "phone" {
+			phone = session.ID
+		}
+	}
+
+	rec := call(http.MethodDelete, "/api/v1/sessions/"+phone, hndl.APIRevokeSession, phone)
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	rec = call(http.MethodDelete, "/api/v1/sessions/unknown", hndl.APIRevokeSession, "unknown")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Len(t, list(), 2)
+
+	rec = call(http.MethodDelete, "/api/v1/sessions", hndl.APIRevokeOtherSessions, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"revoked":1}`, rec.Body.String())
+	sessions = list()
+	require.Len(t, sessions, 1)
+	require.True(t, sessions[0].Current)
+}
+
+func TestStartSessionRegeneratesID(t *testing.T) {
+	store := repository.NewMemorySessionRepository()
+	hndl := NewHandler(Dependencies{SessionStore: store}, v, cfg)
+	e := echo.New()
+	planted := &model.Session{ID: "planted", ProfileID: uuid.New(), LastSeen: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}
+	require.NoError(t, store.Save(context.Background(), planted, time.Hour))
+
+	req := httptest.NewRequest(http.MethodPost, "/login", http.NoBody)
+	req.AddCookie(&http.Cookie{Name: cfg.SessionCookieName, Value: planted.ID})
+	rec := httptest.NewRecorder()
+	err := hndl.startSession(e.NewContext(req, rec), testUser.ID, testUser.Login, true)
+	require.NoError(t, err)
+
+	cookie := rec.Result().Cookies()[0]
+	require.NotEqual(t, planted.ID, cookie.Value)
+	require.Equal(t, int(cfg.SessionRememberAbsoluteTimeout.Seconds()), cookie.MaxAge)
+	old, err := store.Get(context.Background(), planted.ID)
+	require.NoError(t, err)
+	require.Nil(t, old)
+	session, err := store.Get(context.Background(), cookie.Value)
+	require.NoError(t, err)
+	require.True(t, session.Remember)
+	require.Equal(t, testUser.ID, session.ProfileID)
+}
+
+func TestSessionTimeouts(t *testing.T) {
+	store := repository.NewMemorySessionRepository()
+	hndl := NewHandler(Dependencies{SessionStore: store}, v, cfg)
+	e := echo.New()
+	now := time.Now().UTC()
+
+	testCases := []struct {
+		name     string
+		session  model.Session
+		expected uuid.UUID
+	}{
+		{
+			name:     "active",
+			session:  model.Session{LastSeen: now.Add(-time.Minute), ExpiresAt: now.Add(time.Hour)},
+			expected: testUser.ID,
+		},
+		{
+			name:    "idle",
+			session: model.Session{LastSeen: now.Add(-cfg.SessionIdleTimeout - time.Minute), ExpiresAt: now.Add(time.Hour)},
+		},
+		{
+			name:    "absolute",
+			session: model.Session{LastSeen: now, ExpiresAt: now.Add(-time.Second)},
+		},
+		{
+			name: "remember",
+			session: model.Session{LastSeen: now.Add(-cfg.SessionIdleTimeout - time.Minute), ExpiresAt: now.Add(time.Hour),
+				Remember: true},
+			expected: testUser.ID,
+		},
+	}
+	for _, tc := range testCases {
+		session := tc.session
+		session.ID = tc.name
+		session.ProfileID = testUser.ID
+		require.NoError(t, store.Save(context.Background(), &session, time.Hour))
+
+		req := httptest.NewRequest(http.MethodGet, "/index", http.NoBody)
+		req.AddCookie(&http.Cookie{Name: cfg.SessionCookieName, Value: session.ID})
+		profileID, err := hndl.resolveProfileID(e.NewContext(req, httptest.NewRecorder()))
+		if tc.expected == uuid.Nil {
+			require.ErrorIs(t, err, echo.ErrUnauthorized, tc.name)
+			continue
+		}
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.expected, profileID, tc.name)
+		touched, err := store.Get(context.Background(), session.ID)
+		require.NoError(t, err)
+		require.True(t, touched.LastSeen.After(session.LastSeen), tc.name)
+	}
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// streamRetry is a time in milliseconds after which browser reconnects to the stream
+const streamRetry = 3000
+
+// StreamPrices sends updates of prices as Server-Sent Events until client disconnects.
+// Client which reconnects with Last-Event-ID header gets the update it missed, comments keep connection alive
+func (h *Handler) StreamPrices(c echo.Context) error {
+	lastEventID, err := strconv.ParseUint(c.Request().Header.Get("Last-Event-ID"), 10, 64)
+	if err != nil {
+		lastEventID = 0
+	}
+	events, unsubscribe := h.priceStream.Subscribe(lastEventID)
+	defer unsubscribe()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+	if err = h.writeStream(c, fmt.Sprintf("retry: %d\n\n", streamRetry)); err != nil {
+		return nil
+	}
+
+	heartbeat := time.NewTicker(h.cfg.PriceStreamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case <-heartbeat.C:
+			err = h.writeStream(c, ": heartbeat\n\n")
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			err = h.writeEvent(c, &event)
+		}
+		if err != nil {
+			logrus.Infof("streamPrices: %v", err)
+			return nil
+		}
+	}
+}
+
+// writeEvent writes update of prices in format of Server-Sent Events
+func (h *Handler) writeEvent(c echo.Context, event *model.PriceEvent) error {
+	data, err := json.Marshal(event.Shares)
+	if err != nil {
+		return fmt.Errorf("marshal %w", err)
+	}
+	return h.writeStream(c, fmt.Sprintf("id: %d\nevent: prices\ndata: %s\n\n", event.ID, data))
+}
+
+// writeStream writes message to the stream and flushes it. Client which doesn`t read the stream in time
+// is disconnected by write deadline, so it doesn`t hold the handler
+func (h *Handler) writeStream(c echo.Context, message string) error {
+	rc := http.NewResponseController(c.Response())
+	if err := rc.SetWriteDeadline(time.Now().Add(h.cfg.PriceStreamWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return fmt.Errorf("setWriteDeadline %w", err)
+	}
+	if _, err := c.Response().Write([]byte(message)); err != nil {
+		return fmt.Errorf("write %w", err)
+	}
+	c.Response().Flush()
+	return nil
+}
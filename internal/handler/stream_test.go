@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/artnikel/APIService/internal/handler/mocks"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamPrices(t *testing.T) {
+	stream := new(mocks.PriceStream)
+	hndl := NewHandler(Dependencies{PriceStream: stream}, v, cfg)
+	events := make(chan model.PriceEvent, 1)
+	events <- model.PriceEvent{ID: 8, Shares: []model.Share{testShare}, Time: time.Now()}
+	close(events)
+	unsubscribed := false
+	stream.On("Subscribe", uint64(7)).Return((<-chan model.PriceEvent)(events), func() { unsubscribed = true }).Once()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/stream/prices", http.NoBody)
+	req.Header.Set("Last-Event-ID", "7")
+	rec := httptest.NewRecorder()
+	err := hndl.StreamPrices(e.NewContext(req, rec))
+	require.NoError(t, err)
+
+	require.Equal(t, "text/event-stream", rec.Header().Get(echo.HeaderContentType))
+	require.Equal(t, "retry: 3000\n\nid: 8\nevent: prices\ndata: [{\"company\":\"Apple\",\"price\":\"195.5\"}]\n\n", rec.Body.String())
+	require.True(t, rec.Flushed)
+	require.True(t, unsubscribed)
+	stream.AssertExpectations(t)
+}
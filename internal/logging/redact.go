@@ -0,0 +1,86 @@
+// Package logging contains hooks of logrus logger
+package logging
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Redacted is a value which replaces sensitive data in log entries
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are parts of field names whose values are never written to logs
+var sensitiveKeys = []string{"password", "passwd", "token", "secret", "cookie", "authorization", "session"} // nolint gochecknoglobals
+
+// sensitivePatterns find credentials inside of messages and string values
+var sensitivePatterns = []struct { // nolint gochecknoglobals
+	re          *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`(?i)\bbearer\s+[\w\-.~+/]+=*`), "Bearer " + Redacted},
+	{regexp.MustCompile(`\beyJ[\w-]*\.[\w-]*\.[\w-]*`), Redacted},
+	{
+		regexp.MustCompile(`(?i)((?:password|passwd|token|secret|cookie|authorization|session_id)["']?\s*[:=]\s*["']?)[^\s"'&,;}]+`),
+		"${1}" + Redacted,
+	},
+}
+
+// RedactHook is a logrus hook that removes passwords, tokens and cookies from entries before they are written
+type RedactHook struct{}
+
+// NewRedactHook creates an object of *RedactHook
+func NewRedactHook() *RedactHook {
+	return &RedactHook{}
+}
+
+// Levels returns all levels because credentials must be redacted on every level
+func (hook *RedactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire redacts fields with sensitive names and credentials found in message and values of fields
+func (hook *RedactHook) Fire(entry *logrus.Entry) error {
+	entry.Message = RedactString(entry.Message)
+	for key, value := range entry.Data {
+		if isSensitiveKey(key) {
+			entry.Data[key] = Redacted
+			continue
+		}
+		switch val := value.(type) {
+		case string:
+			entry.Data[key] = RedactString(val)
+		case error:
+			if redacted := RedactString(val.Error()); redacted != val.Error() {
+				entry.Data[key] = redacted
+			}
+		default:
+			formatted := fmt.Sprintf("%+v", val)
+			if redacted := RedactString(formatted); redacted != formatted {
+				entry.Data[key] = redacted
+			}
+		}
+	}
+	return nil
+}
+
+// RedactString replaces credentials found in s
+func RedactString(s string) string {
+	for _, pattern := range sensitivePatterns {
+		s = pattern.re.ReplaceAllString(s, pattern.replacement)
+	}
+	return s
+}
+
+// isSensitiveKey checks if name of field contains one of sensitive keys
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
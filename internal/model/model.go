@@ -17,15 +17,15 @@ type User struct {
 
 // Share is a struct for shares entity
 type Share struct {
-	Company string  `json:"company" form:"company"`
-	Price   float64 `json:"price" form:"price"`
+	Company string          `json:"company" form:"company"`
+	Price   decimal.Decimal `json:"price" form:"price"`
 }
 
 // Balance contains an info about the balance and will be written in a balance table
 type Balance struct {
-	BalanceID uuid.UUID `json:"-" validate:"required,uuid"`                          // id of balance operation - each operation have new id
-	ProfileID uuid.UUID `json:"-" validate:"required,uuid"`                          // same value as ID in struct User
-	Operation float64   `json:"operation" validate:"required,gt=0" form:"operation"` // sum of money to be deposit or withdraw
+	BalanceID uuid.UUID       `json:"-" validate:"required,uuid"` // id of balance operation - each operation have new id
+	ProfileID uuid.UUID       `json:"-" validate:"required,uuid"` // same value as ID in struct User
+	Operation decimal.Decimal `json:"operation" form:"operation"` // sum of money to be deposit or withdraw
 }
 
 // Deal is a struct for creating new deals
@@ -41,3 +41,245 @@ type Deal struct {
 	EndDealTime   time.Time       `json:"enddealtime" form:"enddealtime"`                   // time of closing position
 	Profit        decimal.Decimal `json:"profit" form:"profit"`                             // revenue of position
 }
+
+// TokenPair contains signed access token and refresh token for authentication of API clients
+type TokenPair struct {
+	AccessToken  string    `json:"accesstoken"`  // short-lived token sent in Authorization header
+	RefreshToken string    `json:"refreshtoken"` // long-lived token for getting a new pair of tokens
+	ExpiresAt    time.Time `json:"expiresat"`    // expiration time of access token
+}
+
+// Session contains identity and metadata of browser session which are kept in the session store.
+// Credentials of user must never be saved in session
+type Session struct {
+	ID        string    `json:"-"`         // random id of session which is sent in cookie
+	ProfileID uuid.UUID `json:"profileid"` // id of authenticated user
+	Login     string    `json:"login"`     // username of authenticated user
+	IssuedAt  time.Time `json:"issuedat"`  // time when user logged in
+	LastSeen  time.Time `json:"lastseen"`  // time of the last request in session
+	ExpiresAt time.Time `json:"expiresat"` // time when session expires regardless of activity
+	Remember  bool      `json:"remember"`  // session was started with "remember me" and has longer timeouts
+	CSRFToken string    `json:"csrftoken"` // token which must be sent with every state-changing request of session
+	IP        string    `json:"ip"`        // ip address of client which logged in
+	UserAgent string    `json:"useragent"` // user agent of client which logged in
+}
+
+// LoginLockout is a record of audit trail which is written when logging in is locked after too many failed attempts
+type LoginLockout struct {
+	Subject     string    `json:"subject"`     // "login" or "ip" depending on what was locked
+	Value       string    `json:"value"`       // locked login or ip address
+	Failures    int       `json:"failures"`    // count of failed attempts which caused the lockout
+	LockedAt    time.Time `json:"lockedat"`    // time of lockout
+	LockedUntil time.Time `json:"lockeduntil"` // time when logging in is allowed again
+}
+
+// PriceEvent is an update of prices of all shares which is streamed to clients
+type PriceEvent struct {
+	ID     uint64    `json:"id"`     // sequence number of update, it is sent as id of event for resuming the stream
+	Shares []Share   `json:"shares"` // current prices of shares
+	Time   time.Time `json:"time"`   // time when prices were received
+}
+
+// PriceSnapshot is a cached list of prices of all shares
+type PriceSnapshot struct {
+	Shares    []Share   // prices of shares
+	ETag      string    // strong validator of shares, it changes only when prices change
+	UpdatedAt time.Time // time when prices changed the last time
+	Stale     bool      // prices are older than TTL of cache because they couldn`t be refreshed in time
+}
+
+// Candle is an OHLC bar of price of share for some interval
+type Candle struct {
+	Time  time.Time       `json:"time"`  // start of interval
+	Open  decimal.Decimal `json:"open"`  // the first price in interval
+	High  decimal.Decimal `json:"high"`  // the highest price in interval
+	Low   decimal.Decimal `json:"low"`   // the lowest price in interval
+	Close decimal.Decimal `json:"close"` // the last price in interval
+}
+
+// Position is an open deal valued with current price of share
+type Position struct {
+	DealID               uuid.UUID       `json:"dealid"`               // id of deal
+	Company              string          `json:"company"`              // name of company in share
+	Direction            string          `json:"direction"`            // long or short
+	SharesCount          decimal.Decimal `json:"sharescount"`          // amount of shares in deal
+	PurchasePrice        decimal.Decimal `json:"purchaseprice"`        // entry price in position
+	CurrentPrice         decimal.Decimal `json:"currentprice"`         // price of share now
+	Cost                 decimal.Decimal `json:"cost"`                 // money which was paid for opening position
+	MarketValue          decimal.Decimal `json:"marketvalue"`          // shares count multiplied by current price
+	UnrealizedPnL        decimal.Decimal `json:"unrealizedpnl"`        // profit if position was closed now
+	UnrealizedPnLPercent decimal.Decimal `json:"unrealizedpnlpercent"` // profit in percent of cost
+	StopLoss             decimal.Decimal `json:"stoploss"`             // price which closes position with loss
+	TakeProfit           decimal.Decimal `json:"takeprofit"`           // price which closes position with profit
+	StopLossDistance     decimal.Decimal `json:"stoplossdistance"`     // percent of current price left to stop loss
+	TakeProfitDistance   decimal.Decimal `json:"takeprofitdistance"`   // percent of current price left to take profit
+	DealTime             time.Time       `json:"dealtime"`             // entry time in position
+}
+
+// CompanyExposure contains totals of open positions in shares of one company
+type CompanyExposure struct {
+	Company              string          `json:"company"`              // name of company in share
+	Positions            int             `json:"positions"`            // count of open positions
+	Cost                 decimal.Decimal `json:"cost"`                 // money which was paid for opening positions
+	MarketValue          decimal.Decimal `json:"marketvalue"`          // market value of all positions
+	UnrealizedPnL        decimal.Decimal `json:"unrealizedpnl"`        // profit if positions were closed now
+	UnrealizedPnLPercent decimal.Decimal `json:"unrealizedpnlpercent"` // profit in percent of cost
+}
+
+// Portfolio contains open positions of user valued with current prices and equity of account
+type Portfolio struct {
+	Balance       decimal.Decimal    `json:"balance"`       // free money of user
+	Cost          decimal.Decimal    `json:"cost"`          // money which was paid for opening positions
+	MarketValue   decimal.Decimal    `json:"marketvalue"`   // market value of all positions
+	UnrealizedPnL decimal.Decimal    `json:"unrealizedpnl"` // profit if all positions were closed now
+	Equity        decimal.Decimal    `json:"equity"`        // balance with money which closing of all positions returns
+	Positions     []*Position        `json:"positions"`     // open positions
+	Companies     []*CompanyExposure `json:"companies"`     // totals per company ordered by name
+	PricesAt      time.Time          `json:"pricesat"`      // time when prices were received
+	Stale         bool               `json:"stale"`         // prices are older than TTL of cache
+}
+
+// TradeStats contains performance figures of closed deals
+type TradeStats struct {
+	Company            string           `json:"company,omitempty"`  // name of company, empty for all companies
+	Trades             int              `json:"trades"`             // count of closed deals
+	Wins               int              `json:"wins"`               // count of deals with profit
+	Losses             int              `json:"losses"`             // count of deals with loss
+	WinRate            decimal.Decimal  `json:"winrate"`            // percent of deals with profit
+	NetProfit          decimal.Decimal  `json:"netprofit"`          // sum of profits and losses
+	GrossProfit        decimal.Decimal  `json:"grossprofit"`        // sum of profits
+	GrossLoss          decimal.Decimal  `json:"grossloss"`          // sum of losses as positive number
+	ProfitFactor       *decimal.Decimal `json:"profitfactor"`       // gross profit divided by gross loss, null without losses
+	AverageWin         decimal.Decimal  `json:"averagewin"`         // mean profit of winning deals
+	AverageLoss        decimal.Decimal  `json:"averageloss"`        // mean loss of losing deals as positive number
+	MedianWin          decimal.Decimal  `json:"medianwin"`          // median profit of winning deals
+	MedianLoss         decimal.Decimal  `json:"medianloss"`         // median loss of losing deals as positive number
+	LargestWin         decimal.Decimal  `json:"largestwin"`         // the biggest profit
+	LargestLoss        decimal.Decimal  `json:"largestloss"`        // the biggest loss as positive number
+	AverageHoldSeconds int64            `json:"averageholdseconds"` // mean time between opening and closing of deal
+	MaxDrawdown        decimal.Decimal  `json:"maxdrawdown"`        // the biggest fall of cumulative profit from its peak
+}
+
+// TradeAnalytics contains performance figures of closed deals for range of time in total and per company
+type TradeAnalytics struct {
+	From      time.Time     `json:"from,omitempty"` // start of range of closing time
+	To        time.Time     `json:"to,omitempty"`   // end of range of closing time
+	Total     *TradeStats   `json:"total"`          // figures of all deals
+	Companies []*TradeStats `json:"companies"`      // figures per company ordered by name
+}
+
+// DealFilter contains conditions, order and page of listed deals
+type DealFilter struct {
+	Company    string    // name of company, empty for all companies
+	From       time.Time // the earliest entry time, zero for any
+	To         time.Time // entry time must be before it, zero for any
+	ClosedFrom time.Time // the earliest time of closing, zero for any
+	ClosedTo   time.Time // time of closing must be before it, zero for any
+	Profit     string    // sign of profit: positive, negative or zero, empty for any
+	SortBy     string    // field of deal which orders deals
+	Descending bool      // order from the biggest value
+	Limit      int       // the biggest count of deals in page, zero for all deals
+	Cursor     string    // position after the last deal of previous page
+}
+
+// DealPage contains deals of one page and cursor of the next page
+type DealPage struct {
+	Deals      []*Deal // deals of page
+	NextCursor string  // cursor of the next page, empty on the last page
+}
+
+// TradeRow is a deal in export of trade history, open deal is valued with current price of share
+type TradeRow struct {
+	DealID        uuid.UUID        `json:"dealid"`        // id of deal
+	Status        string           `json:"status"`        // closed or open
+	Company       string           `json:"company"`       // name of company in share
+	Direction     string           `json:"direction"`     // long or short
+	SharesCount   decimal.Decimal  `json:"sharescount"`   // amount of shares in deal
+	PurchasePrice decimal.Decimal  `json:"purchaseprice"` // entry price in position
+	StopLoss      decimal.Decimal  `json:"stoploss"`      // price which closes position with loss
+	TakeProfit    decimal.Decimal  `json:"takeprofit"`    // price which closes position with profit
+	DealTime      time.Time        `json:"dealtime"`      // entry time in position
+	EndDealTime   *time.Time       `json:"enddealtime"`   // time of closing position, null for open deal
+	CurrentPrice  *decimal.Decimal `json:"currentprice"`  // price of share now, null for closed deal
+	Profit        decimal.Decimal  `json:"profit"`        // realized profit of closed deal or unrealized profit of open deal
+}
+
+// LedgerEntry is a record of local append-only ledger about change of balance
+type LedgerEntry struct {
+	Seq       uint64          `json:"-"`                // position of entry in ledger of profile
+	ID        uuid.UUID       `json:"id"`               // id of balance operation
+	ProfileID uuid.UUID       `json:"-"`                // id of user/profile
+	Time      time.Time       `json:"time"`             // time when operation was made
+	Type      string          `json:"type"`             // credit or debit
+	Origin    string          `json:"origin"`           // user, trade_open or trade_close
+	DealID    *uuid.UUID      `json:"dealid,omitempty"` // id of deal for operations of trades if it is known
+	Amount    decimal.Decimal `json:"amount"`           // change of balance, negative for debit
+	Balance   decimal.Decimal `json:"balance"`          // balance reported by balance service after operation
+}
+
+// UserOperation is a deposit or withdrawal of user which is counted against limits of operations with balance
+type UserOperation struct {
+	ID     uuid.UUID       `json:"id"`     // id of balance operation
+	Time   time.Time       `json:"time"`   // time when operation was made
+	Amount decimal.Decimal `json:"amount"` // change of balance, negative for withdrawal
+}
+
+// StatementLine is an entry of ledger in statement with balance after all entries of statement up to it
+type StatementLine struct {
+	*LedgerEntry
+	RunningBalance decimal.Decimal `json:"runningbalance"` // opening balance of statement with amounts up to this entry
+}
+
+// Statement contains page of entries of ledger for range of time with balances of the range
+type Statement struct {
+	From           time.Time        `json:"from,omitempty"`       // start of range
+	To             time.Time        `json:"to,omitempty"`         // end of range
+	OpeningBalance decimal.Decimal  `json:"openingbalance"`       // balance before the first entry of range
+	ClosingBalance decimal.Decimal  `json:"closingbalance"`       // balance after the last entry of range
+	Lines          []*StatementLine `json:"lines"`                // entries of page
+	NextCursor     string           `json:"nextcursor,omitempty"` // cursor of the next page, empty on the last page
+}
+
+// IdempotentResponse is a response to request with idempotency key which is replayed to retries of the request
+type IdempotentResponse struct {
+	Fingerprint string            `json:"fingerprint"` // hash of method, path and body of the first request
+	Status      int               `json:"status"`      // HTTP status code of response
+	Header      map[string]string `json:"header"`      // headers of response which are replayed
+	Body        []byte            `json:"body"`        // body of response
+}
+
+// Order is a pending entry order which opens position when price of company reaches the trigger price
+type Order struct {
+	OrderID      uuid.UUID       `json:"orderid"`             // id of order
+	ProfileID    uuid.UUID       `json:"-"`                   // id of user/profile
+	Type         string          `json:"type"`                // limit or stop
+	Company      string          `json:"company"`             // name of company in share
+	SharesCount  decimal.Decimal `json:"sharescount"`         // amount of shares of position
+	TriggerPrice decimal.Decimal `json:"triggerprice"`        // price which triggers opening of position
+	StopLoss     decimal.Decimal `json:"stoploss"`            // stop loss of position
+	TakeProfit   decimal.Decimal `json:"takeprofit"`          // take profit of position
+	Status       string          `json:"status"`              // pending, triggered, filled, cancelled, expired or failed
+	CreatedAt    time.Time       `json:"createdat"`           // time of placing order
+	ExpiresAt    time.Time       `json:"expiresat,omitempty"` // time when pending order expires, zero means never
+	ClosedAt     time.Time       `json:"closedat,omitempty"`  // time when order left pending status the last time
+	DealID       uuid.UUID       `json:"dealid"`              // id of position opened by order, set when it is triggered
+	Reason       string          `json:"reason,omitempty"`    // why order failed
+}
+
+// TrailingStop is a stop of open position which follows the best price of share at the given distance
+type TrailingStop struct {
+	DealID        uuid.UUID       `json:"dealid"`             // id of position
+	ProfileID     uuid.UUID       `json:"-"`                  // id of user/profile
+	Company       string          `json:"company"`            // name of company in share
+	Direction     string          `json:"direction"`          // long or short
+	Type          string          `json:"type"`               // absolute or percent
+	Distance      decimal.Decimal `json:"distance"`           // distance from the best price in dollars or percents
+	StopLoss      decimal.Decimal `json:"stoploss"`           // fixed stop loss of position
+	BestPrice     decimal.Decimal `json:"bestprice"`          // the highest price for long and the lowest for short
+	EffectiveStop decimal.Decimal `json:"effectivestop"`      // trailing stop or fixed stop loss if it is closer to price
+	Status        string          `json:"status"`             // active, closing, closed, cancelled or failed
+	UpdatedAt     time.Time       `json:"updatedat"`          // time of the last change of stop
+	ClosedAt      time.Time       `json:"closedat,omitempty"` // time when stop stopped following price
+	Profit        decimal.Decimal `json:"profit"`             // profit of position closed by stop
+	Reason        string          `json:"reason,omitempty"`   // why position wasn`t closed by stop
+}
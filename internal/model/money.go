@@ -0,0 +1,42 @@
+package model
+
+import "github.com/shopspring/decimal"
+
+// Currency describes how amounts of money in the currency are rounded and limited
+type Currency struct {
+	Code   string // ISO 4217 code of currency
+	Places int32  // count of digits after decimal point in amounts of currency
+}
+
+// USD is a currency of balances, prices and profits, all backends keep money in it
+var USD = Currency{Code: "USD", Places: 2} // nolint gochecknoglobals
+
+const (
+	// PricePlaces is a count of digits after decimal point in prices of shares, stop losses and take profits
+	PricePlaces int32 = 4
+	// QuantityPlaces is a count of digits after decimal point in counts of shares
+	QuantityPlaces int32 = 8
+	// MaxSignificantDigits is a count of significant digits which survive conversion to float64 of gRPC messages,
+	// values with more digits are rejected instead of being silently changed by backends
+	MaxSignificantDigits = 15
+)
+
+// Round rounds amount to places of currency. Banker`s rounding is used, so errors of rounding many amounts
+// don`t accumulate in one direction
+func (c Currency) Round(amount decimal.Decimal) decimal.Decimal {
+	return amount.RoundBank(c.Places)
+}
+
+// Fits reports if amount can be kept in currency without rounding
+func (c Currency) Fits(amount decimal.Decimal) bool {
+	return FitsPrecision(amount, c.Places)
+}
+
+// FitsPrecision reports if value has at most places digits after decimal point
+// and at most MaxSignificantDigits digits at all
+func FitsPrecision(value decimal.Decimal, places int32) bool {
+	if !value.Equal(value.Truncate(places)) {
+		return false
+	}
+	return value.Abs().LessThan(decimal.New(1, MaxSignificantDigits-places))
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCurrencyFits(t *testing.T) {
+	testCases := []struct {
+		amount string
+		fits   bool
+	}{
+		{"637.81", true},
+		{"-0.01", true},
+		{"100", true},
+		{"0.001", false},
+		{"19.999", false},
+		{"9999999999999.99", true},
+		{"10000000000000", false},
+	}
+	for _, tc := range testCases {
+		require.Equal(t, tc.fits, USD.Fits(decimal.RequireFromString(tc.amount)), tc.amount)
+	}
+	require.True(t, FitsPrecision(decimal.RequireFromString("1.12345678"), QuantityPlaces))
+	require.False(t, FitsPrecision(decimal.RequireFromString("195.12345"), PricePlaces))
+}
+
+func TestCurrencyRound(t *testing.T) {
+	for amount, rounded := range map[string]string{"0.125": "0.12", "0.135": "0.14", "-2.675": "-2.68", "10": "10"} {
+		require.Equal(t, rounded, USD.Round(decimal.RequireFromString(amount)).String(), amount)
+	}
+}
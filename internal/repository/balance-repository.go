@@ -3,13 +3,12 @@ package repository
 import (
 	"context"
 	"fmt"
-	"strconv"
 
 	berrors "github.com/artnikel/APIService/internal/errors"
 	"github.com/artnikel/APIService/internal/model"
 	bproto "github.com/artnikel/BalanceService/proto"
 	"github.com/google/uuid"
-	"google.golang.org/grpc/status"
+	"github.com/shopspring/decimal"
 )
 
 // BalanceRepository represents the client of Balance Service repository implementation.
@@ -25,31 +24,27 @@ func NewBalanceRepository(client bproto.BalanceServiceClient) *BalanceRepository
 }
 
 // BalanceOperation call a method of BalanceService.
-func (b *BalanceRepository) BalanceOperation(ctx context.Context, balance *model.Balance) (float64, error) {
+func (b *BalanceRepository) BalanceOperation(ctx context.Context, balance *model.Balance) (decimal.Decimal, error) {
 	resp, err := b.client.BalanceOperation(ctx, &bproto.BalanceOperationRequest{Balance: &bproto.Balance{
 		Balanceid: balance.BalanceID.String(),
 		Profileid: balance.ProfileID.String(),
-		Operation: balance.Operation,
+		Operation: toWire(balance.Operation),
 	}})
 	if err != nil {
-		grpcStatus, ok := status.FromError(err)
-		if ok && grpcStatus.Message() == berrors.NotEnoughMoney {
-			return 0, berrors.New(berrors.NotEnoughMoney, "Not enough money")
-		}
-		return 0, fmt.Errorf("balanceOperation %w", err)
+		return decimal.Zero, fmt.Errorf("balanceOperation %w", berrors.FromGRPC(err))
 	}
-	operation, err := strconv.ParseFloat(resp.Operation, 64)
+	operation, err := decimal.NewFromString(resp.Operation)
 	if err != nil {
-		return 0, fmt.Errorf("parseFloat %w", err)
+		return decimal.Zero, fmt.Errorf("newFromString %w", err)
 	}
-	return operation, nil
+	return model.USD.Round(operation), nil
 }
 
 // GetBalance call a method of BalanceService.
-func (b *BalanceRepository) GetBalance(ctx context.Context, profileid uuid.UUID) (float64, error) {
+func (b *BalanceRepository) GetBalance(ctx context.Context, profileid uuid.UUID) (decimal.Decimal, error) {
 	resp, err := b.client.GetBalance(ctx, &bproto.GetBalanceRequest{Profileid: profileid.String()})
 	if err != nil {
-		return 0, fmt.Errorf("getBalance %w", err)
+		return decimal.Zero, fmt.Errorf("getBalance %w", berrors.FromGRPC(err))
 	}
-	return resp.Money, nil
+	return fromWire(resp.Money, model.USD.Places), nil
 }
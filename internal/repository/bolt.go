@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/artnikel/APIService/internal/config"
+	"github.com/shopspring/decimal"
+	"go.etcd.io/bbolt"
+)
+
+const (
+	boltFileMode    = 0o600
+	boltOpenTimeout = 5 * time.Second
+)
+
+// NewBoltDB opens embedded database which is shared by all repositories of local data
+func NewBoltDB(cfg *config.Variables) (*bbolt.DB, error) {
+	db, err := bbolt.Open(cfg.DataPath, boltFileMode, &bbolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("open %w", err)
+	}
+	return db, nil
+}
+
+// timeKey encodes time as a key which keeps chronological order of keys in bucket
+func timeKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	return key
+}
+
+// keyTime decodes time from key created by timeKey
+func keyTime(key []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(key))).UTC()
+}
+
+// encodeDecimals encodes numbers as a value of bucket, every number keeps all its digits
+func encodeDecimals(values ...decimal.Decimal) []byte {
+	texts := make([]string, len(values))
+	for i, value := range values {
+		texts[i] = value.String()
+	}
+	return []byte(strings.Join(texts, " "))
+}
+
+// decodeDecimals decodes numbers from a value created by encodeDecimals
+func decodeDecimals(data []byte) ([]decimal.Decimal, error) {
+	texts := strings.Fields(string(data))
+	values := make([]decimal.Decimal, len(texts))
+	for i, text := range texts {
+		value, err := decimal.NewFromString(text)
+		if err != nil {
+			return nil, fmt.Errorf("newFromString %w", err)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/garyburd/redigo/redis"
+)
+
+const idempotencyPrefix = "idempotency:"
+
+// IdempotencyRepository keeps responses to requests with idempotency keys in Redis.
+type IdempotencyRepository struct {
+	pool *redis.Pool
+}
+
+// NewIdempotencyRepository creates and returns a new instance of IdempotencyRepository, using the provided redis.Pool.
+func NewIdempotencyRepository(pool *redis.Pool) *IdempotencyRepository {
+	return &IdempotencyRepository{pool: pool}
+}
+
+// Get returns response saved by key or nil if there is no such response.
+func (i *IdempotencyRepository) Get(ctx context.Context, key string) (*model.IdempotentResponse, error) {
+	conn, err := i.pool.GetContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getContext %w", err)
+	}
+	defer closeConn(conn)
+	data, err := redis.Bytes(conn.Do("GET", idempotencyPrefix+key))
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get %w", err)
+	}
+	resp := &model.IdempotentResponse{}
+	if err = json.Unmarshal(data, resp); err != nil {
+		return nil, fmt.Errorf("unmarshal %w", err)
+	}
+	return resp, nil
+}
+
+// Save saves response by key for the given time.
+func (i *IdempotencyRepository) Save(ctx context.Context, key string, resp *model.IdempotentResponse, ttl time.Duration) error {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("marshal %w", err)
+	}
+	conn, err := i.pool.GetContext(ctx)
+	if err != nil {
+		return fmt.Errorf("getContext %w", err)
+	}
+	defer closeConn(conn)
+	if _, err = conn.Do("SET", idempotencyPrefix+key, data, "PX", ttl.Milliseconds()); err != nil {
+		return fmt.Errorf("set %w", err)
+	}
+	return nil
+}
+
+// MemoryIdempotencyRepository keeps responses to requests with idempotency keys in memory of the process.
+type MemoryIdempotencyRepository struct {
+	storage *memoryStorage
+}
+
+// NewMemoryIdempotencyRepository creates and returns a new instance of MemoryIdempotencyRepository.
+func NewMemoryIdempotencyRepository() *MemoryIdempotencyRepository {
+	return &MemoryIdempotencyRepository{storage: newMemoryStorage()}
+}
+
+// Get returns response saved by key or nil if there is no such response.
+func (m *MemoryIdempotencyRepository) Get(_ context.Context, key string) (*model.IdempotentResponse, error) {
+	data, ok := m.storage.get(key)
+	if !ok {
+		return nil, nil
+	}
+	resp := &model.IdempotentResponse{}
+	if err := json.Unmarshal([]byte(data), resp); err != nil {
+		return nil, fmt.Errorf("unmarshal %w", err)
+	}
+	return resp, nil
+}
+
+// Save saves response by key for the given time.
+func (m *MemoryIdempotencyRepository) Save(_ context.Context, key string, resp *model.IdempotentResponse, ttl time.Duration) error {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("marshal %w", err)
+	}
+	m.storage.set(key, string(data), ttl)
+	return nil
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/google/uuid"
+	"go.etcd.io/bbolt"
+)
+
+var ledgerBucket = []byte("ledger") // nolint gochecknoglobals
+
+// LedgerRepository keeps append-only ledger of balance operations in embedded database. Every profile has its own
+// nested bucket where entries are keyed by sequence, so entries are never overwritten and are read in order of appending
+type LedgerRepository struct {
+	db *bbolt.DB
+}
+
+// NewLedgerRepository creates and returns a new instance of LedgerRepository, using the provided bbolt.DB.
+func NewLedgerRepository(db *bbolt.DB) (*LedgerRepository, error) {
+	err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(ledgerBucket)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("update %w", err)
+	}
+	return &LedgerRepository{db: db}, nil
+}
+
+// Append adds entry to the end of ledger of its profile and sets its sequence.
+func (l *LedgerRepository) Append(_ context.Context, entry *model.LedgerEntry) error {
+	err := l.db.Update(func(tx *bbolt.Tx) error {
+		profile, err := tx.Bucket(ledgerBucket).CreateBucketIfNotExists([]byte(entry.ProfileID.String()))
+		if err != nil {
+			return fmt.Errorf("createBucketIfNotExists %w", err)
+		}
+		if entry.Seq, err = profile.NextSequence(); err != nil {
+			return fmt.Errorf("nextSequence %w", err)
+		}
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("marshal %w", err)
+		}
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, entry.Seq)
+		return profile.Put(key, data)
+	})
+	if err != nil {
+		return fmt.Errorf("update %w", err)
+	}
+	return nil
+}
+
+// GetEntries returns all entries of ledger of profile in order of appending.
+func (l *LedgerRepository) GetEntries(_ context.Context, profileID uuid.UUID) ([]*model.LedgerEntry, error) {
+	var entries []*model.LedgerEntry
+	err := l.db.View(func(tx *bbolt.Tx) error {
+		profile := tx.Bucket(ledgerBucket).Bucket([]byte(profileID.String()))
+		if profile == nil {
+			return nil
+		}
+		return profile.ForEach(func(key, value []byte) error {
+			entry := &model.LedgerEntry{}
+			if err := json.Unmarshal(value, entry); err != nil {
+				return fmt.Errorf("unmarshal %w", err)
+			}
+			entry.Seq = binary.BigEndian.Uint64(key)
+			entry.ProfileID = profileID
+			entries = append(entries, entry)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("view %w", err)
+	}
+	return entries, nil
+}
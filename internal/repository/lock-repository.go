@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+const lockPrefix = "lock:"
+
+// releaseScript deletes lock only if it is still held by the given token, so lock which expired
+// and was taken by another holder isn't released by mistake
+var releaseScript = redis.NewScript(1, `if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`) // nolint gochecknoglobals
+
+// extendScript prolongs lock only if it is still held by the given token
+var extendScript = redis.NewScript(1, `if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`) // nolint gochecknoglobals
+
+// LockRepository keeps locks which are shared by all instances of APIService in Redis.
+type LockRepository struct {
+	pool *redis.Pool
+}
+
+// NewLockRepository creates and returns a new instance of LockRepository, using the provided redis.Pool.
+func NewLockRepository(pool *redis.Pool) *LockRepository {
+	return &LockRepository{pool: pool}
+}
+
+// Acquire takes lock by key for holder with the given token, it reports false if lock is held by someone else.
+// Lock is released automatically when ttl ends.
+func (l *LockRepository) Acquire(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	conn, err := l.pool.GetContext(ctx)
+	if err != nil {
+		return false, fmt.Errorf("getContext %w", err)
+	}
+	defer closeConn(conn)
+	_, err = redis.String(conn.Do("SET", lockPrefix+key, token, "NX", "PX", ttl.Milliseconds()))
+	if err == redis.ErrNil {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("set %w", err)
+	}
+	return true, nil
+}
+
+// Release releases lock by key if it is held by holder with the given token.
+func (l *LockRepository) Release(ctx context.Context, key, token string) error {
+	conn, err := l.pool.GetContext(ctx)
+	if err != nil {
+		return fmt.Errorf("getContext %w", err)
+	}
+	defer closeConn(conn)
+	if _, err = releaseScript.Do(conn, lockPrefix+key, token); err != nil {
+		return fmt.Errorf("do %w", err)
+	}
+	return nil
+}
+
+// Extend prolongs lock by key for the given time, it reports false if lock isn`t held by holder with the given token.
+func (l *LockRepository) Extend(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	conn, err := l.pool.GetContext(ctx)
+	if err != nil {
+		return false, fmt.Errorf("getContext %w", err)
+	}
+	defer closeConn(conn)
+	extended, err := redis.Int(extendScript.Do(conn, lockPrefix+key, token, ttl.Milliseconds()))
+	if err != nil {
+		return false, fmt.Errorf("do %w", err)
+	}
+	return extended == 1, nil
+}
+
+// MemoryLockRepository keeps locks in memory of the process, it is used when APIService runs in one instance.
+type MemoryLockRepository struct {
+	storage *memoryStorage
+}
+
+// NewMemoryLockRepository creates and returns a new instance of MemoryLockRepository.
+func NewMemoryLockRepository() *MemoryLockRepository {
+	return &MemoryLockRepository{storage: newMemoryStorage()}
+}
+
+// Acquire takes lock by key for holder with the given token, it reports false if lock is held by someone else.
+func (m *MemoryLockRepository) Acquire(_ context.Context, key, token string, ttl time.Duration) (bool, error) {
+	return m.storage.setNX(key, token, ttl), nil
+}
+
+// Release releases lock by key if it is held by holder with the given token.
+func (m *MemoryLockRepository) Release(_ context.Context, key, token string) error {
+	m.storage.deleteIf(key, token)
+	return nil
+}
+
+// Extend prolongs lock by key for the given time, it reports false if lock isn`t held by holder with the given token.
+func (m *MemoryLockRepository) Extend(_ context.Context, key, token string, ttl time.Duration) (bool, error) {
+	return m.storage.expireIf(key, token, ttl), nil
+}
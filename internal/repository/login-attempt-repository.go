@@ -0,0 +1,234 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/garyburd/redigo/redis"
+)
+
+const (
+	loginAttemptPrefix = "login_attempts:"
+	loginBlockPrefix   = "login_block:"
+	loginLockoutsKey   = "login_lockouts"
+	// maxLoginLockouts is a count of the latest lockouts which are kept in the audit trail
+	maxLoginLockouts = 10000
+)
+
+// addAttemptScript increments counter of attempts and sets its expiration with the first attempt,
+// so counter never stays without expiration when connection fails between the commands
+var addAttemptScript = redis.NewScript(1, `local attempts = redis.call("INCR", KEYS[1])
+if attempts == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return attempts`) // nolint gochecknoglobals
+
+// removeAttemptScript decrements counter of attempts only if it exists, so expired counter isn't created again
+var removeAttemptScript = redis.NewScript(1, `local attempts = tonumber(redis.call("GET", KEYS[1]) or "0")
+if attempts > 0 then
+	return redis.call("DECR", KEYS[1])
+end
+return 0`) // nolint gochecknoglobals
+
+// LoginAttemptRepository keeps counters of log in attempts, blocks and audit trail of lockouts in Redis.
+type LoginAttemptRepository struct {
+	pool *redis.Pool
+}
+
+// NewLoginAttemptRepository creates and returns a new instance of LoginAttemptRepository, using the provided redis.Pool.
+func NewLoginAttemptRepository(pool *redis.Pool) *LoginAttemptRepository {
+	return &LoginAttemptRepository{pool: pool}
+}
+
+// AddAttempt atomically increments count of attempts by key and returns it,
+// counter is reset when window after the first attempt ends.
+func (l *LoginAttemptRepository) AddAttempt(ctx context.Context, key string, window time.Duration) (int, error) {
+	conn, err := l.pool.GetContext(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("getContext %w", err)
+	}
+	defer closeConn(conn)
+	attempts, err := redis.Int(addAttemptScript.Do(conn, loginAttemptPrefix+key, window.Milliseconds()))
+	if err != nil {
+		return 0, fmt.Errorf("do %w", err)
+	}
+	return attempts, nil
+}
+
+// RemoveAttempt decrements count of attempts by key, it is used when attempt wasn`t finished.
+func (l *LoginAttemptRepository) RemoveAttempt(ctx context.Context, key string) error {
+	conn, err := l.pool.GetContext(ctx)
+	if err != nil {
+		return fmt.Errorf("getContext %w", err)
+	}
+	defer closeConn(conn)
+	if _, err = removeAttemptScript.Do(conn, loginAttemptPrefix+key); err != nil {
+		return fmt.Errorf("do %w", err)
+	}
+	return nil
+}
+
+// Attempts returns count of attempts by key in the current window.
+func (l *LoginAttemptRepository) Attempts(ctx context.Context, key string) (int, error) {
+	conn, err := l.pool.GetContext(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("getContext %w", err)
+	}
+	defer closeConn(conn)
+	attempts, err := redis.Int(conn.Do("GET", loginAttemptPrefix+key))
+	if err == redis.ErrNil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("get %w", err)
+	}
+	return attempts, nil
+}
+
+// Block forbids attempts by key for the given time.
+func (l *LoginAttemptRepository) Block(ctx context.Context, key string, ttl time.Duration) error {
+	conn, err := l.pool.GetContext(ctx)
+	if err != nil {
+		return fmt.Errorf("getContext %w", err)
+	}
+	defer closeConn(conn)
+	if _, err = conn.Do("SET", loginBlockPrefix+key, 1, "PX", ttl.Milliseconds()); err != nil {
+		return fmt.Errorf("set %w", err)
+	}
+	return nil
+}
+
+// BlockedFor returns time left until attempts by key are allowed, zero if they aren`t blocked.
+func (l *LoginAttemptRepository) BlockedFor(ctx context.Context, key string) (time.Duration, error) {
+	conn, err := l.pool.GetContext(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("getContext %w", err)
+	}
+	defer closeConn(conn)
+	left, err := redis.Int64(conn.Do("PTTL", loginBlockPrefix+key))
+	if err != nil {
+		return 0, fmt.Errorf("pttl %w", err)
+	}
+	if left <= 0 {
+		return 0, nil
+	}
+	return time.Duration(left) * time.Millisecond, nil
+}
+
+// Reset removes attempts and block by key.
+func (l *LoginAttemptRepository) Reset(ctx context.Context, key string) error {
+	conn, err := l.pool.GetContext(ctx)
+	if err != nil {
+		return fmt.Errorf("getContext %w", err)
+	}
+	defer closeConn(conn)
+	if _, err = conn.Do("DEL", loginAttemptPrefix+key, loginBlockPrefix+key); err != nil {
+		return fmt.Errorf("del %w", err)
+	}
+	return nil
+}
+
+// AddLockout writes lockout to the audit trail, only the latest lockouts are kept.
+func (l *LoginAttemptRepository) AddLockout(ctx context.Context, lockout *model.LoginLockout) error {
+	data, err := json.Marshal(lockout)
+	if err != nil {
+		return fmt.Errorf("marshal %w", err)
+	}
+	conn, err := l.pool.GetContext(ctx)
+	if err != nil {
+		return fmt.Errorf("getContext %w", err)
+	}
+	defer closeConn(conn)
+	if _, err = conn.Do("LPUSH", loginLockoutsKey, data); err != nil {
+		return fmt.Errorf("lpush %w", err)
+	}
+	if _, err = conn.Do("LTRIM", loginLockoutsKey, 0, maxLoginLockouts-1); err != nil {
+		return fmt.Errorf("ltrim %w", err)
+	}
+	return nil
+}
+
+// MemoryLoginAttemptRepository keeps counters of failed log in attempts, blocks and audit trail of lockouts
+// in memory of a single instance.
+type MemoryLoginAttemptRepository struct {
+	storage  *memoryStorage
+	mu       sync.Mutex
+	lockouts []*model.LoginLockout
+}
+
+// NewMemoryLoginAttemptRepository creates and returns a new instance of MemoryLoginAttemptRepository.
+func NewMemoryLoginAttemptRepository() *MemoryLoginAttemptRepository {
+	return &MemoryLoginAttemptRepository{storage: newMemoryStorage()}
+}
+
+// AddAttempt atomically increments count of attempts by key and returns it,
+// counter is reset when window after the first attempt ends.
+func (m *MemoryLoginAttemptRepository) AddAttempt(_ context.Context, key string, window time.Duration) (int, error) {
+	attempts, err := m.storage.incr(loginAttemptPrefix+key, window)
+	if err != nil {
+		return 0, fmt.Errorf("incr %w", err)
+	}
+	return attempts, nil
+}
+
+// RemoveAttempt decrements count of attempts by key, it is used when attempt wasn`t finished.
+func (m *MemoryLoginAttemptRepository) RemoveAttempt(_ context.Context, key string) error {
+	if err := m.storage.decr(loginAttemptPrefix + key); err != nil {
+		return fmt.Errorf("decr %w", err)
+	}
+	return nil
+}
+
+// Attempts returns count of attempts by key in the current window.
+func (m *MemoryLoginAttemptRepository) Attempts(_ context.Context, key string) (int, error) {
+	value, ok := m.storage.get(loginAttemptPrefix + key)
+	if !ok {
+		return 0, nil
+	}
+	attempts, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("atoi %w", err)
+	}
+	return attempts, nil
+}
+
+// Block forbids attempts by key for the given time.
+func (m *MemoryLoginAttemptRepository) Block(_ context.Context, key string, ttl time.Duration) error {
+	m.storage.set(loginBlockPrefix+key, "1", ttl)
+	return nil
+}
+
+// BlockedFor returns time left until attempts by key are allowed, zero if they aren`t blocked.
+func (m *MemoryLoginAttemptRepository) BlockedFor(_ context.Context, key string) (time.Duration, error) {
+	return m.storage.expiresIn(loginBlockPrefix + key), nil
+}
+
+// Reset removes attempts and block by key.
+func (m *MemoryLoginAttemptRepository) Reset(_ context.Context, key string) error {
+	m.storage.pop(loginAttemptPrefix + key)
+	m.storage.pop(loginBlockPrefix + key)
+	return nil
+}
+
+// AddLockout writes lockout to the audit trail, only the latest lockouts are kept.
+func (m *MemoryLoginAttemptRepository) AddLockout(_ context.Context, lockout *model.LoginLockout) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lockouts = append(m.lockouts, lockout)
+	if len(m.lockouts) > maxLoginLockouts {
+		m.lockouts = m.lockouts[len(m.lockouts)-maxLoginLockouts:]
+	}
+	return nil
+}
+
+// Lockouts returns audit trail of lockouts, the oldest first.
+func (m *MemoryLoginAttemptRepository) Lockouts() []*model.LoginLockout {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*model.LoginLockout(nil), m.lockouts...)
+}
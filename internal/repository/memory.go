@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// memoryItem is a value of memoryStorage with its expiration time
+type memoryItem struct {
+	value     string
+	expiresAt time.Time
+}
+
+// memoryStorage is a key-value storage with expiration of keys for in-memory repositories
+type memoryStorage struct {
+	mu    sync.Mutex
+	items map[string]memoryItem
+}
+
+// newMemoryStorage creates an empty memoryStorage
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{items: make(map[string]memoryItem)}
+}
+
+// set saves value by key for the given time, zero ttl means that key never expires
+func (m *memoryStorage) set(key, value string, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item := memoryItem{value: value}
+	if ttl > 0 {
+		item.expiresAt = time.Now().Add(ttl)
+	}
+	m.items[key] = item
+}
+
+// get returns value by key and false if there is no such key or it is expired
+func (m *memoryStorage) get(key string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.items[key]
+	if !ok {
+		return "", false
+	}
+	if !item.expiresAt.IsZero() && time.Now().After(item.expiresAt) {
+		delete(m.items, key)
+		return "", false
+	}
+	return item.value, true
+}
+
+// pop removes value by key and returns it, so only one of concurrent callers gets the value
+func (m *memoryStorage) pop(key string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.items[key]
+	if !ok {
+		return "", false
+	}
+	delete(m.items, key)
+	if !item.expiresAt.IsZero() && time.Now().After(item.expiresAt) {
+		return "", false
+	}
+	return item.value, true
+}
+
+// incr increments integer value by key, the key gets the given ttl only when it is created
+func (m *memoryStorage) incr(key string, ttl time.Duration) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.items[key]
+	if !ok || (!item.expiresAt.IsZero() && time.Now().After(item.expiresAt)) {
+		item = memoryItem{value: "0"}
+		if ttl > 0 {
+			item.expiresAt = time.Now().Add(ttl)
+		}
+	}
+	value, err := strconv.Atoi(item.value)
+	if err != nil {
+		return 0, fmt.Errorf("atoi %w", err)
+	}
+	value++
+	item.value = strconv.Itoa(value)
+	m.items[key] = item
+	return value, nil
+}
+
+// decr decrements integer value by key only if key exists and value is positive, ttl of the key is kept
+func (m *memoryStorage) decr(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.items[key]
+	if !ok || (!item.expiresAt.IsZero() && time.Now().After(item.expiresAt)) {
+		return nil
+	}
+	value, err := strconv.Atoi(item.value)
+	if err != nil {
+		return fmt.Errorf("atoi %w", err)
+	}
+	if value > 0 {
+		item.value = strconv.Itoa(value - 1)
+		m.items[key] = item
+	}
+	return nil
+}
+
+// expiresIn returns time left until key expires, zero if there is no such key or it never expires
+func (m *memoryStorage) expiresIn(key string) time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.items[key]
+	if !ok || item.expiresAt.IsZero() {
+		return 0
+	}
+	left := time.Until(item.expiresAt)
+	if left <= 0 {
+		delete(m.items, key)
+		return 0
+	}
+	return left
+}
+
+// setNX saves value by key for the given time only if there is no such key, it reports if value was saved
+func (m *memoryStorage) setNX(key, value string, ttl time.Duration) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if item, ok := m.items[key]; ok && (item.expiresAt.IsZero() || time.Now().Before(item.expiresAt)) {
+		return false
+	}
+	item := memoryItem{value: value}
+	if ttl > 0 {
+		item.expiresAt = time.Now().Add(ttl)
+	}
+	m.items[key] = item
+	return true
+}
+
+// deleteIf removes key only if it has the given value, it reports if key was removed
+func (m *memoryStorage) deleteIf(key, value string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.items[key]
+	if !ok || item.value != value {
+		return false
+	}
+	delete(m.items, key)
+	return true
+}
+
+// expireIf sets the given ttl of key only if it has the given value, it reports if ttl was set
+func (m *memoryStorage) expireIf(key, value string, ttl time.Duration) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.items[key]
+	if !ok || item.value != value || (!item.expiresAt.IsZero() && time.Now().After(item.expiresAt)) {
+		return false
+	}
+	item.expiresAt = time.Time{}
+	if ttl > 0 {
+		item.expiresAt = time.Now().Add(ttl)
+	}
+	m.items[key] = item
+	return true
+}
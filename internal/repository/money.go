@@ -0,0 +1,15 @@
+package repository
+
+import "github.com/shopspring/decimal"
+
+// toWire converts decimal value to float64 of gRPC messages. Values which fit
+// model.MaxSignificantDigits are converted to the nearest float64 which is converted back by fromWire without loss
+func toWire(value decimal.Decimal) float64 {
+	return value.InexactFloat64()
+}
+
+// fromWire converts float64 of gRPC messages to decimal value rounded to places,
+// rounding removes noise of binary fractions which came from arithmetic of backends
+func fromWire(value float64, places int32) decimal.Decimal {
+	return decimal.NewFromFloat(value).RoundBank(places)
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/artnikel/APIService/internal/model"
+	bproto "github.com/artnikel/BalanceService/proto"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// realisticAmounts are sums of money which lose cents if they are added as float64
+var realisticAmounts = []string{"0.01", "0.1", "0.2", "0.3", "19.99", "100.07", "637.81", "1234567.89", "9999999999999.99"} // nolint gochecknoglobals
+
+// walletClient keeps balance like balance service does: it converts float64 of request to decimal
+// and returns balance as float64
+type walletClient struct {
+	money decimal.Decimal
+}
+
+func (w *walletClient) BalanceOperation(_ context.Context, in *bproto.BalanceOperationRequest,
+	_ ...grpc.CallOption) (*bproto.BalanceOperationResponse, error) {
+	operation := decimal.NewFromFloat(in.Balance.Operation)
+	w.money = w.money.Add(operation)
+	return &bproto.BalanceOperationResponse{Operation: operation.String()}, nil
+}
+
+func (w *walletClient) GetBalance(_ context.Context, _ *bproto.GetBalanceRequest,
+	_ ...grpc.CallOption) (*bproto.GetBalanceResponse, error) {
+	return &bproto.GetBalanceResponse{Money: w.money.InexactFloat64()}, nil
+}
+
+func TestWireRoundTrip(t *testing.T) {
+	for _, amount := range realisticAmounts {
+		value := decimal.RequireFromString(amount)
+		require.True(t, value.Equal(fromWire(toWire(value), model.USD.Places)), amount)
+	}
+	for _, price := range []string{"195.5", "0.0001", "31.4159", "123456789.1234"} {
+		value := decimal.RequireFromString(price)
+		require.True(t, value.Equal(fromWire(toWire(value), model.PricePlaces)), price)
+	}
+	require.Equal(t, "0.3", fromWire(0.1+0.2, model.USD.Places).String())
+}
+
+func TestBalanceRoundTripIsLossless(t *testing.T) {
+	rep := NewBalanceRepository(&walletClient{})
+	expected := decimal.Zero
+	for _, amount := range realisticAmounts {
+		value := decimal.RequireFromString(amount)
+		for _, operation := range []decimal.Decimal{value, value.Neg(), value} {
+			result, err := rep.BalanceOperation(context.Background(), &model.Balance{
+				BalanceID: uuid.New(), ProfileID: uuid.New(), Operation: operation,
+			})
+			require.NoError(t, err)
+			require.True(t, operation.Equal(result), amount)
+			expected = expected.Add(operation)
+		}
+	}
+	money, err := rep.GetBalance(context.Background(), uuid.New())
+	require.NoError(t, err)
+	require.Equal(t, expected.StringFixed(model.USD.Places), money.StringFixed(model.USD.Places))
+}
+
+func TestDealRoundTripIsLossless(t *testing.T) {
+	deal := &model.Deal{
+		DealID:        uuid.New(),
+		SharesCount:   decimal.RequireFromString("12.12345678"),
+		Company:       "Apple",
+		PurchasePrice: decimal.RequireFromString("195.1234"),
+		StopLoss:      decimal.RequireFromString("180.05"),
+		TakeProfit:    decimal.RequireFromString("250.9999"),
+		DealTime:      time.Date(2023, time.October, 1, 12, 0, 0, 0, time.UTC),
+		Profit:        decimal.RequireFromString("-1234.57"),
+	}
+	converted, err := dealFromProto(dealToProto(deal))
+	require.NoError(t, err)
+	require.Equal(t, deal.DealID, converted.DealID)
+	require.True(t, deal.SharesCount.Equal(converted.SharesCount))
+	require.True(t, deal.PurchasePrice.Equal(converted.PurchasePrice))
+	require.True(t, deal.StopLoss.Equal(converted.StopLoss))
+	require.True(t, deal.TakeProfit.Equal(converted.TakeProfit))
+	require.True(t, deal.Profit.Equal(converted.Profit))
+	require.Equal(t, deal.DealTime, converted.DealTime)
+}
@@ -0,0 +1,314 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/garyburd/redigo/redis"
+	"github.com/google/uuid"
+	"go.etcd.io/bbolt"
+)
+
+var ordersBucket = []byte("orders") // nolint gochecknoglobals
+
+// OrderRepository keeps entry orders in embedded database, so they survive restarts. Every profile has its own
+// nested bucket where orders are keyed by id. Database isn`t shared, so it is used only when APIService runs
+// in one instance
+type OrderRepository struct {
+	db *bbolt.DB
+}
+
+// NewOrderRepository creates and returns a new instance of OrderRepository, using the provided bbolt.DB.
+func NewOrderRepository(db *bbolt.DB) (*OrderRepository, error) {
+	err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(ordersBucket)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("update %w", err)
+	}
+	return &OrderRepository{db: db}, nil
+}
+
+// Create saves a new order in bucket of its profile.
+func (o *OrderRepository) Create(_ context.Context, order *model.Order) error {
+	err := o.db.Update(func(tx *bbolt.Tx) error {
+		profile, err := tx.Bucket(ordersBucket).CreateBucketIfNotExists([]byte(order.ProfileID.String()))
+		if err != nil {
+			return fmt.Errorf("createBucketIfNotExists %w", err)
+		}
+		return putOrder(profile, order)
+	})
+	if err != nil {
+		return fmt.Errorf("update %w", err)
+	}
+	return nil
+}
+
+// Update applies change to order in one transaction and returns changed order, so concurrent changes of order
+// can`t overwrite each other. Order isn`t saved if change returns error.
+func (o *OrderRepository) Update(_ context.Context, profileID, orderID uuid.UUID, change func(order *model.Order) error) (*model.Order, error) {
+	var order *model.Order
+	err := o.db.Update(func(tx *bbolt.Tx) error {
+		profile := tx.Bucket(ordersBucket).Bucket([]byte(profileID.String()))
+		if profile == nil {
+			return berrors.New(berrors.NotFound, "Order not found")
+		}
+		value := profile.Get([]byte(orderID.String()))
+		if value == nil {
+			return berrors.New(berrors.NotFound, "Order not found")
+		}
+		var err error
+		if order, err = decodeOrder(value, profileID); err != nil {
+			return err
+		}
+		if err = change(order); err != nil {
+			return err
+		}
+		return putOrder(profile, order)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("update %w", err)
+	}
+	return order, nil
+}
+
+// GetOrders returns all orders of profile.
+func (o *OrderRepository) GetOrders(_ context.Context, profileID uuid.UUID) ([]*model.Order, error) {
+	var orders []*model.Order
+	err := o.db.View(func(tx *bbolt.Tx) error {
+		profile := tx.Bucket(ordersBucket).Bucket([]byte(profileID.String()))
+		if profile == nil {
+			return nil
+		}
+		return profile.ForEach(func(_, value []byte) error {
+			order, err := decodeOrder(value, profileID)
+			if err != nil {
+				return err
+			}
+			orders = append(orders, order)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("view %w", err)
+	}
+	return orders, nil
+}
+
+// GetOrdersWithStatus returns orders of all profiles which have one of the given statuses.
+func (o *OrderRepository) GetOrdersWithStatus(_ context.Context, statuses ...string) ([]*model.Order, error) {
+	var orders []*model.Order
+	err := o.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(ordersBucket).ForEachBucket(func(key []byte) error {
+			profileID, err := uuid.ParseBytes(key)
+			if err != nil {
+				return fmt.Errorf("parseBytes %w", err)
+			}
+			return tx.Bucket(ordersBucket).Bucket(key).ForEach(func(_, value []byte) error {
+				order, err := decodeOrder(value, profileID)
+				if err != nil {
+					return err
+				}
+				for _, status := range statuses {
+					if order.Status == status {
+						orders = append(orders, order)
+						break
+					}
+				}
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("view %w", err)
+	}
+	return orders, nil
+}
+
+// putOrder saves order in bucket of profile by its id
+func putOrder(profile *bbolt.Bucket, order *model.Order) error {
+	data, err := json.Marshal(order)
+	if err != nil {
+		return fmt.Errorf("marshal %w", err)
+	}
+	return profile.Put([]byte(order.OrderID.String()), data)
+}
+
+// decodeOrder decodes order saved by putOrder
+func decodeOrder(value []byte, profileID uuid.UUID) (*model.Order, error) {
+	order := &model.Order{}
+	if err := json.Unmarshal(value, order); err != nil {
+		return nil, fmt.Errorf("unmarshal %w", err)
+	}
+	order.ProfileID = profileID
+	return order, nil
+}
+
+const (
+	orderPrefix         = "order:"
+	profileOrdersPrefix = "orders:"
+	orderStatusPrefix   = "orders_status:"
+)
+
+// RedisOrderRepository keeps entry orders in Redis, so orders are shared by all instances of APIService.
+// Order is kept by id of profile and id of order, it is indexed by profile and by status
+type RedisOrderRepository struct {
+	pool *redis.Pool
+}
+
+// NewRedisOrderRepository creates and returns a new instance of RedisOrderRepository, using the provided redis.Pool.
+func NewRedisOrderRepository(pool *redis.Pool) *RedisOrderRepository {
+	return &RedisOrderRepository{pool: pool}
+}
+
+// Create saves a new order with its indexes.
+func (r *RedisOrderRepository) Create(ctx context.Context, order *model.Order) error {
+	data, err := json.Marshal(order)
+	if err != nil {
+		return fmt.Errorf("marshal %w", err)
+	}
+	conn, err := r.pool.GetContext(ctx)
+	if err != nil {
+		return fmt.Errorf("getContext %w", err)
+	}
+	defer closeConn(conn)
+	member := orderMember(order.ProfileID, order.OrderID)
+	if err = conn.Send("MULTI"); err != nil {
+		return fmt.Errorf("send %w", err)
+	}
+	if err = conn.Send("SET", orderPrefix+member, data); err != nil {
+		return fmt.Errorf("send %w", err)
+	}
+	if err = conn.Send("SADD", profileOrdersPrefix+order.ProfileID.String(), order.OrderID.String()); err != nil {
+		return fmt.Errorf("send %w", err)
+	}
+	if err = conn.Send("SADD", orderStatusPrefix+order.Status, member); err != nil {
+		return fmt.Errorf("send %w", err)
+	}
+	if _, err = conn.Do("EXEC"); err != nil {
+		return fmt.Errorf("exec %w", err)
+	}
+	return nil
+}
+
+// Update applies change to order in one transaction and returns changed order, so concurrent changes of order
+// can`t overwrite each other. Order isn`t saved if change returns error, change is called again
+// if order was changed by someone else meanwhile.
+func (r *RedisOrderRepository) Update(ctx context.Context, profileID, orderID uuid.UUID,
+	change func(order *model.Order) error) (*model.Order, error) {
+	member := orderMember(profileID, orderID)
+	var order *model.Order
+	err := watchUpdate(ctx, r.pool, orderPrefix+member, func(value []byte) ([]redisCommand, error) {
+		if value == nil {
+			return nil, berrors.New(berrors.NotFound, "Order not found")
+		}
+		var errUpdate error
+		if order, errUpdate = decodeOrder(value, profileID); errUpdate != nil {
+			return nil, errUpdate
+		}
+		status := order.Status
+		if errUpdate = change(order); errUpdate != nil {
+			return nil, errUpdate
+		}
+		data, errUpdate := json.Marshal(order)
+		if errUpdate != nil {
+			return nil, fmt.Errorf("marshal %w", errUpdate)
+		}
+		commands := []redisCommand{{name: "SET", args: []interface{}{orderPrefix + member, data}}}
+		if order.Status != status {
+			commands = append(commands,
+				redisCommand{name: "SREM", args: []interface{}{orderStatusPrefix + status, member}},
+				redisCommand{name: "SADD", args: []interface{}{orderStatusPrefix + order.Status, member}})
+		}
+		return commands, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("watchUpdate %w", err)
+	}
+	return order, nil
+}
+
+// GetOrders returns all orders of profile.
+func (r *RedisOrderRepository) GetOrders(ctx context.Context, profileID uuid.UUID) ([]*model.Order, error) {
+	conn, err := r.pool.GetContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getContext %w", err)
+	}
+	defer closeConn(conn)
+	ids, err := redis.Strings(conn.Do("SMEMBERS", profileOrdersPrefix+profileID.String()))
+	if err != nil {
+		return nil, fmt.Errorf("smembers %w", err)
+	}
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, orderPrefix+profileID.String()+":"+id)
+	}
+	values, err := getValues(conn, keys)
+	if err != nil {
+		return nil, fmt.Errorf("getValues %w", err)
+	}
+	orders := make([]*model.Order, 0, len(values))
+	for _, value := range values {
+		if value == nil {
+			continue
+		}
+		order, errDecode := decodeOrder(value, profileID)
+		if errDecode != nil {
+			return nil, fmt.Errorf("decodeOrder %w", errDecode)
+		}
+		orders = append(orders, order)
+	}
+	return orders, nil
+}
+
+// GetOrdersWithStatus returns orders of all profiles which have one of the given statuses.
+func (r *RedisOrderRepository) GetOrdersWithStatus(ctx context.Context, statuses ...string) ([]*model.Order, error) {
+	conn, err := r.pool.GetContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getContext %w", err)
+	}
+	defer closeConn(conn)
+	var orders []*model.Order
+	for _, status := range statuses {
+		members, errMembers := redis.Strings(conn.Do("SMEMBERS", orderStatusPrefix+status))
+		if errMembers != nil {
+			return nil, fmt.Errorf("smembers %w", errMembers)
+		}
+		keys := make([]string, 0, len(members))
+		for _, member := range members {
+			keys = append(keys, orderPrefix+member)
+		}
+		values, errValues := getValues(conn, keys)
+		if errValues != nil {
+			return nil, fmt.Errorf("getValues %w", errValues)
+		}
+		for i, value := range values {
+			if value == nil {
+				continue
+			}
+			profileID, errParse := uuid.Parse(strings.SplitN(members[i], ":", 2)[0])
+			if errParse != nil {
+				return nil, fmt.Errorf("parse %w", errParse)
+			}
+			order, errDecode := decodeOrder(value, profileID)
+			if errDecode != nil {
+				return nil, fmt.Errorf("decodeOrder %w", errDecode)
+			}
+			// order could change its status between reading of index and reading of order
+			if order.Status == status {
+				orders = append(orders, order)
+			}
+		}
+	}
+	return orders, nil
+}
+
+// orderMember returns member of indexes and suffix of key of order
+func orderMember(profileID, orderID uuid.UUID) string {
+	return profileID.String() + ":" + orderID.String()
+}
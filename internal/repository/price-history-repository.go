@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+	"go.etcd.io/bbolt"
+)
+
+var (
+	priceSamplesBucket = []byte("price_samples") // nolint gochecknoglobals
+	priceBarsBucket    = []byte("price_bars")    // nolint gochecknoglobals
+)
+
+// PriceHistoryRepository keeps samples of prices and bars downsampled from old samples in embedded database.
+// Every company has its own nested bucket where keys are times, so ranges of time are read by cursor
+type PriceHistoryRepository struct {
+	db *bbolt.DB
+}
+
+// NewPriceHistoryRepository creates and returns a new instance of PriceHistoryRepository, using the provided bbolt.DB.
+func NewPriceHistoryRepository(db *bbolt.DB) (*PriceHistoryRepository, error) {
+	err := db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range [][]byte{priceSamplesBucket, priceBarsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return fmt.Errorf("createBucketIfNotExists %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("update %w", err)
+	}
+	return &PriceHistoryRepository{db: db}, nil
+}
+
+// AddSamples saves prices of all shares received at the given time. Shares whose company can`t be a name of bucket
+// are skipped, so one broken share doesn`t lose samples of the others.
+func (p *PriceHistoryRepository) AddSamples(_ context.Context, at time.Time, shares []model.Share) error {
+	err := p.db.Update(func(tx *bbolt.Tx) error {
+		samples := tx.Bucket(priceSamplesBucket)
+		for _, share := range shares {
+			if share.Company == "" || len(share.Company) > bbolt.MaxKeySize {
+				logrus.Warnf("addSamples: skipped share with invalid company %.64q", share.Company)
+				continue
+			}
+			company, err := samples.CreateBucketIfNotExists([]byte(share.Company))
+			if err != nil {
+				return fmt.Errorf("createBucketIfNotExists %w", err)
+			}
+			if err = company.Put(timeKey(at), encodeDecimals(share.Price)); err != nil {
+				return fmt.Errorf("put %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("update %w", err)
+	}
+	return nil
+}
+
+// GetCandles returns bars and samples of company in [from, to) ordered by time, every sample is a bar
+// with equal prices. Bar of the given interval which starts before from but overlaps the range is returned
+// with time from, so its prices aren`t lost and all candles stay in the range.
+func (p *PriceHistoryRepository) GetCandles(_ context.Context, company string, from, to time.Time,
+	barInterval time.Duration) ([]model.Candle, error) {
+	var candles []model.Candle
+	err := p.db.View(func(tx *bbolt.Tx) error {
+		sources := []struct {
+			name []byte
+			from time.Time
+		}{
+			{priceBarsBucket, from.Truncate(barInterval)},
+			{priceSamplesBucket, from},
+		}
+		for _, source := range sources {
+			bucket := tx.Bucket(source.name).Bucket([]byte(company))
+			if bucket == nil {
+				continue
+			}
+			cursor := bucket.Cursor()
+			for key, value := cursor.Seek(timeKey(source.from)); key != nil && bytes.Compare(key, timeKey(to)) < 0; key, value = cursor.Next() {
+				candle, err := decodeCandle(key, value)
+				if err != nil {
+					return fmt.Errorf("decodeCandle %w", err)
+				}
+				if candle.Time.Before(from) {
+					candle.Time = from
+				}
+				candles = append(candles, candle)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("view %w", err)
+	}
+	return candles, nil
+}
+
+// Downsample merges samples older than before into bars of the given interval and deletes bars older than expire.
+func (p *PriceHistoryRepository) Downsample(_ context.Context, before time.Time, interval time.Duration, expire time.Time) error {
+	err := p.db.Update(func(tx *bbolt.Tx) error {
+		samples := tx.Bucket(priceSamplesBucket)
+		bars := tx.Bucket(priceBarsBucket)
+		err := samples.ForEach(func(company, _ []byte) error {
+			companySamples := samples.Bucket(company)
+			companyBars, err := bars.CreateBucketIfNotExists(company)
+			if err != nil {
+				return fmt.Errorf("createBucketIfNotExists %w", err)
+			}
+			if err = downsampleCompany(companySamples, companyBars, before, interval); err != nil {
+				return fmt.Errorf("downsampleCompany %w", err)
+			}
+			return deleteBefore(companyBars, expire)
+		})
+		if err != nil {
+			return fmt.Errorf("forEach %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("update %w", err)
+	}
+	return nil
+}
+
+// downsampleCompany merges samples older than before into bars and deletes these samples
+func downsampleCompany(samples, bars *bbolt.Bucket, before time.Time, interval time.Duration) error {
+	var merged []model.Candle
+	cursor := samples.Cursor()
+	for key, value := cursor.First(); key != nil && bytes.Compare(key, timeKey(before)) < 0; key, value = cursor.Next() {
+		sample, err := decodeCandle(key, value)
+		if err != nil {
+			return fmt.Errorf("decodeCandle %w", err)
+		}
+		start := sample.Time.Truncate(interval)
+		if len(merged) > 0 && merged[len(merged)-1].Time.Equal(start) {
+			mergeCandle(&merged[len(merged)-1], &sample)
+			continue
+		}
+		if existing := bars.Get(timeKey(start)); existing != nil {
+			bar, errBar := decodeCandle(timeKey(start), existing)
+			if errBar != nil {
+				return fmt.Errorf("decodeCandle %w", errBar)
+			}
+			mergeCandle(&bar, &sample)
+			merged = append(merged, bar)
+			continue
+		}
+		sample.Time = start
+		merged = append(merged, sample)
+	}
+	for i := range merged {
+		bar := &merged[i]
+		if err := bars.Put(timeKey(bar.Time), encodeDecimals(bar.Open, bar.High, bar.Low, bar.Close)); err != nil {
+			return fmt.Errorf("put %w", err)
+		}
+	}
+	return deleteBefore(samples, before)
+}
+
+// deleteBefore deletes keys of bucket which are older than before
+func deleteBefore(bucket *bbolt.Bucket, before time.Time) error {
+	cursor := bucket.Cursor()
+	for key, _ := cursor.First(); key != nil && bytes.Compare(key, timeKey(before)) < 0; key, _ = cursor.First() {
+		if err := cursor.Delete(); err != nil {
+			return fmt.Errorf("delete %w", err)
+		}
+	}
+	return nil
+}
+
+// mergeCandle adds later candle to the bar
+func mergeCandle(bar, later *model.Candle) {
+	bar.High = decimal.Max(bar.High, later.High)
+	bar.Low = decimal.Min(bar.Low, later.Low)
+	bar.Close = later.Close
+}
+
+// decodeCandle decodes bar or sample, sample has only one price
+func decodeCandle(key, value []byte) (model.Candle, error) {
+	prices, err := decodeDecimals(value)
+	if err != nil {
+		return model.Candle{}, fmt.Errorf("decodeDecimals %w", err)
+	}
+	switch len(prices) {
+	case 1:
+		return model.Candle{Time: keyTime(key), Open: prices[0], High: prices[0], Low: prices[0], Close: prices[0]}, nil
+	case 4:
+		return model.Candle{Time: keyTime(key), Open: prices[0], High: prices[1], Low: prices[2], Close: prices[3]}, nil
+	default:
+		return model.Candle{}, fmt.Errorf("decodeCandle: %d prices instead of 1 or 4", len(prices))
+	}
+}
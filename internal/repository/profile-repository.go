@@ -9,7 +9,6 @@ import (
 	"github.com/artnikel/APIService/internal/model"
 	uproto "github.com/artnikel/ProfileService/proto"
 	"github.com/google/uuid"
-	"google.golang.org/grpc/status"
 )
 
 // ProfileRepository represents the client of UserService repository implementation.
@@ -31,11 +30,7 @@ func (p *ProfileRepository) SignUp(ctx context.Context, user *model.User) error
 		Password: user.Password,
 	}})
 	if err != nil {
-		grpcStatus, ok := status.FromError(err)
-		if ok && grpcStatus.Message() == berrors.LoginAlreadyExist {
-			return berrors.New(berrors.LoginAlreadyExist, "Login is occupied by another user")
-		}
-		return fmt.Errorf("signUp %w", err)
+		return fmt.Errorf("signUp %w", berrors.FromGRPC(err))
 	}
 	return nil
 }
@@ -44,7 +39,7 @@ func (p *ProfileRepository) SignUp(ctx context.Context, user *model.User) error
 func (p *ProfileRepository) GetByLogin(ctx context.Context, login string) ([]byte, uuid.UUID, error) {
 	resp, err := p.client.GetByLogin(ctx, &uproto.GetByLoginRequest{Login: login})
 	if err != nil {
-		return nil, uuid.Nil, fmt.Errorf("getByLogin %w", err)
+		return nil, uuid.Nil, fmt.Errorf("getByLogin %w", berrors.FromGRPC(err))
 	}
 	idUUID, err := uuid.Parse(resp.Id)
 	if err != nil {
@@ -57,11 +52,7 @@ func (p *ProfileRepository) GetByLogin(ctx context.Context, login string) ([]byt
 func (p *ProfileRepository) DeleteAccount(ctx context.Context, id uuid.UUID) (string, error) {
 	resp, err := p.client.DeleteAccount(ctx, &uproto.DeleteAccountRequest{Id: id.String()})
 	if err != nil {
-		grpcStatus, ok := status.FromError(err)
-		if ok && grpcStatus.Message() == berrors.UserDoesntExists {
-			return "", berrors.New(berrors.LoginAlreadyExist, "User doesnt exist")
-		}
-		return "", fmt.Errorf("deleteAccount %w", err)
+		return "", fmt.Errorf("deleteAccount %w", berrors.FromGRPC(err))
 	}
 	return resp.Id, nil
 }
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/artnikel/APIService/internal/config"
+	"github.com/garyburd/redigo/redis"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	redisMaxIdle     = 10
+	redisIdleTimeout = 240 * time.Second
+	// maxWatchRetries is a count of attempts of transaction which is aborted by concurrent changes of its key
+	maxWatchRetries = 10
+)
+
+// redisCommand is a command which is queued in transaction
+type redisCommand struct {
+	name string
+	args []interface{}
+}
+
+// NewRedisPool creates pool of connections to Redis which is shared by all Redis repositories
+func NewRedisPool(cfg *config.Variables) *redis.Pool {
+	return &redis.Pool{
+		MaxIdle:     redisMaxIdle,
+		IdleTimeout: redisIdleTimeout,
+		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			_, err := c.Do("PING")
+			return err
+		},
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", cfg.RedisPriceAddress)
+		},
+	}
+}
+
+// closeConn returns connection to the pool
+func closeConn(conn redis.Conn) {
+	if err := conn.Close(); err != nil {
+		logrus.Errorf("closeConn: %v", err)
+	}
+}
+
+// watchUpdate reads value of key and runs commands which update returns for it in one transaction.
+// Transaction is aborted if key is changed by someone else after it was read, then update is retried
+// with the new value, so concurrent changes can`t overwrite each other. Value is nil if there is no such key
+func watchUpdate(ctx context.Context, pool *redis.Pool, key string, update func(value []byte) ([]redisCommand, error)) error {
+	conn, err := pool.GetContext(ctx)
+	if err != nil {
+		return fmt.Errorf("getContext %w", err)
+	}
+	// watched key and not executed transaction are discarded when connection returns to the pool
+	defer closeConn(conn)
+	for i := 0; i < maxWatchRetries; i++ {
+		if _, err = conn.Do("WATCH", key); err != nil {
+			return fmt.Errorf("watch %w", err)
+		}
+		value, errGet := redis.Bytes(conn.Do("GET", key))
+		if errGet != nil && errGet != redis.ErrNil {
+			return fmt.Errorf("get %w", errGet)
+		}
+		commands, errUpdate := update(value)
+		if errUpdate != nil {
+			return errUpdate
+		}
+		if err = conn.Send("MULTI"); err != nil {
+			return fmt.Errorf("send %w", err)
+		}
+		for _, command := range commands {
+			if err = conn.Send(command.name, command.args...); err != nil {
+				return fmt.Errorf("send %w", err)
+			}
+		}
+		reply, errExec := conn.Do("EXEC")
+		if errExec != nil {
+			return fmt.Errorf("exec %w", errExec)
+		}
+		if reply != nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("watchUpdate: %s is changed too often", key)
+}
+
+// getValues returns values of keys, value is nil if there is no such key
+func getValues(conn redis.Conn, keys []string) ([][]byte, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	args := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		args = append(args, key)
+	}
+	values, err := redis.ByteSlices(conn.Do("MGET", args...))
+	if err != nil {
+		return nil, fmt.Errorf("mget %w", err)
+	}
+	return values, nil
+}
@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/garyburd/redigo/redis"
+	"github.com/google/uuid"
+)
+
+const (
+	sessionPrefix        = "session:"
+	profileSessionPrefix = "profile_sessions:"
+)
+
+// SessionRepository keeps sessions of browsers in Redis together with index of sessions of every profile.
+type SessionRepository struct {
+	pool *redis.Pool
+}
+
+// NewSessionRepository creates and returns a new instance of SessionRepository, using the provided redis.Pool.
+func NewSessionRepository(pool *redis.Pool) *SessionRepository {
+	return &SessionRepository{pool: pool}
+}
+
+// Get returns session by id or nil if session doesn`t exist or expired.
+func (s *SessionRepository) Get(ctx context.Context, id string) (*model.Session, error) {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getContext %w", err)
+	}
+	defer closeConn(conn)
+	data, err := redis.Bytes(conn.Do("GET", sessionPrefix+id))
+	if errors.Is(err, redis.ErrNil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get %w", err)
+	}
+	session := &model.Session{ID: id}
+	if err = json.Unmarshal(data, session); err != nil {
+		return nil, fmt.Errorf("unmarshal %w", err)
+	}
+	return session, nil
+}
+
+// Save writes session for the given time and adds it to the index of its profile.
+// Index lives as long as the longest session of profile.
+func (s *SessionRepository) Save(ctx context.Context, session *model.Session, ttl time.Duration) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("marshal %w", err)
+	}
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return fmt.Errorf("getContext %w", err)
+	}
+	defer closeConn(conn)
+	if _, err = conn.Do("SET", sessionPrefix+session.ID, data, "PX", ttl.Milliseconds()); err != nil {
+		return fmt.Errorf("set %w", err)
+	}
+	indexKey := profileSessionPrefix + session.ProfileID.String()
+	if _, err = conn.Do("SADD", indexKey, session.ID); err != nil {
+		return fmt.Errorf("sadd %w", err)
+	}
+	indexTTL, err := redis.Int64(conn.Do("PTTL", indexKey))
+	if err != nil {
+		return fmt.Errorf("pttl %w", err)
+	}
+	if indexTTL < ttl.Milliseconds() {
+		if _, err = conn.Do("PEXPIRE", indexKey, ttl.Milliseconds()); err != nil {
+			return fmt.Errorf("pexpire %w", err)
+		}
+	}
+	return nil
+}
+
+// Delete removes session by id and removes it from the index of its profile.
+func (s *SessionRepository) Delete(ctx context.Context, id string) error {
+	session, err := s.Get(ctx, id)
+	if err != nil {
+		return fmt.Errorf("get %w", err)
+	}
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return fmt.Errorf("getContext %w", err)
+	}
+	defer closeConn(conn)
+	if _, err = conn.Do("DEL", sessionPrefix+id); err != nil {
+		return fmt.Errorf("del %w", err)
+	}
+	if session != nil {
+		if _, err = conn.Do("SREM", profileSessionPrefix+session.ProfileID.String(), id); err != nil {
+			return fmt.Errorf("srem %w", err)
+		}
+	}
+	return nil
+}
+
+// List returns active sessions of profile, expired sessions are removed from the index.
+func (s *SessionRepository) List(ctx context.Context, profileID uuid.UUID) ([]*model.Session, error) {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getContext %w", err)
+	}
+	defer closeConn(conn)
+	indexKey := profileSessionPrefix + profileID.String()
+	ids, err := redis.Strings(conn.Do("SMEMBERS", indexKey))
+	if err != nil {
+		return nil, fmt.Errorf("smembers %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	keys := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, sessionPrefix+id)
+	}
+	values, err := redis.ByteSlices(conn.Do("MGET", keys...))
+	if err != nil {
+		return nil, fmt.Errorf("mget %w", err)
+	}
+	sessions := make([]*model.Session, 0, len(ids))
+	for i, data := range values {
+		if data == nil {
+			if _, err = conn.Do("SREM", indexKey, ids[i]); err != nil {
+				return nil, fmt.Errorf("srem %w", err)
+			}
+			continue
+		}
+		session := &model.Session{ID: ids[i]}
+		if err = json.Unmarshal(data, session); err != nil {
+			return nil, fmt.Errorf("unmarshal %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+// MemorySessionRepository keeps sessions of browsers in memory of a single instance.
+type MemorySessionRepository struct {
+	storage *memoryStorage
+	mu      sync.Mutex
+	index   map[uuid.UUID]map[string]struct{}
+}
+
+// NewMemorySessionRepository creates and returns a new instance of MemorySessionRepository.
+func NewMemorySessionRepository() *MemorySessionRepository {
+	return &MemorySessionRepository{
+		storage: newMemoryStorage(),
+		index:   make(map[uuid.UUID]map[string]struct{}),
+	}
+}
+
+// Get returns session by id or nil if session doesn`t exist or expired.
+func (m *MemorySessionRepository) Get(_ context.Context, id string) (*model.Session, error) {
+	data, ok := m.storage.get(sessionPrefix + id)
+	if !ok {
+		return nil, nil
+	}
+	session := &model.Session{ID: id}
+	if err := json.Unmarshal([]byte(data), session); err != nil {
+		return nil, fmt.Errorf("unmarshal %w", err)
+	}
+	return session, nil
+}
+
+// Save writes session for the given time and adds it to the index of its profile.
+func (m *MemorySessionRepository) Save(_ context.Context, session *model.Session, ttl time.Duration) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("marshal %w", err)
+	}
+	m.storage.set(sessionPrefix+session.ID, string(data), ttl)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ids, ok := m.index[session.ProfileID]
+	if !ok {
+		ids = make(map[string]struct{})
+		m.index[session.ProfileID] = ids
+	}
+	ids[session.ID] = struct{}{}
+	return nil
+}
+
+// Delete removes session by id and removes it from the index of its profile.
+func (m *MemorySessionRepository) Delete(ctx context.Context, id string) error {
+	session, err := m.Get(ctx, id)
+	if err != nil {
+		return fmt.Errorf("get %w", err)
+	}
+	m.storage.pop(sessionPrefix + id)
+	if session != nil {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.index[session.ProfileID], id)
+	}
+	return nil
+}
+
+// List returns active sessions of profile, expired sessions are removed from the index.
+func (m *MemorySessionRepository) List(ctx context.Context, profileID uuid.UUID) ([]*model.Session, error) {
+	m.mu.Lock()
+	ids := make([]string, 0, len(m.index[profileID]))
+	for id := range m.index[profileID] {
+		ids = append(ids, id)
+	}
+	m.mu.Unlock()
+	sessions := make([]*model.Session, 0, len(ids))
+	for _, id := range ids {
+		session, err := m.Get(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("get %w", err)
+		}
+		if session == nil {
+			m.mu.Lock()
+			delete(m.index[profileID], id)
+			m.mu.Unlock()
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/google/uuid"
+)
+
+const (
+	refreshTokenPrefix = "refresh_token:"
+	revokedTokenPrefix = "revoked_token:"
+)
+
+// TokenRepository keeps refresh tokens and revoked access tokens in Redis.
+type TokenRepository struct {
+	pool *redis.Pool
+}
+
+// NewTokenRepository creates and returns a new instance of TokenRepository, using the provided redis.Pool.
+func NewTokenRepository(pool *redis.Pool) *TokenRepository {
+	return &TokenRepository{pool: pool}
+}
+
+// AddRefreshToken saves id of refresh token of profile until the token expires.
+func (t *TokenRepository) AddRefreshToken(ctx context.Context, tokenID string, profileID uuid.UUID, ttl time.Duration) error {
+	conn, err := t.pool.GetContext(ctx)
+	if err != nil {
+		return fmt.Errorf("getContext %w", err)
+	}
+	defer closeConn(conn)
+	_, err = conn.Do("SET", refreshTokenPrefix+tokenID, profileID.String(), "PX", ttl.Milliseconds())
+	if err != nil {
+		return fmt.Errorf("set %w", err)
+	}
+	return nil
+}
+
+// GetRefreshToken returns id of profile by id of refresh token or uuid.Nil if token doesn`t exist.
+func (t *TokenRepository) GetRefreshToken(ctx context.Context, tokenID string) (uuid.UUID, error) {
+	conn, err := t.pool.GetContext(ctx)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("getContext %w", err)
+	}
+	defer closeConn(conn)
+	profileID, err := redis.String(conn.Do("GET", refreshTokenPrefix+tokenID))
+	if errors.Is(err, redis.ErrNil) {
+		return uuid.Nil, nil
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("get %w", err)
+	}
+	profileUUID, err := uuid.Parse(profileID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("parse %w", err)
+	}
+	return profileUUID, nil
+}
+
+// DeleteRefreshToken removes refresh token, so it can`t be used anymore.
+func (t *TokenRepository) DeleteRefreshToken(ctx context.Context, tokenID string) (bool, error) {
+	conn, err := t.pool.GetContext(ctx)
+	if err != nil {
+		return false, fmt.Errorf("getContext %w", err)
+	}
+	defer closeConn(conn)
+	deleted, err := redis.Int(conn.Do("DEL", refreshTokenPrefix+tokenID))
+	if err != nil {
+		return false, fmt.Errorf("del %w", err)
+	}
+	return deleted > 0, nil
+}
+
+// RevokeAccessToken adds id of access token to the revocation list until the token expires.
+func (t *TokenRepository) RevokeAccessToken(ctx context.Context, tokenID string, ttl time.Duration) error {
+	conn, err := t.pool.GetContext(ctx)
+	if err != nil {
+		return fmt.Errorf("getContext %w", err)
+	}
+	defer closeConn(conn)
+	_, err = conn.Do("SET", revokedTokenPrefix+tokenID, 1, "PX", ttl.Milliseconds())
+	if err != nil {
+		return fmt.Errorf("set %w", err)
+	}
+	return nil
+}
+
+// IsAccessTokenRevoked checks if access token is in the revocation list.
+func (t *TokenRepository) IsAccessTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	conn, err := t.pool.GetContext(ctx)
+	if err != nil {
+		return false, fmt.Errorf("getContext %w", err)
+	}
+	defer closeConn(conn)
+	revoked, err := redis.Bool(conn.Do("EXISTS", revokedTokenPrefix+tokenID))
+	if err != nil {
+		return false, fmt.Errorf("exists %w", err)
+	}
+	return revoked, nil
+}
+
+// MemoryTokenRepository keeps refresh tokens and revoked access tokens in memory of a single instance.
+type MemoryTokenRepository struct {
+	storage *memoryStorage
+}
+
+// NewMemoryTokenRepository creates and returns a new instance of MemoryTokenRepository.
+func NewMemoryTokenRepository() *MemoryTokenRepository {
+	return &MemoryTokenRepository{storage: newMemoryStorage()}
+}
+
+// AddRefreshToken saves id of refresh token of profile until the token expires.
+func (m *MemoryTokenRepository) AddRefreshToken(_ context.Context, tokenID string, profileID uuid.UUID, ttl time.Duration) error {
+	m.storage.set(refreshTokenPrefix+tokenID, profileID.String(), ttl)
+	return nil
+}
+
+// GetRefreshToken returns id of profile by id of refresh token or uuid.Nil if token doesn`t exist.
+func (m *MemoryTokenRepository) GetRefreshToken(_ context.Context, tokenID string) (uuid.UUID, error) {
+	profileID, ok := m.storage.get(refreshTokenPrefix + tokenID)
+	if !ok {
+		return uuid.Nil, nil
+	}
+	profileUUID, err := uuid.Parse(profileID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("parse %w", err)
+	}
+	return profileUUID, nil
+}
+
+// DeleteRefreshToken removes refresh token, so it can`t be used anymore.
+func (m *MemoryTokenRepository) DeleteRefreshToken(_ context.Context, tokenID string) (bool, error) {
+	_, ok := m.storage.pop(refreshTokenPrefix + tokenID)
+	return ok, nil
+}
+
+// RevokeAccessToken adds id of access token to the revocation list until the token expires.
+func (m *MemoryTokenRepository) RevokeAccessToken(_ context.Context, tokenID string, ttl time.Duration) error {
+	m.storage.set(revokedTokenPrefix+tokenID, "1", ttl)
+	return nil
+}
+
+// IsAccessTokenRevoked checks if access token is in the revocation list.
+func (m *MemoryTokenRepository) IsAccessTokenRevoked(_ context.Context, tokenID string) (bool, error) {
+	_, ok := m.storage.get(revokedTokenPrefix + tokenID)
+	return ok, nil
+}
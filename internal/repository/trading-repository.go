@@ -9,7 +9,6 @@ import (
 	tproto "github.com/artnikel/TradingService/proto"
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
-	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -27,43 +26,23 @@ func NewTradingRepository(client tproto.TradingServiceClient) *TradingRepository
 
 // CreatePosition call a method of TradingService.
 func (r *TradingRepository) CreatePosition(ctx context.Context, deal *model.Deal) error {
-	_, err := r.client.CreatePosition(ctx, &tproto.CreatePositionRequest{
-		Deal: &tproto.Deal{
-			DealID:        deal.DealID.String(),
-			SharesCount:   deal.SharesCount.InexactFloat64(),
-			ProfileID:     deal.ProfileID.String(),
-			Company:       deal.Company,
-			PurchasePrice: deal.PurchasePrice.InexactFloat64(),
-			StopLoss:      deal.StopLoss.InexactFloat64(),
-			TakeProfit:    deal.TakeProfit.InexactFloat64(),
-			DealTime:      timestamppb.New(deal.DealTime),
-			EndDealTime:   timestamppb.New(deal.EndDealTime),
-			Profit:        deal.Profit.InexactFloat64(),
-		},
-	})
+	_, err := r.client.CreatePosition(ctx, &tproto.CreatePositionRequest{Deal: dealToProto(deal)})
 	if err != nil {
-		grpcStatus, ok := status.FromError(err)
-		if ok && grpcStatus.Message() == berrors.NotEnoughMoney {
-			return berrors.New(berrors.NotEnoughMoney, "Not enough money")
-		}
-		if ok && grpcStatus.Message() == berrors.PurchasePriceOut {
-			return berrors.New(berrors.PurchasePriceOut, "Purchase price out of stoploss/takeprofit")
-		}
-		return fmt.Errorf("createPosition %w", err)
+		return fmt.Errorf("createPosition %w", berrors.FromGRPC(err))
 	}
 	return nil
 }
 
 // ClosePositionManually call a method of TradingService.
-func (r *TradingRepository) ClosePositionManually(ctx context.Context, dealid, profileid uuid.UUID) (float64, error) {
+func (r *TradingRepository) ClosePositionManually(ctx context.Context, dealid, profileid uuid.UUID) (decimal.Decimal, error) {
 	resp, err := r.client.ClosePositionManually(ctx, &tproto.ClosePositionManuallyRequest{
 		Dealid:    dealid.String(),
 		Profileid: profileid.String(),
 	})
 	if err != nil {
-		return 0, fmt.Errorf("closePositionManually %w", err)
+		return decimal.Zero, fmt.Errorf("closePositionManually %w", berrors.FromGRPC(err))
 	}
-	return resp.Profit, nil
+	return fromWire(resp.Profit, model.USD.Places), nil
 }
 
 // GetUnclosedPositions call a method of TradingService.
@@ -72,22 +51,13 @@ func (r *TradingRepository) GetUnclosedPositions(ctx context.Context, profileid
 		Profileid: profileid.String(),
 	})
 	if err != nil {
-		return nil, fmt.Errorf("getUncosedPositions %w", err)
+		return nil, fmt.Errorf("getUncosedPositions %w", berrors.FromGRPC(err))
 	}
 	unclosedDeals := make([]*model.Deal, len(resp.Deal))
 	for i, deal := range resp.Deal {
-		dealUUID, err := uuid.Parse(deal.DealID)
+		unclosedDeal, err := dealFromProto(deal)
 		if err != nil {
-			return nil, fmt.Errorf("parse %w", err)
-		}
-		unclosedDeal := &model.Deal{
-			DealID:        dealUUID,
-			SharesCount:   decimal.NewFromFloat(deal.SharesCount),
-			Company:       deal.Company,
-			PurchasePrice: decimal.NewFromFloat(deal.PurchasePrice),
-			StopLoss:      decimal.NewFromFloat(deal.StopLoss),
-			TakeProfit:    decimal.NewFromFloat(deal.TakeProfit),
-			DealTime:      deal.DealTime.AsTime(),
+			return nil, fmt.Errorf("dealFromProto %w", err)
 		}
 		unclosedDeals[i] = unclosedDeal
 	}
@@ -100,25 +70,15 @@ func (r *TradingRepository) GetClosedPositions(ctx context.Context, profileid uu
 		Profileid: profileid.String(),
 	})
 	if err != nil {
-		return nil, fmt.Errorf("getClosedPositions %w", err)
+		return nil, fmt.Errorf("getClosedPositions %w", berrors.FromGRPC(err))
 	}
 	closedDeals := make([]*model.Deal, len(resp.Deal))
 	for i, deal := range resp.Deal {
-		dealUUID, err := uuid.Parse(deal.DealID)
+		closedDeal, err := dealFromProto(deal)
 		if err != nil {
-			return nil, fmt.Errorf("parse %w", err)
-		}
-		closedDeal := &model.Deal{
-			DealID:        dealUUID,
-			SharesCount:   decimal.NewFromFloat(deal.SharesCount),
-			Company:       deal.Company,
-			PurchasePrice: decimal.NewFromFloat(deal.PurchasePrice),
-			StopLoss:      decimal.NewFromFloat(deal.StopLoss),
-			TakeProfit:    decimal.NewFromFloat(deal.TakeProfit),
-			DealTime:      deal.DealTime.AsTime(),
-			Profit:        decimal.NewFromFloat(deal.Profit),
-			EndDealTime:   deal.EndDealTime.AsTime(),
+			return nil, fmt.Errorf("dealFromProto %w", err)
 		}
+		closedDeal.EndDealTime = deal.EndDealTime.AsTime()
 		closedDeals[i] = closedDeal
 	}
 	return closedDeals, nil
@@ -128,15 +88,49 @@ func (r *TradingRepository) GetClosedPositions(ctx context.Context, profileid uu
 func (r *TradingRepository) GetPrices(ctx context.Context) ([]model.Share, error) {
 	resp, err := r.client.GetPrices(ctx, &tproto.GetPricesRequest{})
 	if err != nil {
-		return nil, fmt.Errorf("getPrices %w", err)
+		return nil, fmt.Errorf("getPrices %w", berrors.FromGRPC(err))
 	}
 	allShares := make([]model.Share, len(resp.Share))
 	for i, share := range resp.Share {
 		allShare := model.Share{
 			Company: share.Company,
-			Price:   share.Price,
+			Price:   fromWire(share.Price, model.PricePlaces),
 		}
 		allShares[i] = allShare
 	}
 	return allShares, nil
 }
+
+// dealToProto converts deal to the message of TradingService
+func dealToProto(deal *model.Deal) *tproto.Deal {
+	return &tproto.Deal{
+		DealID:        deal.DealID.String(),
+		SharesCount:   toWire(deal.SharesCount),
+		ProfileID:     deal.ProfileID.String(),
+		Company:       deal.Company,
+		PurchasePrice: toWire(deal.PurchasePrice),
+		StopLoss:      toWire(deal.StopLoss),
+		TakeProfit:    toWire(deal.TakeProfit),
+		DealTime:      timestamppb.New(deal.DealTime),
+		EndDealTime:   timestamppb.New(deal.EndDealTime),
+		Profit:        toWire(deal.Profit),
+	}
+}
+
+// dealFromProto converts message of TradingService to deal, time of closing is set only for closed deals
+func dealFromProto(deal *tproto.Deal) (*model.Deal, error) {
+	dealUUID, err := uuid.Parse(deal.DealID)
+	if err != nil {
+		return nil, fmt.Errorf("parse %w", err)
+	}
+	return &model.Deal{
+		DealID:        dealUUID,
+		SharesCount:   fromWire(deal.SharesCount, model.QuantityPlaces),
+		Company:       deal.Company,
+		PurchasePrice: fromWire(deal.PurchasePrice, model.PricePlaces),
+		StopLoss:      fromWire(deal.StopLoss, model.PricePlaces),
+		TakeProfit:    fromWire(deal.TakeProfit, model.PricePlaces),
+		DealTime:      deal.DealTime.AsTime(),
+		Profit:        fromWire(deal.Profit, model.USD.Places),
+	}, nil
+}
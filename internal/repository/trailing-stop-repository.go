@@ -0,0 +1,265 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/garyburd/redigo/redis"
+	"github.com/google/uuid"
+	"go.etcd.io/bbolt"
+)
+
+var trailingStopsBucket = []byte("trailing_stops") // nolint gochecknoglobals
+
+// TrailingStopRepository keeps trailing stops of positions in embedded database, so monitoring of prices continues
+// after restart. Every profile has its own nested bucket where stops are keyed by id of deal. Database isn`t shared,
+// so it is used only when APIService runs in one instance
+type TrailingStopRepository struct {
+	db *bbolt.DB
+}
+
+// NewTrailingStopRepository creates and returns a new instance of TrailingStopRepository, using the provided bbolt.DB.
+func NewTrailingStopRepository(db *bbolt.DB) (*TrailingStopRepository, error) {
+	err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(trailingStopsBucket)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("update %w", err)
+	}
+	return &TrailingStopRepository{db: db}, nil
+}
+
+// Save applies change to stop of deal in one transaction and returns changed stop. If deal has no stop, change gets
+// an empty stop with ids of profile and deal. Stop isn`t saved if change returns error.
+func (t *TrailingStopRepository) Save(_ context.Context, profileID, dealID uuid.UUID,
+	change func(stop *model.TrailingStop) error) (*model.TrailingStop, error) {
+	stop := &model.TrailingStop{}
+	err := t.db.Update(func(tx *bbolt.Tx) error {
+		profile, err := tx.Bucket(trailingStopsBucket).CreateBucketIfNotExists([]byte(profileID.String()))
+		if err != nil {
+			return fmt.Errorf("createBucketIfNotExists %w", err)
+		}
+		key := []byte(dealID.String())
+		if value := profile.Get(key); value != nil {
+			if err = json.Unmarshal(value, stop); err != nil {
+				return fmt.Errorf("unmarshal %w", err)
+			}
+		}
+		stop.ProfileID = profileID
+		stop.DealID = dealID
+		if err = change(stop); err != nil {
+			return err
+		}
+		data, err := json.Marshal(stop)
+		if err != nil {
+			return fmt.Errorf("marshal %w", err)
+		}
+		return profile.Put(key, data)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("update %w", err)
+	}
+	return stop, nil
+}
+
+// GetTrailingStops returns all stops of profile.
+func (t *TrailingStopRepository) GetTrailingStops(_ context.Context, profileID uuid.UUID) ([]*model.TrailingStop, error) {
+	var stops []*model.TrailingStop
+	err := t.db.View(func(tx *bbolt.Tx) error {
+		profile := tx.Bucket(trailingStopsBucket).Bucket([]byte(profileID.String()))
+		if profile == nil {
+			return nil
+		}
+		return profile.ForEach(func(_, value []byte) error {
+			stop, err := decodeTrailingStop(value, profileID)
+			if err != nil {
+				return err
+			}
+			stops = append(stops, stop)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("view %w", err)
+	}
+	return stops, nil
+}
+
+// GetTrailingStopsWithStatus returns stops of all profiles which have one of the given statuses.
+func (t *TrailingStopRepository) GetTrailingStopsWithStatus(_ context.Context, statuses ...string) ([]*model.TrailingStop, error) {
+	var stops []*model.TrailingStop
+	err := t.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(trailingStopsBucket).ForEachBucket(func(key []byte) error {
+			profileID, err := uuid.ParseBytes(key)
+			if err != nil {
+				return fmt.Errorf("parseBytes %w", err)
+			}
+			return tx.Bucket(trailingStopsBucket).Bucket(key).ForEach(func(_, value []byte) error {
+				stop, err := decodeTrailingStop(value, profileID)
+				if err != nil {
+					return err
+				}
+				for _, status := range statuses {
+					if stop.Status == status {
+						stops = append(stops, stop)
+						break
+					}
+				}
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("view %w", err)
+	}
+	return stops, nil
+}
+
+// decodeTrailingStop decodes stop saved by Save
+func decodeTrailingStop(value []byte, profileID uuid.UUID) (*model.TrailingStop, error) {
+	stop := &model.TrailingStop{}
+	if err := json.Unmarshal(value, stop); err != nil {
+		return nil, fmt.Errorf("unmarshal %w", err)
+	}
+	stop.ProfileID = profileID
+	return stop, nil
+}
+
+const (
+	trailingStopPrefix         = "trailing_stop:"
+	profileTrailingStopsPrefix = "trailing_stops:"
+	trailingStopStatusPrefix   = "trailing_stops_status:"
+)
+
+// RedisTrailingStopRepository keeps trailing stops of positions in Redis, so stops are shared by all instances
+// of APIService. Stop is kept by id of profile and id of deal, it is indexed by profile and by status
+type RedisTrailingStopRepository struct {
+	pool *redis.Pool
+}
+
+// NewRedisTrailingStopRepository creates and returns a new instance of RedisTrailingStopRepository,
+// using the provided redis.Pool.
+func NewRedisTrailingStopRepository(pool *redis.Pool) *RedisTrailingStopRepository {
+	return &RedisTrailingStopRepository{pool: pool}
+}
+
+// Save applies change to stop of deal in one transaction and returns changed stop. If deal has no stop, change gets
+// an empty stop with ids of profile and deal. Stop isn`t saved if change returns error, change is called again
+// if stop was changed by someone else meanwhile.
+func (r *RedisTrailingStopRepository) Save(ctx context.Context, profileID, dealID uuid.UUID,
+	change func(stop *model.TrailingStop) error) (*model.TrailingStop, error) {
+	member := profileID.String() + ":" + dealID.String()
+	var stop *model.TrailingStop
+	err := watchUpdate(ctx, r.pool, trailingStopPrefix+member, func(value []byte) ([]redisCommand, error) {
+		stop = &model.TrailingStop{}
+		if value != nil {
+			if errUnmarshal := json.Unmarshal(value, stop); errUnmarshal != nil {
+				return nil, fmt.Errorf("unmarshal %w", errUnmarshal)
+			}
+		}
+		stop.ProfileID = profileID
+		stop.DealID = dealID
+		status := stop.Status
+		if errChange := change(stop); errChange != nil {
+			return nil, errChange
+		}
+		data, errMarshal := json.Marshal(stop)
+		if errMarshal != nil {
+			return nil, fmt.Errorf("marshal %w", errMarshal)
+		}
+		commands := []redisCommand{
+			{name: "SET", args: []interface{}{trailingStopPrefix + member, data}},
+			{name: "SADD", args: []interface{}{profileTrailingStopsPrefix + profileID.String(), dealID.String()}},
+		}
+		if stop.Status != status {
+			commands = append(commands,
+				redisCommand{name: "SREM", args: []interface{}{trailingStopStatusPrefix + status, member}},
+				redisCommand{name: "SADD", args: []interface{}{trailingStopStatusPrefix + stop.Status, member}})
+		}
+		return commands, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("watchUpdate %w", err)
+	}
+	return stop, nil
+}
+
+// GetTrailingStops returns all stops of profile.
+func (r *RedisTrailingStopRepository) GetTrailingStops(ctx context.Context, profileID uuid.UUID) ([]*model.TrailingStop, error) {
+	conn, err := r.pool.GetContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getContext %w", err)
+	}
+	defer closeConn(conn)
+	ids, err := redis.Strings(conn.Do("SMEMBERS", profileTrailingStopsPrefix+profileID.String()))
+	if err != nil {
+		return nil, fmt.Errorf("smembers %w", err)
+	}
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, trailingStopPrefix+profileID.String()+":"+id)
+	}
+	values, err := getValues(conn, keys)
+	if err != nil {
+		return nil, fmt.Errorf("getValues %w", err)
+	}
+	stops := make([]*model.TrailingStop, 0, len(values))
+	for _, value := range values {
+		if value == nil {
+			continue
+		}
+		stop, errDecode := decodeTrailingStop(value, profileID)
+		if errDecode != nil {
+			return nil, fmt.Errorf("decodeTrailingStop %w", errDecode)
+		}
+		stops = append(stops, stop)
+	}
+	return stops, nil
+}
+
+// GetTrailingStopsWithStatus returns stops of all profiles which have one of the given statuses.
+func (r *RedisTrailingStopRepository) GetTrailingStopsWithStatus(ctx context.Context,
+	statuses ...string) ([]*model.TrailingStop, error) {
+	conn, err := r.pool.GetContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getContext %w", err)
+	}
+	defer closeConn(conn)
+	var stops []*model.TrailingStop
+	for _, status := range statuses {
+		members, errMembers := redis.Strings(conn.Do("SMEMBERS", trailingStopStatusPrefix+status))
+		if errMembers != nil {
+			return nil, fmt.Errorf("smembers %w", errMembers)
+		}
+		keys := make([]string, 0, len(members))
+		for _, member := range members {
+			keys = append(keys, trailingStopPrefix+member)
+		}
+		values, errValues := getValues(conn, keys)
+		if errValues != nil {
+			return nil, fmt.Errorf("getValues %w", errValues)
+		}
+		for i, value := range values {
+			if value == nil {
+				continue
+			}
+			profileID, errParse := uuid.Parse(strings.SplitN(members[i], ":", 2)[0])
+			if errParse != nil {
+				return nil, fmt.Errorf("parse %w", errParse)
+			}
+			stop, errDecode := decodeTrailingStop(value, profileID)
+			if errDecode != nil {
+				return nil, fmt.Errorf("decodeTrailingStop %w", errDecode)
+			}
+			// stop could change its status between reading of index and reading of stop
+			if stop.Status == status {
+				stops = append(stops, stop)
+			}
+		}
+	}
+	return stops, nil
+}
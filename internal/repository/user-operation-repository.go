@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/garyburd/redigo/redis"
+	"github.com/google/uuid"
+)
+
+const userOperationPrefix = "user_operations:"
+
+// addUserOperationScript adds operation scored by its time, removes operations older than the kept period
+// and prolongs the key in one step, so counters of instances never see the set without expiration
+var addUserOperationScript = redis.NewScript(1, `redis.call("ZADD", KEYS[1], ARGV[1], ARGV[2])
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", "(" .. (tonumber(ARGV[1]) - tonumber(ARGV[3])))
+redis.call("PEXPIRE", KEYS[1], ARGV[3])
+return 1`) // nolint gochecknoglobals
+
+// UserOperationRepository keeps recent deposits and withdrawals of users in Redis, so limits of operations
+// are shared by all instances of APIService. Operations of profile are kept in sorted set scored by time
+type UserOperationRepository struct {
+	pool *redis.Pool
+}
+
+// NewUserOperationRepository creates and returns a new instance of UserOperationRepository, using the provided redis.Pool.
+func NewUserOperationRepository(pool *redis.Pool) *UserOperationRepository {
+	return &UserOperationRepository{pool: pool}
+}
+
+// AddOperation saves operation of profile, operations older than keep are removed.
+func (u *UserOperationRepository) AddOperation(ctx context.Context, profileID uuid.UUID, operation *model.UserOperation,
+	keep time.Duration) error {
+	data, err := json.Marshal(operation)
+	if err != nil {
+		return fmt.Errorf("marshal %w", err)
+	}
+	conn, err := u.pool.GetContext(ctx)
+	if err != nil {
+		return fmt.Errorf("getContext %w", err)
+	}
+	defer closeConn(conn)
+	_, err = addUserOperationScript.Do(conn, userOperationPrefix+profileID.String(), operation.Time.UnixMilli(), data,
+		keep.Milliseconds())
+	if err != nil {
+		return fmt.Errorf("do %w", err)
+	}
+	return nil
+}
+
+// RemoveOperation removes saved operation of profile.
+func (u *UserOperationRepository) RemoveOperation(ctx context.Context, profileID uuid.UUID,
+	operation *model.UserOperation) error {
+	data, err := json.Marshal(operation)
+	if err != nil {
+		return fmt.Errorf("marshal %w", err)
+	}
+	conn, err := u.pool.GetContext(ctx)
+	if err != nil {
+		return fmt.Errorf("getContext %w", err)
+	}
+	defer closeConn(conn)
+	if _, err = conn.Do("ZREM", userOperationPrefix+profileID.String(), data); err != nil {
+		return fmt.Errorf("zrem %w", err)
+	}
+	return nil
+}
+
+// GetOperations returns operations of profile made since the given time.
+func (u *UserOperationRepository) GetOperations(ctx context.Context, profileID uuid.UUID,
+	since time.Time) ([]*model.UserOperation, error) {
+	conn, err := u.pool.GetContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getContext %w", err)
+	}
+	defer closeConn(conn)
+	values, err := redis.ByteSlices(conn.Do("ZRANGEBYSCORE", userOperationPrefix+profileID.String(),
+		since.UnixMilli(), "+inf"))
+	if err != nil {
+		return nil, fmt.Errorf("zrangebyscore %w", err)
+	}
+	operations := make([]*model.UserOperation, 0, len(values))
+	for _, data := range values {
+		operation := &model.UserOperation{}
+		if err = json.Unmarshal(data, operation); err != nil {
+			return nil, fmt.Errorf("unmarshal %w", err)
+		}
+		operations = append(operations, operation)
+	}
+	return operations, nil
+}
+
+// MemoryUserOperationRepository keeps recent deposits and withdrawals of users in memory of a single instance.
+type MemoryUserOperationRepository struct {
+	mu         sync.Mutex
+	operations map[uuid.UUID][]*model.UserOperation
+}
+
+// NewMemoryUserOperationRepository creates and returns a new instance of MemoryUserOperationRepository.
+func NewMemoryUserOperationRepository() *MemoryUserOperationRepository {
+	return &MemoryUserOperationRepository{operations: make(map[uuid.UUID][]*model.UserOperation)}
+}
+
+// AddOperation saves operation of profile, operations older than keep are removed.
+func (m *MemoryUserOperationRepository) AddOperation(_ context.Context, profileID uuid.UUID,
+	operation *model.UserOperation, keep time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	oldest := operation.Time.Add(-keep)
+	operations := make([]*model.UserOperation, 0, len(m.operations[profileID])+1)
+	for _, saved := range m.operations[profileID] {
+		if !saved.Time.Before(oldest) {
+			operations = append(operations, saved)
+		}
+	}
+	saved := *operation
+	m.operations[profileID] = append(operations, &saved)
+	return nil
+}
+
+// RemoveOperation removes saved operation of profile.
+func (m *MemoryUserOperationRepository) RemoveOperation(_ context.Context, profileID uuid.UUID,
+	operation *model.UserOperation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	operations := m.operations[profileID][:0]
+	for _, saved := range m.operations[profileID] {
+		if saved.ID != operation.ID {
+			operations = append(operations, saved)
+		}
+	}
+	m.operations[profileID] = operations
+	return nil
+}
+
+// GetOperations returns operations of profile made since the given time.
+func (m *MemoryUserOperationRepository) GetOperations(_ context.Context, profileID uuid.UUID,
+	since time.Time) ([]*model.UserOperation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var operations []*model.UserOperation
+	for _, saved := range m.operations[profileID] {
+		if !saved.Time.Before(since) {
+			operation := *saved
+			operations = append(operations, &operation)
+		}
+	}
+	return operations, nil
+}
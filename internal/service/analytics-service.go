@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// moneyPlaces is a count of decimal places of averages of money in analytics
+const moneyPlaces = 2
+
+// ClosedPositionSource is an interface that contains method for getting closed positions of user
+type ClosedPositionSource interface {
+	GetClosedPositions(ctx context.Context, profileid uuid.UUID) ([]*model.Deal, error)
+}
+
+// AnalyticsService calculates performance of trading from closed positions of user
+type AnalyticsService struct {
+	positions ClosedPositionSource
+}
+
+// NewAnalyticsService accepts ClosedPositionSource object and returnes an object of type *AnalyticsService
+func NewAnalyticsService(positions ClosedPositionSource) *AnalyticsService {
+	return &AnalyticsService{positions: positions}
+}
+
+// GetAnalytics is a method of AnalyticsService that calculates figures of deals closed in [from, to) in total and per company.
+// Zero from or to leaves range open, empty company means all companies
+func (as *AnalyticsService) GetAnalytics(ctx context.Context, profileid uuid.UUID, from, to time.Time, company string) (*model.TradeAnalytics, error) {
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return nil, berrors.New(berrors.InvalidRequest, "From must be before to")
+	}
+	deals, err := as.positions.GetClosedPositions(ctx, profileid)
+	if err != nil {
+		return nil, fmt.Errorf("getClosedPositions %w", err)
+	}
+	selected := make([]*model.Deal, 0, len(deals))
+	for _, deal := range deals {
+		if !from.IsZero() && deal.EndDealTime.Before(from) || !to.IsZero() && !deal.EndDealTime.Before(to) {
+			continue
+		}
+		if company != "" && !strings.EqualFold(deal.Company, company) {
+			continue
+		}
+		selected = append(selected, deal)
+	}
+	sort.SliceStable(selected, func(i, j int) bool { return selected[i].EndDealTime.Before(selected[j].EndDealTime) })
+	analytics := &model.TradeAnalytics{From: from, To: to, Total: tradeStats(selected), Companies: make([]*model.TradeStats, 0)}
+	byCompany := make(map[string][]*model.Deal)
+	for _, deal := range selected {
+		byCompany[deal.Company] = append(byCompany[deal.Company], deal)
+	}
+	for name, companyDeals := range byCompany {
+		stats := tradeStats(companyDeals)
+		stats.Company = name
+		analytics.Companies = append(analytics.Companies, stats)
+	}
+	sort.Slice(analytics.Companies, func(i, j int) bool {
+		return analytics.Companies[i].Company < analytics.Companies[j].Company
+	})
+	return analytics, nil
+}
+
+// tradeStats calculates figures of deals which are ordered by closing time. Deals without profit and loss
+// are counted only in total count of deals
+func tradeStats(deals []*model.Deal) *model.TradeStats {
+	stats := &model.TradeStats{Trades: len(deals)}
+	var wins, losses []decimal.Decimal
+	var hold time.Duration
+	var peak decimal.Decimal
+	for _, deal := range deals {
+		switch {
+		case deal.Profit.IsPositive():
+			wins = append(wins, deal.Profit)
+			stats.GrossProfit = stats.GrossProfit.Add(deal.Profit)
+		case deal.Profit.IsNegative():
+			losses = append(losses, deal.Profit.Abs())
+			stats.GrossLoss = stats.GrossLoss.Add(deal.Profit.Abs())
+		}
+		hold += deal.EndDealTime.Sub(deal.DealTime)
+		stats.NetProfit = stats.NetProfit.Add(deal.Profit)
+		if stats.NetProfit.GreaterThan(peak) {
+			peak = stats.NetProfit
+		}
+		if drawdown := peak.Sub(stats.NetProfit); drawdown.GreaterThan(stats.MaxDrawdown) {
+			stats.MaxDrawdown = drawdown
+		}
+	}
+	stats.Wins, stats.Losses = len(wins), len(losses)
+	if stats.Trades > 0 {
+		stats.WinRate = percentOf(decimal.NewFromInt(int64(stats.Wins)), decimal.NewFromInt(int64(stats.Trades)))
+		stats.AverageHoldSeconds = int64((hold / time.Duration(stats.Trades)).Seconds())
+	}
+	if !stats.GrossLoss.IsZero() {
+		profitFactor := stats.GrossProfit.DivRound(stats.GrossLoss, moneyPlaces)
+		stats.ProfitFactor = &profitFactor
+	}
+	stats.AverageWin, stats.MedianWin, stats.LargestWin = summarize(wins)
+	stats.AverageLoss, stats.MedianLoss, stats.LargestLoss = summarize(losses)
+	return stats
+}
+
+// summarize returns mean, median and maximum of values, empty values give zeros
+func summarize(values []decimal.Decimal) (mean, median, largest decimal.Decimal) {
+	if len(values) == 0 {
+		return decimal.Zero, decimal.Zero, decimal.Zero
+	}
+	sorted := make([]decimal.Decimal, len(values))
+	copy(sorted, values)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].LessThan(sorted[j]) })
+	count := decimal.NewFromInt(int64(len(sorted)))
+	mean = decimal.Sum(sorted[0], sorted[1:]...).DivRound(count, moneyPlaces)
+	middle := len(sorted) / 2
+	median = sorted[middle]
+	if len(sorted)%2 == 0 {
+		median = sorted[middle-1].Add(sorted[middle]).DivRound(decimal.NewFromInt(2), moneyPlaces)
+	}
+	return mean, median, sorted[len(sorted)-1]
+}
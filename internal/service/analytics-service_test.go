@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+// closedSource returns fixed closed positions
+type closedSource struct {
+	deals []*model.Deal
+}
+
+func (s *closedSource) GetClosedPositions(_ context.Context, _ uuid.UUID) ([]*model.Deal, error) {
+	return s.deals, nil
+}
+
+func closedDeal(company string, profit int64, closedAt time.Time, hold time.Duration) *model.Deal {
+	return &model.Deal{
+		DealID:      uuid.New(),
+		Company:     company,
+		Profit:      decimal.NewFromInt(profit),
+		DealTime:    closedAt.Add(-hold),
+		EndDealTime: closedAt,
+	}
+}
+
+func TestGetAnalytics(t *testing.T) {
+	start := time.Date(2023, time.October, 1, 12, 0, 0, 0, time.UTC)
+	source := &closedSource{deals: []*model.Deal{
+		closedDeal("Tesla", -30, start.Add(3*time.Hour), time.Hour),
+		closedDeal("Apple", 100, start.Add(time.Hour), time.Hour),
+		closedDeal("Apple", -50, start.Add(2*time.Hour), 3*time.Hour),
+		closedDeal("Apple", 40, start.Add(4*time.Hour), time.Hour),
+		closedDeal("Tesla", 0, start.Add(5*time.Hour), time.Hour),
+		closedDeal("Apple", 500, start.Add(-time.Hour), time.Hour),
+	}}
+	srv := NewAnalyticsService(source)
+	analytics, err := srv.GetAnalytics(context.Background(), uuid.New(), start, start.Add(24*time.Hour), "")
+	require.NoError(t, err)
+
+	total := analytics.Total
+	require.Equal(t, 5, total.Trades)
+	require.Equal(t, 2, total.Wins)
+	require.Equal(t, 2, total.Losses)
+	require.Equal(t, "40", total.WinRate.String())
+	require.Equal(t, "60", total.NetProfit.String())
+	require.Equal(t, "1.75", total.ProfitFactor.String())
+	require.Equal(t, "70", total.AverageWin.String())
+	require.Equal(t, "70", total.MedianWin.String())
+	require.Equal(t, "40", total.AverageLoss.String())
+	require.Equal(t, "100", total.LargestWin.String())
+	require.Equal(t, "50", total.LargestLoss.String())
+	require.Equal(t, int64(5040), total.AverageHoldSeconds)
+	// cumulative profit is 100, 50, 20, 60, 60
+	require.Equal(t, "80", total.MaxDrawdown.String())
+
+	require.Len(t, analytics.Companies, 2)
+	require.Equal(t, "Apple", analytics.Companies[0].Company)
+	require.Equal(t, 3, analytics.Companies[0].Trades)
+	require.Equal(t, "50", analytics.Companies[0].MaxDrawdown.String())
+	require.Equal(t, "Tesla", analytics.Companies[1].Company)
+	require.Equal(t, "0", analytics.Companies[1].ProfitFactor.String())
+}
+
+func TestGetAnalyticsFilters(t *testing.T) {
+	start := time.Date(2023, time.October, 1, 12, 0, 0, 0, time.UTC)
+	source := &closedSource{deals: []*model.Deal{
+		closedDeal("Apple", 10, start, time.Hour),
+		closedDeal("Tesla", 20, start, time.Hour),
+	}}
+	srv := NewAnalyticsService(source)
+	analytics, err := srv.GetAnalytics(context.Background(), uuid.New(), time.Time{}, time.Time{}, "tesla")
+	require.NoError(t, err)
+	require.Equal(t, 1, analytics.Total.Trades)
+	require.Equal(t, "20", analytics.Total.NetProfit.String())
+	require.Nil(t, analytics.Total.ProfitFactor)
+
+	analytics, err = srv.GetAnalytics(context.Background(), uuid.New(), time.Time{}, start, "")
+	require.NoError(t, err)
+	require.Zero(t, analytics.Total.Trades)
+	require.Empty(t, analytics.Companies)
+
+	_, err = srv.GetAnalytics(context.Background(), uuid.New(), start, start, "")
+	var businessErr *berrors.BusinessError
+	require.ErrorAs(t, err, &businessErr)
+	require.Equal(t, berrors.InvalidRequest, businessErr.Code)
+}
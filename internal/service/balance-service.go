@@ -2,60 +2,127 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/artnikel/APIService/internal/config"
 	berrors "github.com/artnikel/APIService/internal/errors"
 	"github.com/artnikel/APIService/internal/model"
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
 )
 
 // BalanceRepository is an interface that contains methods for user manipulation
 type BalanceRepository interface {
-	BalanceOperation(ctx context.Context, balance *model.Balance) (float64, error)
-	GetBalance(ctx context.Context, profileid uuid.UUID) (float64, error)
+	BalanceOperation(ctx context.Context, balance *model.Balance) (decimal.Decimal, error)
+	GetBalance(ctx context.Context, profileid uuid.UUID) (decimal.Decimal, error)
 }
 
 // BalanceService contains BalanceRepository interface
 type BalanceService struct {
-	bRep BalanceRepository
-	cfg  config.Variables
+	bRep   BalanceRepository
+	ledger *LedgerService
+	lock   *ProfileLock
+	rules  *WithdrawalRules
+	cfg    config.Variables
 }
 
-// NewBalanceService accepts BalanceRepository object and returnes an object of type *BalanceService
-func NewBalanceService(bRep BalanceRepository, cfg *config.Variables) *BalanceService {
-	return &BalanceService{bRep: bRep, cfg: *cfg}
+// NewBalanceService accepts BalanceRepository, LedgerService, ProfileLock and WithdrawalRules objects and returnes
+// an object of type *BalanceService, nil ledger disables recording of operations, nil lock disables their serialization
+// and nil rules disable limits
+func NewBalanceService(bRep BalanceRepository, ledger *LedgerService, lock *ProfileLock, rules *WithdrawalRules,
+	cfg *config.Variables) *BalanceService {
+	return &BalanceService{bRep: bRep, ledger: ledger, lock: lock, rules: rules, cfg: *cfg}
 }
 
-// BalanceOperation is a method of BalanceService calls method of Repository
-func (bs *BalanceService) BalanceOperation(ctx context.Context, balance *model.Balance) (float64, error) {
-	if decimal.NewFromFloat(balance.Operation).IsNegative() {
-		money, err := bs.GetBalance(ctx, balance.ProfileID)
-		if err != nil {
-			return 0, fmt.Errorf("balanceOperation %w", err)
+// BalanceOperation is a method of BalanceService calls method of Repository, operation gets id if it has no id.
+// Sum of operation must fit precision of currency, it is never rounded silently. Operations of profile are serialized,
+// so concurrent withdrawals can`t pass the check of balance and limits together and overdraw it
+func (bs *BalanceService) BalanceOperation(ctx context.Context, balance *model.Balance) (decimal.Decimal, error) {
+	if balance.Operation.IsZero() || !model.USD.Fits(balance.Operation) {
+		return decimal.Zero, berrors.New(berrors.InvalidRequest, "Invalid sum of money")
+	}
+	if balance.BalanceID == uuid.Nil {
+		balance.BalanceID = uuid.New()
+	}
+	var operation decimal.Decimal
+	err := bs.serialize(ctx, balance.ProfileID, func(ctx context.Context) error {
+		now := time.Now().UTC()
+		if bs.rules != nil {
+			if err := bs.rules.Check(ctx, balance.ProfileID, balance.Operation, now); err != nil {
+				return err
+			}
 		}
-		if decimal.NewFromFloat(money).Cmp(decimal.NewFromFloat(balance.Operation).Abs()) == 1 {
-			operation, err := bs.bRep.BalanceOperation(ctx, balance)
+		if balance.Operation.IsNegative() {
+			money, err := bs.GetBalance(ctx, balance.ProfileID)
 			if err != nil {
-				return 0, fmt.Errorf("balanceOperation %w", err)
+				return err
+			}
+			if money.LessThan(balance.Operation.Abs()) {
+				return berrors.New(berrors.NotEnoughMoney, "Not enough money")
 			}
-			return operation, nil
 		}
-		return 0, berrors.New(berrors.NotEnoughMoney, "Not enough money")
-	}
-	operation, err := bs.bRep.BalanceOperation(ctx, balance)
+		var err error
+		operation, err = bs.limited(ctx, balance, now)
+		return err
+	})
 	if err != nil {
-		return 0, fmt.Errorf("balanceOperation %w", err)
+		return decimal.Zero, fmt.Errorf("balanceOperation %w", err)
 	}
 	return operation, nil
 }
 
 // GetBalance is a method of BalanceService calls method of Repository
-func (bs *BalanceService) GetBalance(ctx context.Context, profileid uuid.UUID) (float64, error) {
+func (bs *BalanceService) GetBalance(ctx context.Context, profileid uuid.UUID) (decimal.Decimal, error) {
 	money, err := bs.bRep.GetBalance(ctx, profileid)
 	if err != nil {
-		return 0, fmt.Errorf("getBalance %w", err)
+		return decimal.Zero, fmt.Errorf("getBalance %w", err)
 	}
 	return money, nil
 }
+
+// serialize runs operation under lock of profile if it is enabled
+func (bs *BalanceService) serialize(ctx context.Context, profileID uuid.UUID, operation func(ctx context.Context) error) error {
+	if bs.lock == nil {
+		return operation(ctx)
+	}
+	return bs.lock.Do(ctx, profileID, operation)
+}
+
+// limited records operation for limits of user before it is made, so failure of the store rejects operation
+// instead of letting it pass uncounted. Operation is forgotten only if backend surely rejected it
+func (bs *BalanceService) limited(ctx context.Context, balance *model.Balance, now time.Time) (decimal.Decimal, error) {
+	if bs.rules == nil {
+		return bs.operate(ctx, balance)
+	}
+	userOperation := &model.UserOperation{ID: balance.BalanceID, Time: now, Amount: balance.Operation}
+	if err := bs.rules.Record(ctx, balance.ProfileID, userOperation); err != nil {
+		return decimal.Zero, fmt.Errorf("record %w", err)
+	}
+	operation, err := bs.operate(ctx, balance)
+	var e *berrors.BusinessError
+	if errors.As(err, &e) && !berrors.Lookup(e.Code).Retryable {
+		if errForget := bs.rules.Forget(ctx, balance.ProfileID, userOperation); errForget != nil {
+			logrus.WithField("BalanceId", balance.BalanceID).Errorf("withdrawalRules: %v", errForget)
+		}
+	}
+	return operation, err
+}
+
+// operate makes balance operation and records it in ledger, error of ledger is only logged
+// because money is already moved when it happens
+func (bs *BalanceService) operate(ctx context.Context, balance *model.Balance) (decimal.Decimal, error) {
+	operation, err := bs.bRep.BalanceOperation(ctx, balance)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if bs.ledger != nil {
+		errRecord := bs.ledger.Record(ctx, balance.BalanceID, balance.ProfileID, OriginUser, nil, balance.Operation)
+		if errRecord != nil {
+			logrus.WithField("BalanceId", balance.BalanceID).Errorf("ledger: %v", errRecord)
+		}
+	}
+	return operation, nil
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/artnikel/APIService/internal/config"
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/artnikel/APIService/internal/repository"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+var profileLockCfg = config.Variables{
+	ProfileLockTTL:         time.Minute,
+	ProfileLockWaitTimeout: 10 * time.Second,
+}
+
+// slowWallet is a balance repository which is safe for concurrent use, but like remote balance service
+// it doesn`t check balance itself, and reading balance takes time, so unserialized withdrawals overdraw it
+type slowWallet struct {
+	mu       sync.Mutex
+	money    decimal.Decimal
+	lowest   decimal.Decimal
+	applied  int
+	readTime time.Duration
+}
+
+func (w *slowWallet) BalanceOperation(_ context.Context, balance *model.Balance) (decimal.Decimal, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.money = w.money.Add(balance.Operation)
+	w.lowest = decimal.Min(w.lowest, w.money)
+	w.applied++
+	return balance.Operation, nil
+}
+
+func (w *slowWallet) GetBalance(_ context.Context, _ uuid.UUID) (decimal.Decimal, error) {
+	w.mu.Lock()
+	money := w.money
+	w.mu.Unlock()
+	time.Sleep(w.readTime)
+	return money, nil
+}
+
+func TestBalanceOperationParallelWithdrawals(t *testing.T) {
+	wallet := &slowWallet{money: decimal.NewFromInt(100), readTime: time.Millisecond}
+	lock := NewProfileLock(repository.NewMemoryLockRepository(), &profileLockCfg)
+	srv := NewBalanceService(wallet, nil, lock, nil, &profileLockCfg)
+	profileID := uuid.New()
+
+	const withdrawals = 50
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded, rejected := 0, 0
+	for i := 0; i < withdrawals; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := srv.BalanceOperation(context.Background(), &model.Balance{
+				ProfileID: profileID, Operation: decimal.NewFromInt(-7),
+			})
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				succeeded++
+				return
+			}
+			var e *berrors.BusinessError
+			require.ErrorAs(t, err, &e)
+			require.Equal(t, berrors.NotEnoughMoney, e.Code)
+			rejected++
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, 14, succeeded)
+	require.Equal(t, withdrawals-14, rejected)
+	require.Equal(t, "2", wallet.money.String())
+	require.False(t, wallet.lowest.IsNegative())
+}
+
+func TestBalanceOperationWithdrawsFullBalance(t *testing.T) {
+	wallet := &slowWallet{money: decimal.RequireFromString("100.05")}
+	srv := NewBalanceService(wallet, nil, NewProfileLock(repository.NewMemoryLockRepository(), &profileLockCfg), nil,
+		&profileLockCfg)
+
+	_, err := srv.BalanceOperation(context.Background(), &model.Balance{
+		ProfileID: uuid.New(), Operation: decimal.RequireFromString("-100.06"),
+	})
+	var e *berrors.BusinessError
+	require.ErrorAs(t, err, &e)
+	require.Equal(t, berrors.NotEnoughMoney, e.Code)
+
+	_, err = srv.BalanceOperation(context.Background(), &model.Balance{
+		ProfileID: uuid.New(), Operation: decimal.RequireFromString("-100.05"),
+	})
+	require.NoError(t, err)
+	require.True(t, wallet.money.IsZero())
+}
+
+func TestProfileLockTimesOut(t *testing.T) {
+	locks := repository.NewMemoryLockRepository()
+	lock := NewProfileLock(locks, &config.Variables{ProfileLockTTL: time.Minute, ProfileLockWaitTimeout: 50 * time.Millisecond})
+	profileID := uuid.New()
+	acquired, err := locks.Acquire(context.Background(), profileLockPrefix+profileID.String(), "otherInstance", time.Minute)
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	called := false
+	err = lock.Do(context.Background(), profileID, func(context.Context) error {
+		called = true
+		return nil
+	})
+	var e *berrors.BusinessError
+	require.ErrorAs(t, err, &e)
+	require.Equal(t, berrors.ProfileBusy, e.Code)
+	require.False(t, called)
+
+	require.NoError(t, lock.Do(context.Background(), uuid.New(), func(context.Context) error {
+		called = true
+		return nil
+	}))
+	require.True(t, called)
+}
+
+func TestProfileLockIsHeldLongerThanTTL(t *testing.T) {
+	locks := repository.NewMemoryLockRepository()
+	lock := NewProfileLock(locks, &config.Variables{ProfileLockTTL: 30 * time.Millisecond, ProfileLockWaitTimeout: 10 * time.Millisecond})
+	profileID := uuid.New()
+	key := profileLockPrefix + profileID.String()
+
+	err := lock.Do(context.Background(), profileID, func(ctx context.Context) error {
+		time.Sleep(100 * time.Millisecond)
+		require.NoError(t, ctx.Err())
+		acquired, errAcquire := locks.Acquire(context.Background(), key, "otherInstance", time.Minute)
+		require.NoError(t, errAcquire)
+		require.False(t, acquired)
+		return nil
+	})
+	require.NoError(t, err)
+	acquired, err := locks.Acquire(context.Background(), key, "otherInstance", time.Minute)
+	require.NoError(t, err)
+	require.True(t, acquired)
+}
+
+// losingLocks is a LockRepository whose locks can`t be extended, as if they were taken by another instance
+type losingLocks struct {
+	*repository.MemoryLockRepository
+}
+
+func (l losingLocks) Extend(_ context.Context, _, _ string, _ time.Duration) (bool, error) {
+	return false, nil
+}
+
+func TestProfileLockCancelsOperationWhenLockIsLost(t *testing.T) {
+	lock := NewProfileLock(losingLocks{repository.NewMemoryLockRepository()},
+		&config.Variables{ProfileLockTTL: 30 * time.Millisecond, ProfileLockWaitTimeout: 10 * time.Millisecond})
+
+	err := lock.Do(context.Background(), uuid.New(), func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+			return nil
+		}
+	})
+	var e *berrors.BusinessError
+	require.ErrorAs(t, err, &e)
+	require.Equal(t, berrors.Unavailable, e.Code)
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// defaultDealSort is a field which orders deals when sort isn't set
+	defaultDealSort = "dealtime"
+	// maxDealLimit is the biggest count of deals in one page
+	maxDealLimit = 1000
+)
+
+// dealComparators compare deals by fields which can order deals
+var dealComparators = map[string]func(a, b *model.Deal) int{ // nolint gochecknoglobals
+	"dealtime":      func(a, b *model.Deal) int { return compareTime(a.DealTime, b.DealTime) },
+	"enddealtime":   func(a, b *model.Deal) int { return compareTime(a.EndDealTime, b.EndDealTime) },
+	"profit":        func(a, b *model.Deal) int { return a.Profit.Cmp(b.Profit) },
+	"company":       func(a, b *model.Deal) int { return strings.Compare(a.Company, b.Company) },
+	"sharescount":   func(a, b *model.Deal) int { return a.SharesCount.Cmp(b.SharesCount) },
+	"purchaseprice": func(a, b *model.Deal) int { return a.PurchasePrice.Cmp(b.PurchasePrice) },
+}
+
+// dealCursor is a position in ordered deals, it keeps order of deals so cursor can't be used with another order
+type dealCursor struct {
+	SortBy     string    `json:"s"`
+	Descending bool      `json:"d"`
+	Key        string    `json:"k"`
+	DealID     uuid.UUID `json:"i"`
+}
+
+// queryDeals filters and orders deals and returns the page after cursor of filter. Deals with the same value
+// of sort field are ordered by id, so pages don't skip or repeat deals while the list doesn't change
+func queryDeals(deals []*model.Deal, filter *model.DealFilter) (*model.DealPage, error) {
+	if filter.SortBy == "" {
+		filter.SortBy = defaultDealSort
+	}
+	compare, ok := dealComparators[filter.SortBy]
+	if !ok {
+		return nil, berrors.New(berrors.InvalidRequest, "Deals can be sorted by dealtime, enddealtime, profit, company, sharescount or purchaseprice")
+	}
+	if filter.Limit < 0 || filter.Limit > maxDealLimit {
+		return nil, berrors.New(berrors.InvalidRequest, fmt.Sprintf("Limit must be between 0 and %d", maxDealLimit))
+	}
+	match, err := dealMatcher(filter)
+	if err != nil {
+		return nil, err
+	}
+	less := func(a, b *model.Deal) bool {
+		result := compare(a, b)
+		if result == 0 {
+			result = strings.Compare(a.DealID.String(), b.DealID.String())
+		}
+		if filter.Descending {
+			return result > 0
+		}
+		return result < 0
+	}
+	selected := make([]*model.Deal, 0, len(deals))
+	for _, deal := range deals {
+		if match(deal) {
+			selected = append(selected, deal)
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool { return less(selected[i], selected[j]) })
+	if filter.Cursor != "" {
+		last, errCursor := decodeDealCursor(filter)
+		if errCursor != nil {
+			return nil, errCursor
+		}
+		start := sort.Search(len(selected), func(i int) bool { return less(last, selected[i]) })
+		selected = selected[start:]
+	}
+	page := &model.DealPage{Deals: selected}
+	if filter.Limit > 0 && len(selected) > filter.Limit {
+		page.Deals = selected[:filter.Limit]
+		page.NextCursor = encodeDealCursor(filter, page.Deals[filter.Limit-1])
+	}
+	return page, nil
+}
+
+// dealMatcher returns function which checks if deal satisfies conditions of filter
+func dealMatcher(filter *model.DealFilter) (func(deal *model.Deal) bool, error) {
+	var profitSign int
+	switch filter.Profit {
+	case "":
+	case "positive":
+		profitSign = 1
+	case "negative":
+		profitSign = -1
+	case "zero":
+		profitSign = 0
+	default:
+		return nil, berrors.New(berrors.InvalidRequest, "Profit must be positive, negative or zero")
+	}
+	return func(deal *model.Deal) bool {
+		if filter.Company != "" && !strings.EqualFold(deal.Company, filter.Company) {
+			return false
+		}
+		if filter.Profit != "" && deal.Profit.Sign() != profitSign {
+			return false
+		}
+		return inRange(deal.DealTime, filter.From, filter.To) && inRange(deal.EndDealTime, filter.ClosedFrom, filter.ClosedTo)
+	}, nil
+}
+
+// inRange checks if t is in [from, to), zero bound leaves range open
+func inRange(t, from, to time.Time) bool {
+	return (from.IsZero() || !t.Before(from)) && (to.IsZero() || t.Before(to))
+}
+
+// encodeDealCursor encodes position of deal in order of filter
+func encodeDealCursor(filter *model.DealFilter, deal *model.Deal) string {
+	cursor := dealCursor{SortBy: filter.SortBy, Descending: filter.Descending, DealID: deal.DealID}
+	switch filter.SortBy {
+	case "dealtime":
+		cursor.Key = deal.DealTime.Format(time.RFC3339Nano)
+	case "enddealtime":
+		cursor.Key = deal.EndDealTime.Format(time.RFC3339Nano)
+	case "profit":
+		cursor.Key = deal.Profit.String()
+	case "company":
+		cursor.Key = deal.Company
+	case "sharescount":
+		cursor.Key = deal.SharesCount.String()
+	case "purchaseprice":
+		cursor.Key = deal.PurchasePrice.String()
+	}
+	data, _ := json.Marshal(cursor) // nolint errchkjson
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeDealCursor decodes cursor of filter into deal which has only id and value of sort field
+func decodeDealCursor(filter *model.DealFilter) (*model.Deal, error) {
+	invalid := berrors.New(berrors.InvalidRequest, "Invalid cursor")
+	data, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+	if err != nil {
+		return nil, invalid
+	}
+	var cursor dealCursor
+	if err = json.Unmarshal(data, &cursor); err != nil {
+		return nil, invalid
+	}
+	if cursor.SortBy != filter.SortBy || cursor.Descending != filter.Descending {
+		return nil, berrors.New(berrors.InvalidRequest, "Cursor was created for another order of deals")
+	}
+	deal := &model.Deal{DealID: cursor.DealID}
+	switch cursor.SortBy {
+	case "dealtime", "enddealtime":
+		var t time.Time
+		if t, err = time.Parse(time.RFC3339Nano, cursor.Key); err != nil {
+			return nil, invalid
+		}
+		deal.DealTime, deal.EndDealTime = t, t
+	case "profit", "sharescount", "purchaseprice":
+		var value decimal.Decimal
+		if value, err = decimal.NewFromString(cursor.Key); err != nil {
+			return nil, invalid
+		}
+		deal.Profit, deal.SharesCount, deal.PurchasePrice = value, value, value
+	case "company":
+		deal.Company = cursor.Key
+	}
+	return deal, nil
+}
+
+// compareTime compares times like strings.Compare compares strings
+func compareTime(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	default:
+		return 0
+	}
+}
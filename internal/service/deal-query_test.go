@@ -0,0 +1,83 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func testQueryDeals() []*model.Deal {
+	start := time.Date(2023, time.October, 1, 12, 0, 0, 0, time.UTC)
+	deals := make([]*model.Deal, 0, 7)
+	for i, profit := range []int64{30, -10, 30, 0, -20, 30, 5} {
+		company := "Apple"
+		if i%2 == 1 {
+			company = "Tesla"
+		}
+		deals = append(deals, &model.Deal{
+			DealID:      uuid.New(),
+			Company:     company,
+			Profit:      decimal.NewFromInt(profit),
+			DealTime:    start.Add(time.Duration(i) * time.Hour),
+			EndDealTime: start.Add(time.Duration(i+1) * time.Hour),
+		})
+	}
+	return deals
+}
+
+func TestQueryDealsPagination(t *testing.T) {
+	deals := testQueryDeals()
+	filter := &model.DealFilter{SortBy: "profit", Descending: true, Limit: 2}
+	var got []*model.Deal
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, len(deals))
+		page, err := queryDeals(deals, filter)
+		require.NoError(t, err)
+		got = append(got, page.Deals...)
+		if page.NextCursor == "" {
+			break
+		}
+		filter.Cursor = page.NextCursor
+	}
+	require.Len(t, got, len(deals))
+	for i := 1; i < len(got); i++ {
+		require.False(t, got[i].Profit.GreaterThan(got[i-1].Profit))
+	}
+	ids := make(map[uuid.UUID]struct{})
+	for _, deal := range got {
+		ids[deal.DealID] = struct{}{}
+	}
+	require.Len(t, ids, len(deals), "pages must not repeat deals with the same profit")
+}
+
+func TestQueryDealsFilter(t *testing.T) {
+	deals := testQueryDeals()
+	page, err := queryDeals(deals, &model.DealFilter{Company: "apple", Profit: "positive"})
+	require.NoError(t, err)
+	require.Len(t, page.Deals, 3)
+	require.Equal(t, deals[0].DealID, page.Deals[0].DealID)
+
+	page, err = queryDeals(deals, &model.DealFilter{From: deals[2].DealTime, ClosedTo: deals[4].EndDealTime, SortBy: "-"})
+	require.Nil(t, page)
+	var businessErr *berrors.BusinessError
+	require.ErrorAs(t, err, &businessErr)
+
+	page, err = queryDeals(deals, &model.DealFilter{From: deals[2].DealTime, ClosedTo: deals[4].EndDealTime})
+	require.NoError(t, err)
+	require.Len(t, page.Deals, 2)
+
+	first, err := queryDeals(deals, &model.DealFilter{Limit: 1})
+	require.NoError(t, err)
+	_, err = queryDeals(deals, &model.DealFilter{Limit: 1, Descending: true, Cursor: first.NextCursor})
+	require.ErrorAs(t, err, &businessErr)
+	require.Equal(t, berrors.InvalidRequest, businessErr.Code)
+	_, err = queryDeals(deals, &model.DealFilter{Cursor: "???"})
+	require.ErrorAs(t, err, &businessErr)
+	_, err = queryDeals(deals, &model.DealFilter{Profit: "some"})
+	require.ErrorAs(t, err, &businessErr)
+}
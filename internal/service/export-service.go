@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	closedStatus = "closed"
+	openStatus   = "open"
+)
+
+// TradeSource is an interface that contains methods for getting positions of user and cached prices
+type TradeSource interface {
+	PositionSource
+	ClosedPositionSource
+}
+
+// ExportService prepares trade history of user for export
+type ExportService struct {
+	trades TradeSource
+}
+
+// NewExportService accepts TradeSource object and returnes an object of type *ExportService
+func NewExportService(trades TradeSource) *ExportService {
+	return &ExportService{trades: trades}
+}
+
+// ExportTrades is a method of ExportService that passes closed deals which match filter to write one by one,
+// so caller can send them without keeping the whole export. Export is never split into pages, limit and cursor
+// of filter are ignored. When includeOpen is set, open deals which match company and entry time of filter
+// follow closed deals, valued with current prices
+func (es *ExportService) ExportTrades(ctx context.Context, profileid uuid.UUID, filter *model.DealFilter, includeOpen bool,
+	write func(row *model.TradeRow) error) error {
+	closedDeals, err := es.trades.GetClosedPositions(ctx, profileid)
+	if err != nil {
+		return fmt.Errorf("getClosedPositions %w", err)
+	}
+	closedFilter := *filter
+	closedFilter.Limit, closedFilter.Cursor = 0, ""
+	if closedFilter.SortBy == "" {
+		closedFilter.SortBy = "enddealtime"
+	}
+	page, err := queryDeals(closedDeals, &closedFilter)
+	if err != nil {
+		return err
+	}
+	var openDeals *model.DealPage
+	var prices map[string]decimal.Decimal
+	if includeOpen {
+		if openDeals, prices, err = es.openDeals(ctx, profileid, filter); err != nil {
+			return err
+		}
+	}
+	for _, deal := range page.Deals {
+		row := tradeRow(deal, closedStatus)
+		endDealTime := deal.EndDealTime
+		row.EndDealTime = &endDealTime
+		row.Profit = deal.Profit
+		if err = write(row); err != nil {
+			return fmt.Errorf("write %w", err)
+		}
+	}
+	if openDeals == nil {
+		return nil
+	}
+	for _, deal := range openDeals.Deals {
+		price, ok := prices[deal.Company]
+		if !ok {
+			return berrors.New(berrors.Unavailable, fmt.Sprintf("Price of %s is unavailable", deal.Company))
+		}
+		position := valuePosition(deal, price)
+		row := tradeRow(deal, openStatus)
+		row.CurrentPrice = &price
+		row.Profit = position.UnrealizedPnL
+		if err = write(row); err != nil {
+			return fmt.Errorf("write %w", err)
+		}
+	}
+	return nil
+}
+
+// openDeals returns open deals which match company and entry time of filter and current prices of shares
+func (es *ExportService) openDeals(ctx context.Context, profileid uuid.UUID, filter *model.DealFilter) (*model.DealPage,
+	map[string]decimal.Decimal, error) {
+	deals, err := es.trades.GetUnclosedPositions(ctx, profileid)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getUnclosedPositions %w", err)
+	}
+	page, err := queryDeals(deals, &model.DealFilter{Company: filter.Company, From: filter.From, To: filter.To})
+	if err != nil {
+		return nil, nil, err
+	}
+	snapshot, err := es.trades.GetPriceSnapshot(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getPriceSnapshot %w", err)
+	}
+	prices := make(map[string]decimal.Decimal, len(snapshot.Shares))
+	for _, share := range snapshot.Shares {
+		prices[share.Company] = share.Price
+	}
+	return page, prices, nil
+}
+
+// tradeRow copies fields which closed and open deals have in common
+func tradeRow(deal *model.Deal, status string) *model.TradeRow {
+	return &model.TradeRow{
+		DealID:        deal.DealID,
+		Status:        status,
+		Company:       deal.Company,
+		Direction:     dealDirection(deal),
+		SharesCount:   deal.SharesCount,
+		PurchasePrice: deal.PurchasePrice,
+		StopLoss:      deal.StopLoss,
+		TakeProfit:    deal.TakeProfit,
+		DealTime:      deal.DealTime,
+	}
+}
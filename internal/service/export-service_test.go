@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+// tradeSource returns fixed closed and open positions and prices
+type tradeSource struct {
+	portfolioSource
+	closed []*model.Deal
+}
+
+func (s *tradeSource) GetClosedPositions(_ context.Context, _ uuid.UUID) ([]*model.Deal, error) {
+	return s.closed, nil
+}
+
+func TestExportTrades(t *testing.T) {
+	start := time.Date(2023, time.October, 1, 12, 0, 0, 0, time.UTC)
+	source := &tradeSource{
+		portfolioSource: portfolioSource{
+			deals: []*model.Deal{{
+				DealID: uuid.New(), Company: "Apple", SharesCount: decimal.NewFromInt(2), PurchasePrice: decimal.NewFromInt(100),
+				StopLoss: decimal.NewFromInt(120), TakeProfit: decimal.NewFromInt(80), DealTime: start,
+			}},
+			shares: []model.Share{{Company: "Apple", Price: decimal.RequireFromString("95.5")}},
+		},
+		closed: []*model.Deal{
+			closedDeal("Apple", 10, start.Add(2*time.Hour), time.Hour),
+			closedDeal("Apple", -5, start.Add(time.Hour), time.Hour),
+		},
+	}
+	srv := NewExportService(source)
+	var rows []*model.TradeRow
+	write := func(row *model.TradeRow) error {
+		rows = append(rows, row)
+		return nil
+	}
+	require.NoError(t, srv.ExportTrades(context.Background(), uuid.New(), &model.DealFilter{}, true, write))
+	require.Len(t, rows, 3)
+	require.Equal(t, closedStatus, rows[0].Status)
+	require.Equal(t, "-5", rows[0].Profit.String())
+	require.Equal(t, start.Add(time.Hour), *rows[0].EndDealTime)
+	require.Nil(t, rows[0].CurrentPrice)
+	require.Equal(t, openStatus, rows[2].Status)
+	require.Equal(t, short, rows[2].Direction)
+	require.Equal(t, "95.5", rows[2].CurrentPrice.String())
+	require.Equal(t, "9", rows[2].Profit.String())
+	require.Nil(t, rows[2].EndDealTime)
+
+	rows = nil
+	require.NoError(t, srv.ExportTrades(context.Background(), uuid.New(), &model.DealFilter{Profit: "positive"}, false, write))
+	require.Len(t, rows, 1)
+	require.Equal(t, "10", rows[0].Profit.String())
+}
+
+func TestExportTradesIgnoresPage(t *testing.T) {
+	start := time.Date(2023, time.October, 1, 12, 0, 0, 0, time.UTC)
+	source := &tradeSource{closed: []*model.Deal{
+		closedDeal("Apple", 10, start.Add(3*time.Hour), time.Hour),
+		closedDeal("Apple", -5, start.Add(2*time.Hour), time.Hour),
+		closedDeal("Tesla", 3, start.Add(time.Hour), time.Hour),
+	}}
+	srv := NewExportService(source)
+	var rows []*model.TradeRow
+	write := func(row *model.TradeRow) error {
+		rows = append(rows, row)
+		return nil
+	}
+	filter := &model.DealFilter{Company: "Apple", Limit: 1, Cursor: "unknown"}
+	require.NoError(t, srv.ExportTrades(context.Background(), uuid.New(), filter, false, write))
+	require.Len(t, rows, 2)
+	require.Equal(t, "-5", rows[0].Profit.String())
+	require.Equal(t, "10", rows[1].Profit.String())
+}
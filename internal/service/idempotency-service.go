@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/artnikel/APIService/internal/config"
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// idempotencyPollInterval is how often request waits for response of the duplicate which is in progress
+const idempotencyPollInterval = 50 * time.Millisecond
+
+// IdempotencyRepository is an interface that contains methods for keeping responses to requests with idempotency keys
+type IdempotencyRepository interface {
+	Get(ctx context.Context, key string) (*model.IdempotentResponse, error)
+	Save(ctx context.Context, key string, resp *model.IdempotentResponse, ttl time.Duration) error
+}
+
+// LockRepository is an interface that contains methods for locks shared by all instances of APIService
+type LockRepository interface {
+	Acquire(ctx context.Context, key, token string, ttl time.Duration) (bool, error)
+	Release(ctx context.Context, key, token string) error
+	Extend(ctx context.Context, key, token string, ttl time.Duration) (bool, error)
+}
+
+// IdempotencyService contains IdempotencyRepository and LockRepository interfaces
+type IdempotencyService struct {
+	iRep  IdempotencyRepository
+	locks LockRepository
+	cfg   config.Variables
+}
+
+// NewIdempotencyService accepts IdempotencyRepository and LockRepository objects and returnes an object of type *IdempotencyService
+func NewIdempotencyService(iRep IdempotencyRepository, locks LockRepository, cfg *config.Variables) *IdempotencyService {
+	return &IdempotencyService{iRep: iRep, locks: locks, cfg: *cfg}
+}
+
+// Begin is a method of IdempotencyService that returns saved response if request with the key was already processed.
+// Otherwise it takes the key for processing and returns function that releases the key, caller must save response
+// with Complete before calling it. Key is held until it is released however long request runs, IdempotencyLockTTL
+// only limits how long key of a crashed instance stays taken. Duplicates which come while request is processed
+// wait for its response, and get business error if it doesn`t come in time.
+func (is *IdempotencyService) Begin(ctx context.Context, key string) (*model.IdempotentResponse, func(), error) {
+	token := uuid.New().String()
+	deadline := time.Now().Add(is.cfg.IdempotencyWaitTimeout)
+	for {
+		resp, err := is.iRep.Get(ctx, key)
+		if err != nil {
+			return nil, nil, fmt.Errorf("get %w", err)
+		}
+		if resp != nil {
+			return resp, nil, nil
+		}
+		acquired, err := is.locks.Acquire(ctx, key, token, is.cfg.IdempotencyLockTTL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("acquire %w", err)
+		}
+		if acquired {
+			stop, stopped := make(chan struct{}), make(chan struct{})
+			go is.keep(key, token, stop, stopped)
+			release := func() {
+				close(stop)
+				<-stopped
+				if errRelease := is.locks.Release(context.Background(), key, token); errRelease != nil {
+					logrus.Errorf("begin: %v", errRelease)
+				}
+			}
+			// response could be saved between Get and Acquire by the request which held the lock
+			resp, err = is.iRep.Get(ctx, key)
+			if err != nil {
+				release()
+				return nil, nil, fmt.Errorf("get %w", err)
+			}
+			if resp != nil {
+				release()
+				return resp, nil, nil
+			}
+			return nil, release, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, nil, berrors.New(berrors.RequestInProgress, "Request with the same idempotency key is in progress")
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil, fmt.Errorf("begin %w", ctx.Err())
+		case <-time.After(idempotencyPollInterval):
+		}
+	}
+}
+
+// keep extends lock of key every third of its ttl until stop is closed, so the lock doesn`t expire
+// while request is processed and its duplicate isn`t executed once more
+func (is *IdempotencyService) keep(key, token string, stop <-chan struct{}, stopped chan<- struct{}) {
+	defer close(stopped)
+	ticker := time.NewTicker(is.cfg.IdempotencyLockTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			extended, err := is.locks.Extend(context.Background(), key, token, is.cfg.IdempotencyLockTTL)
+			if err != nil {
+				logrus.Errorf("keep: %v", err)
+				continue
+			}
+			if !extended {
+				logrus.Errorf("keep: lock of idempotency key %s is lost", key)
+				return
+			}
+		}
+	}
+}
+
+// Complete is a method of IdempotencyService that saves response to request with the key, so it is replayed to retries
+func (is *IdempotencyService) Complete(ctx context.Context, key string, resp *model.IdempotentResponse) error {
+	if err := is.iRep.Save(ctx, key, resp, is.cfg.IdempotencyTTL); err != nil {
+		return fmt.Errorf("save %w", err)
+	}
+	return nil
+}
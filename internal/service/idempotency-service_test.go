@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/artnikel/APIService/internal/config"
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/artnikel/APIService/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+var idempotencyCfg = config.Variables{
+	IdempotencyTTL:         time.Hour,
+	IdempotencyLockTTL:     time.Minute,
+	IdempotencyWaitTimeout: 200 * time.Millisecond,
+}
+
+func TestIdempotencyReplaysSavedResponse(t *testing.T) {
+	srv := NewIdempotencyService(repository.NewMemoryIdempotencyRepository(), repository.NewMemoryLockRepository(), &idempotencyCfg)
+	ctx := context.Background()
+
+	saved, release, err := srv.Begin(ctx, "testKey")
+	require.NoError(t, err)
+	require.Nil(t, saved)
+	require.NotNil(t, release)
+	resp := &model.IdempotentResponse{Fingerprint: "testFingerprint", Status: 200, Body: []byte(`{"operation":10}`)}
+	require.NoError(t, srv.Complete(ctx, "testKey", resp))
+	release()
+
+	saved, release, err = srv.Begin(ctx, "testKey")
+	require.NoError(t, err)
+	require.Nil(t, release)
+	require.Equal(t, resp, saved)
+}
+
+func TestIdempotencyDuplicateWaitsForResponse(t *testing.T) {
+	srv := NewIdempotencyService(repository.NewMemoryIdempotencyRepository(), repository.NewMemoryLockRepository(), &idempotencyCfg)
+	ctx := context.Background()
+
+	_, release, err := srv.Begin(ctx, "testKey")
+	require.NoError(t, err)
+	resp := &model.IdempotentResponse{Fingerprint: "testFingerprint", Status: 200}
+	go func() {
+		time.Sleep(2 * idempotencyPollInterval)
+		require.NoError(t, srv.Complete(ctx, "testKey", resp))
+		release()
+	}()
+
+	saved, releaseDuplicate, err := srv.Begin(ctx, "testKey")
+	require.NoError(t, err)
+	require.Nil(t, releaseDuplicate)
+	require.Equal(t, resp, saved)
+}
+
+func TestIdempotencyDuplicateTimesOut(t *testing.T) {
+	srv := NewIdempotencyService(repository.NewMemoryIdempotencyRepository(), repository.NewMemoryLockRepository(), &idempotencyCfg)
+	ctx := context.Background()
+
+	_, release, err := srv.Begin(ctx, "testKey")
+	require.NoError(t, err)
+	defer release()
+
+	_, _, err = srv.Begin(ctx, "testKey")
+	var e *berrors.BusinessError
+	require.ErrorAs(t, err, &e)
+	require.Equal(t, berrors.RequestInProgress, e.Code)
+
+	_, releaseOther, err := srv.Begin(ctx, "otherKey")
+	require.NoError(t, err)
+	require.NotNil(t, releaseOther)
+	releaseOther()
+}
+
+func TestIdempotencyKeyIsHeldLongerThanLockTTL(t *testing.T) {
+	cfg := idempotencyCfg
+	cfg.IdempotencyLockTTL = 30 * time.Millisecond
+	cfg.IdempotencyWaitTimeout = 10 * time.Millisecond
+	srv := NewIdempotencyService(repository.NewMemoryIdempotencyRepository(), repository.NewMemoryLockRepository(), &cfg)
+	ctx := context.Background()
+
+	_, release, err := srv.Begin(ctx, "testKey")
+	require.NoError(t, err)
+	time.Sleep(4 * cfg.IdempotencyLockTTL)
+	_, _, err = srv.Begin(ctx, "testKey")
+	var e *berrors.BusinessError
+	require.ErrorAs(t, err, &e)
+	require.Equal(t, berrors.RequestInProgress, e.Code)
+
+	release()
+	_, releaseRetry, err := srv.Begin(ctx, "testKey")
+	require.NoError(t, err)
+	require.NotNil(t, releaseRetry)
+	releaseRetry()
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// leaderLockPrefix is a prefix of keys of leader locks in LockRepository
+const leaderLockPrefix = "leader:"
+
+// Leader elects one instance of APIService which runs background engine. Leadership is a lock in LockRepository
+// which the leader extends on every tick, so another instance takes it over when the leader stops.
+// Leader is used by one goroutine of engine
+type Leader struct {
+	locks LockRepository
+	key   string
+	token string
+	ttl   time.Duration
+	held  bool
+}
+
+// NewLeader accepts LockRepository object and returnes an object of type *Leader for engine with the given name
+func NewLeader(locks LockRepository, name string, ttl time.Duration) *Leader {
+	return &Leader{locks: locks, key: leaderLockPrefix + name, token: uuid.New().String(), ttl: ttl}
+}
+
+// Lead is a method of Leader that reports if this instance leads engine. Held lock is extended,
+// otherwise instance tries to take it. Error of extension keeps the lock held until it is known to be lost
+func (l *Leader) Lead(ctx context.Context) (bool, error) {
+	if l.held {
+		extended, err := l.locks.Extend(ctx, l.key, l.token, l.ttl)
+		if err != nil {
+			return false, fmt.Errorf("extend %w", err)
+		}
+		if extended {
+			return true, nil
+		}
+		l.held = false
+	}
+	acquired, err := l.locks.Acquire(ctx, l.key, l.token, l.ttl)
+	if err != nil {
+		return false, fmt.Errorf("acquire %w", err)
+	}
+	l.held = acquired
+	return acquired, nil
+}
+
+// Resign is a method of Leader that releases held lock, so another instance doesn`t wait until it expires
+func (l *Leader) Resign(ctx context.Context) error {
+	if !l.held {
+		return nil
+	}
+	l.held = false
+	if err := l.locks.Release(ctx, l.key, l.token); err != nil {
+		return fmt.Errorf("release %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/artnikel/APIService/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeaderIsElectedOnce(t *testing.T) {
+	locks := repository.NewMemoryLockRepository()
+	first := NewLeader(locks, "orders", time.Minute)
+	second := NewLeader(locks, "orders", time.Minute)
+	other := NewLeader(locks, "trailing_stops", time.Minute)
+	ctx := context.Background()
+
+	lead, err := first.Lead(ctx)
+	require.NoError(t, err)
+	require.True(t, lead)
+	lead, err = second.Lead(ctx)
+	require.NoError(t, err)
+	require.False(t, lead)
+	lead, err = other.Lead(ctx)
+	require.NoError(t, err)
+	require.True(t, lead, "engines are led separately")
+	lead, err = first.Lead(ctx)
+	require.NoError(t, err)
+	require.True(t, lead, "leader keeps leadership")
+
+	require.NoError(t, first.Resign(ctx))
+	lead, err = second.Lead(ctx)
+	require.NoError(t, err)
+	require.True(t, lead)
+	lead, err = first.Lead(ctx)
+	require.NoError(t, err)
+	require.False(t, lead)
+}
+
+func TestLeaderIsReplacedWhenLockExpires(t *testing.T) {
+	locks := repository.NewMemoryLockRepository()
+	first := NewLeader(locks, "orders", 20*time.Millisecond)
+	second := NewLeader(locks, "orders", 20*time.Millisecond)
+	ctx := context.Background()
+
+	lead, err := first.Lead(ctx)
+	require.NoError(t, err)
+	require.True(t, lead)
+	time.Sleep(30 * time.Millisecond)
+	lead, err = second.Lead(ctx)
+	require.NoError(t, err)
+	require.True(t, lead)
+	lead, err = first.Lead(ctx)
+	require.NoError(t, err)
+	require.False(t, lead, "expired leader doesn't extend lock of another leader")
+}
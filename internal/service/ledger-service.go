@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// OriginUser is an origin of deposits and withdrawals made by user
+	OriginUser = "user"
+	// OriginTradeOpen is an origin of payments for opening positions
+	OriginTradeOpen = "trade_open"
+	// OriginTradeClose is an origin of money returned by closing positions
+	OriginTradeClose = "trade_close"
+
+	creditType = "credit"
+	debitType  = "debit"
+
+	// defaultStatementLimit is a count of entries of statement page when limit isn't set
+	defaultStatementLimit = 50
+	// maxStatementLimit is the biggest count of entries of statement page
+	maxStatementLimit = 1000
+)
+
+// LedgerRepository is an interface that contains methods for append-only ledger of balance operations
+type LedgerRepository interface {
+	Append(ctx context.Context, entry *model.LedgerEntry) error
+	GetEntries(ctx context.Context, profileID uuid.UUID) ([]*model.LedgerEntry, error)
+}
+
+// LedgerService records changes of balance in local ledger and builds statements from it.
+// Operations which trading service makes itself, like closing by stop loss, don't pass through APIService
+// and are seen in statement only as difference between recorded balances
+type LedgerService struct {
+	lRep     LedgerRepository
+	balances BalanceSource
+}
+
+// NewLedgerService accepts LedgerRepository and BalanceSource objects and returnes an object of type *LedgerService
+func NewLedgerService(lRep LedgerRepository, balances BalanceSource) *LedgerService {
+	return &LedgerService{lRep: lRep, balances: balances}
+}
+
+// Record is a method of LedgerService that appends operation which was already made to ledger with balance after it
+func (ls *LedgerService) Record(ctx context.Context, id, profileID uuid.UUID, origin string, dealID *uuid.UUID,
+	amount decimal.Decimal) error {
+	money, err := ls.balances.GetBalance(ctx, profileID)
+	if err != nil {
+		return fmt.Errorf("getBalance %w", err)
+	}
+	entry := &model.LedgerEntry{
+		ID:        id,
+		ProfileID: profileID,
+		Time:      time.Now().UTC(),
+		Type:      creditType,
+		Origin:    origin,
+		DealID:    dealID,
+		Amount:    amount,
+		Balance:   money,
+	}
+	if amount.IsNegative() {
+		entry.Type = debitType
+	}
+	if err = ls.lRep.Append(ctx, entry); err != nil {
+		return fmt.Errorf("append %w", err)
+	}
+	return nil
+}
+
+// Track is a method of LedgerService that runs operation of trade and records change of balance which it made.
+// Ledger errors are only logged, because operation is already made when they happen
+func (ls *LedgerService) Track(ctx context.Context, profileID uuid.UUID, origin string, dealID *uuid.UUID, operation func() error) error {
+	before, errBefore := ls.balances.GetBalance(ctx, profileID)
+	if err := operation(); err != nil {
+		return err
+	}
+	if errBefore != nil {
+		logrus.Errorf("ledger: getBalance %v", errBefore)
+		return nil
+	}
+	after, err := ls.balances.GetBalance(ctx, profileID)
+	if err != nil {
+		logrus.Errorf("ledger: getBalance %v", err)
+		return nil
+	}
+	amount := after.Sub(before)
+	if amount.IsZero() {
+		return nil
+	}
+	entry := &model.LedgerEntry{
+		ID:        uuid.New(),
+		ProfileID: profileID,
+		Time:      time.Now().UTC(),
+		Type:      creditType,
+		Origin:    origin,
+		DealID:    dealID,
+		Amount:    amount,
+		Balance:   after,
+	}
+	if amount.IsNegative() {
+		entry.Type = debitType
+	}
+	if err = ls.lRep.Append(ctx, entry); err != nil {
+		logrus.Errorf("ledger: append %v", err)
+	}
+	return nil
+}
+
+// GetStatement is a method of LedgerService that returns page of entries of ledger made in [from, to).
+// Opening balance is balance before the first entry of range and running balance adds amounts of entries to it,
+// so it doesn't depend on page. Zero from or to leaves range open, zero limit means default size of page
+func (ls *LedgerService) GetStatement(ctx context.Context, profileID uuid.UUID, from, to time.Time, limit int,
+	cursor string) (*model.Statement, error) {
+	if limit == 0 {
+		limit = defaultStatementLimit
+	}
+	if limit < 0 || limit > maxStatementLimit {
+		return nil, berrors.New(berrors.InvalidRequest, fmt.Sprintf("Limit must be between 0 and %d", maxStatementLimit))
+	}
+	var after uint64
+	if cursor != "" {
+		var err error
+		if after, err = strconv.ParseUint(cursor, 36, 64); err != nil {
+			return nil, berrors.New(berrors.InvalidRequest, "Invalid cursor")
+		}
+	}
+	entries, err := ls.lRep.GetEntries(ctx, profileID)
+	if err != nil {
+		return nil, fmt.Errorf("getEntries %w", err)
+	}
+	statement := &model.Statement{From: from, To: to, Lines: make([]*model.StatementLine, 0)}
+	opened := false
+	for _, entry := range entries {
+		if !from.IsZero() && entry.Time.Before(from) {
+			statement.OpeningBalance = entry.Balance
+			continue
+		}
+		if !to.IsZero() && !entry.Time.Before(to) {
+			break
+		}
+		if !opened {
+			statement.OpeningBalance = entry.Balance.Sub(entry.Amount)
+			statement.ClosingBalance = statement.OpeningBalance
+			opened = true
+		}
+		statement.ClosingBalance = statement.ClosingBalance.Add(entry.Amount)
+		if entry.Seq <= after {
+			continue
+		}
+		if len(statement.Lines) == limit {
+			statement.NextCursor = strconv.FormatUint(statement.Lines[limit-1].Seq, 36)
+			continue
+		}
+		statement.Lines = append(statement.Lines, &model.StatementLine{LedgerEntry: entry, RunningBalance: statement.ClosingBalance})
+	}
+	if !opened {
+		statement.ClosingBalance = statement.OpeningBalance
+	}
+	return statement, nil
+}
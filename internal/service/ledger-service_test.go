@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/artnikel/APIService/internal/config"
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/artnikel/APIService/internal/repository"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+// walletRepository keeps balance in memory like balance service
+type walletRepository struct {
+	money decimal.Decimal
+}
+
+func (w *walletRepository) BalanceOperation(_ context.Context, balance *model.Balance) (decimal.Decimal, error) {
+	w.money = w.money.Add(balance.Operation)
+	return balance.Operation, nil
+}
+
+func (w *walletRepository) GetBalance(_ context.Context, _ uuid.UUID) (decimal.Decimal, error) {
+	return w.money, nil
+}
+
+func newTestLedger(t *testing.T) (*repository.LedgerRepository, *walletRepository, *LedgerService) {
+	db, err := repository.NewBoltDB(&config.Variables{DataPath: filepath.Join(t.TempDir(), "test.db")})
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, db.Close()) })
+	lRep, err := repository.NewLedgerRepository(db)
+	require.NoError(t, err)
+	wallet := &walletRepository{}
+	return lRep, wallet, NewLedgerService(lRep, wallet)
+}
+
+func TestBalanceOperationRecordsLedger(t *testing.T) {
+	lRep, wallet, ledger := newTestLedger(t)
+	srv := NewBalanceService(wallet, ledger, nil, nil, &config.Variables{})
+	profileID := uuid.New()
+
+	deposit := &model.Balance{ProfileID: profileID, Operation: decimal.NewFromInt(100)}
+	_, err := srv.BalanceOperation(context.Background(), deposit)
+	require.NoError(t, err)
+	require.NotEqual(t, uuid.Nil, deposit.BalanceID)
+	_, err = srv.BalanceOperation(context.Background(), &model.Balance{ProfileID: profileID, Operation: decimal.NewFromInt(-30)})
+	require.NoError(t, err)
+
+	entries, err := lRep.GetEntries(context.Background(), profileID)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, deposit.BalanceID, entries[0].ID)
+	require.Equal(t, creditType, entries[0].Type)
+	require.Equal(t, OriginUser, entries[0].Origin)
+	require.Equal(t, "100", entries[0].Balance.String())
+	require.Equal(t, debitType, entries[1].Type)
+	require.Equal(t, "-30", entries[1].Amount.String())
+	require.Equal(t, "70", entries[1].Balance.String())
+}
+
+func TestLedgerTrack(t *testing.T) {
+	lRep, wallet, ledger := newTestLedger(t)
+	profileID, dealID := uuid.New(), uuid.New()
+	wallet.money = decimal.NewFromInt(100)
+
+	err := ledger.Track(context.Background(), profileID, OriginTradeClose, &dealID, func() error {
+		wallet.money = wallet.money.Add(decimal.RequireFromString("25.5"))
+		return nil
+	})
+	require.NoError(t, err)
+	err = ledger.Track(context.Background(), profileID, OriginTradeOpen, nil, func() error { return nil })
+	require.NoError(t, err)
+
+	entries, err := lRep.GetEntries(context.Background(), profileID)
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "operation which didn't change balance isn't recorded")
+	require.Equal(t, OriginTradeClose, entries[0].Origin)
+	require.Equal(t, dealID, *entries[0].DealID)
+	require.Equal(t, "25.5", entries[0].Amount.String())
+}
+
+func TestGetStatement(t *testing.T) {
+	lRep, _, ledger := newTestLedger(t)
+	profileID := uuid.New()
+	start := time.Date(2023, time.October, 1, 12, 0, 0, 0, time.UTC)
+	balance := decimal.NewFromInt(50)
+	for i, amount := range []int64{100, -20, 30, -10, 5} {
+		balance = balance.Add(decimal.NewFromInt(amount))
+		require.NoError(t, lRep.Append(context.Background(), &model.LedgerEntry{
+			ID: uuid.New(), ProfileID: profileID, Time: start.Add(time.Duration(i) * time.Hour),
+			Origin: OriginUser, Amount: decimal.NewFromInt(amount), Balance: balance,
+		}))
+	}
+
+	statement, err := ledger.GetStatement(context.Background(), profileID, start.Add(time.Hour), start.Add(4*time.Hour), 2, "")
+	require.NoError(t, err)
+	require.Equal(t, "150", statement.OpeningBalance.String())
+	require.Equal(t, "150", statement.ClosingBalance.String())
+	require.Len(t, statement.Lines, 2)
+	require.Equal(t, "130", statement.Lines[0].RunningBalance.String())
+	require.Equal(t, "160", statement.Lines[1].RunningBalance.String())
+	require.NotEmpty(t, statement.NextCursor)
+
+	next, err := ledger.GetStatement(context.Background(), profileID, start.Add(time.Hour), start.Add(4*time.Hour), 2, statement.NextCursor)
+	require.NoError(t, err)
+	require.Len(t, next.Lines, 1)
+	require.Equal(t, "150", next.Lines[0].RunningBalance.String())
+	require.Empty(t, next.NextCursor)
+
+	empty, err := ledger.GetStatement(context.Background(), profileID, start.Add(10*time.Hour), time.Time{}, 0, "")
+	require.NoError(t, err)
+	require.Empty(t, empty.Lines)
+	require.Equal(t, "155", empty.OpeningBalance.String())
+	require.Equal(t, "155", empty.ClosingBalance.String())
+
+	_, err = ledger.GetStatement(context.Background(), profileID, time.Time{}, time.Time{}, 0, "not a cursor")
+	require.Error(t, err)
+}
+
+func TestBalanceOperationRejectsExtraPrecision(t *testing.T) {
+	_, wallet, ledger := newTestLedger(t)
+	srv := NewBalanceService(wallet, ledger, nil, nil, &config.Variables{})
+
+	for _, amount := range []string{"0.001", "10.005", "0", "10000000000000"} {
+		_, err := srv.BalanceOperation(context.Background(), &model.Balance{
+			ProfileID: uuid.New(), Operation: decimal.RequireFromString(amount),
+		})
+		var e *berrors.BusinessError
+		require.ErrorAs(t, err, &e, amount)
+		require.Equal(t, berrors.InvalidRequest, e.Code, amount)
+	}
+	require.True(t, wallet.money.IsZero())
+}
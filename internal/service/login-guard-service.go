@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/artnikel/APIService/internal/config"
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/model"
+)
+
+const (
+	loginSubject = "login"
+	ipSubject    = "ip"
+)
+
+// LoginAttemptRepository is an interface that contains methods for counting log in attempts
+type LoginAttemptRepository interface {
+	AddAttempt(ctx context.Context, key string, window time.Duration) (int, error)
+	RemoveAttempt(ctx context.Context, key string) error
+	Attempts(ctx context.Context, key string) (int, error)
+	Block(ctx context.Context, key string, ttl time.Duration) error
+	BlockedFor(ctx context.Context, key string) (time.Duration, error)
+	Reset(ctx context.Context, key string) error
+	AddLockout(ctx context.Context, lockout *model.LoginLockout) error
+}
+
+// LoginGuardService contains LoginAttemptRepository interface
+type LoginGuardService struct {
+	lRep LoginAttemptRepository
+	cfg  config.Variables
+}
+
+// NewLoginGuardService accepts LoginAttemptRepository object and returnes an object of type *LoginGuardService
+func NewLoginGuardService(lRep LoginAttemptRepository, cfg *config.Variables) *LoginGuardService {
+	return &LoginGuardService{lRep: lRep, cfg: *cfg}
+}
+
+// attemptSubject is a login or ip address whose failed attempts are counted separately
+type attemptSubject struct {
+	kind        string
+	value       string
+	maxAttempts int
+}
+
+// key returns key of subject in the repository
+func (s attemptSubject) key() string {
+	return s.kind + ":" + s.value
+}
+
+// subjects returns subjects of attempt, login is case-insensitive
+func (ls *LoginGuardService) subjects(login, ip string) []attemptSubject {
+	return []attemptSubject{
+		{kind: loginSubject, value: strings.ToLower(login), maxAttempts: ls.cfg.LoginMaxAttempts},
+		{kind: ipSubject, value: ip, maxAttempts: ls.cfg.LoginIPMaxAttempts},
+	}
+}
+
+// Check is a method of LoginGuardService that returns business error if log in by login or from ip is blocked.
+// Otherwise attempt is reserved before credentials are checked, so concurrent attempts can`t exceed the limit.
+// Reserved attempt is kept by Fail and is given back by Succeed or Release
+func (ls *LoginGuardService) Check(ctx context.Context, login, ip string) error {
+	subjects := ls.subjects(login, ip)
+	for _, subject := range subjects {
+		left, err := ls.lRep.BlockedFor(ctx, subject.key())
+		if err != nil {
+			return fmt.Errorf("blockedFor %w", err)
+		}
+		if left > 0 {
+			return berrors.New(berrors.TooManyLoginAttempts,
+				fmt.Sprintf("Too many login attempts, try again in %d seconds", int(math.Ceil(left.Seconds()))))
+		}
+	}
+	for i, subject := range subjects {
+		attempts, err := ls.lRep.AddAttempt(ctx, subject.key(), ls.cfg.LoginAttemptWindow)
+		if err != nil {
+			if errRemove := ls.remove(ctx, subjects[:i]); errRemove != nil {
+				return fmt.Errorf("remove %w", errRemove)
+			}
+			return fmt.Errorf("addAttempt %w", err)
+		}
+		if attempts > subject.maxAttempts {
+			if errRemove := ls.remove(ctx, subjects[:i+1]); errRemove != nil {
+				return fmt.Errorf("remove %w", errRemove)
+			}
+			return berrors.New(berrors.TooManyLoginAttempts, "Too many login attempts, try again later")
+		}
+	}
+	return nil
+}
+
+// Fail is a method of LoginGuardService that keeps attempt reserved by Check as failed and blocks next attempts
+// with exponential backoff. Login or ip is locked out when count of failed attempts reaches the limit,
+// lockout is written to the audit trail
+func (ls *LoginGuardService) Fail(ctx context.Context, login, ip string) error {
+	for _, subject := range ls.subjects(login, ip) {
+		failures, err := ls.lRep.Attempts(ctx, subject.key())
+		if err != nil {
+			return fmt.Errorf("attempts %w", err)
+		}
+		if failures < 1 {
+			failures = 1
+		}
+		if failures < subject.maxAttempts {
+			if err = ls.lRep.Block(ctx, subject.key(), ls.backoff(failures)); err != nil {
+				return fmt.Errorf("block %w", err)
+			}
+			continue
+		}
+		if err = ls.lRep.Block(ctx, subject.key(), ls.cfg.LoginLockoutDuration); err != nil {
+			return fmt.Errorf("block %w", err)
+		}
+		now := time.Now().UTC()
+		err = ls.lRep.AddLockout(ctx, &model.LoginLockout{
+			Subject:     subject.kind,
+			Value:       subject.value,
+			Failures:    failures,
+			LockedAt:    now,
+			LockedUntil: now.Add(ls.cfg.LoginLockoutDuration),
+		})
+		if err != nil {
+			return fmt.Errorf("addLockout %w", err)
+		}
+	}
+	return nil
+}
+
+// Succeed is a method of LoginGuardService that forgets failed attempts of login after successful log in.
+// Failed attempts from ip are kept, so one valid account doesn`t help to guess passwords of other accounts,
+// only attempt reserved by Check is given back
+func (ls *LoginGuardService) Succeed(ctx context.Context, login, ip string) error {
+	subjects := ls.subjects(login, ip)
+	if err := ls.lRep.Reset(ctx, subjects[0].key()); err != nil {
+		return fmt.Errorf("reset %w", err)
+	}
+	if err := ls.remove(ctx, subjects[1:]); err != nil {
+		return fmt.Errorf("remove %w", err)
+	}
+	return nil
+}
+
+// Release is a method of LoginGuardService that gives back attempt reserved by Check
+// when credentials couldn`t be checked, so failures of backends aren`t counted as guesses
+func (ls *LoginGuardService) Release(ctx context.Context, login, ip string) error {
+	if err := ls.remove(ctx, ls.subjects(login, ip)); err != nil {
+		return fmt.Errorf("remove %w", err)
+	}
+	return nil
+}
+
+// remove gives back reserved attempts of subjects
+func (ls *LoginGuardService) remove(ctx context.Context, subjects []attemptSubject) error {
+	for _, subject := range subjects {
+		if err := ls.lRep.RemoveAttempt(ctx, subject.key()); err != nil {
+			return fmt.Errorf("removeAttempt %w", err)
+		}
+	}
+	return nil
+}
+
+// backoff returns time of block after failed attempt, it doubles with every attempt but never exceeds lockout
+func (ls *LoginGuardService) backoff(failures int) time.Duration {
+	delay := ls.cfg.LoginBackoffBase
+	for i := 1; i < failures && delay < ls.cfg.LoginLockoutDuration; i++ {
+		delay *= 2
+	}
+	if delay > ls.cfg.LoginLockoutDuration {
+		return ls.cfg.LoginLockoutDuration
+	}
+	return delay
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/artnikel/APIService/internal/config"
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+var loginGuardCfg = config.Variables{
+	LoginMaxAttempts:     3,
+	LoginIPMaxAttempts:   5,
+	LoginAttemptWindow:   time.Minute,
+	LoginBackoffBase:     10 * time.Millisecond,
+	LoginLockoutDuration: time.Hour,
+}
+
+// failLogin makes failed attempt of log in and waits until backoff after it ends
+func failLogin(ctx context.Context, t *testing.T, srv *LoginGuardService, login, ip string) {
+	require.NoError(t, srv.Check(ctx, login, ip))
+	require.NoError(t, srv.Fail(ctx, login, ip))
+	time.Sleep(srv.backoff(loginGuardCfg.LoginIPMaxAttempts))
+}
+
+func TestLoginGuardBackoffAndLockout(t *testing.T) {
+	rep := repository.NewMemoryLoginAttemptRepository()
+	srv := NewLoginGuardService(rep, &loginGuardCfg)
+	ctx := context.Background()
+
+	require.NoError(t, srv.Check(ctx, "testLogin", "10.0.0.1"))
+	require.NoError(t, srv.Fail(ctx, "testLogin", "10.0.0.1"))
+	err := srv.Check(ctx, "TESTLOGIN", "10.0.0.2")
+	var e *berrors.BusinessError
+	require.ErrorAs(t, err, &e)
+	require.Equal(t, berrors.TooManyLoginAttempts, e.Code)
+	left, err := rep.BlockedFor(ctx, "login:testlogin")
+	require.NoError(t, err)
+	require.LessOrEqual(t, left, loginGuardCfg.LoginBackoffBase)
+	time.Sleep(left + time.Millisecond)
+
+	require.NoError(t, srv.Check(ctx, "testLogin", "10.0.0.1"))
+	require.NoError(t, srv.Fail(ctx, "testLogin", "10.0.0.1"))
+	left, err = rep.BlockedFor(ctx, "login:testlogin")
+	require.NoError(t, err)
+	require.Greater(t, left, loginGuardCfg.LoginBackoffBase)
+	require.Empty(t, rep.Lockouts())
+	time.Sleep(left + time.Millisecond)
+
+	require.NoError(t, srv.Check(ctx, "testLogin", "10.0.0.1"))
+	require.NoError(t, srv.Fail(ctx, "testLogin", "10.0.0.1"))
+	left, err = rep.BlockedFor(ctx, "login:testlogin")
+	require.NoError(t, err)
+	require.Greater(t, left, loginGuardCfg.LoginLockoutDuration-time.Minute)
+	lockouts := rep.Lockouts()
+	require.Len(t, lockouts, 1)
+	require.Equal(t, loginSubject, lockouts[0].Subject)
+	require.Equal(t, "testlogin", lockouts[0].Value)
+	require.Equal(t, 3, lockouts[0].Failures)
+
+	require.NoError(t, srv.Succeed(ctx, "testLogin", "10.0.0.1"))
+	require.NoError(t, srv.Check(ctx, "testLogin", "10.0.0.2"))
+	require.Error(t, srv.Check(ctx, "testLogin", "10.0.0.1"))
+}
+
+func TestLoginGuardLocksOutIP(t *testing.T) {
+	rep := repository.NewMemoryLoginAttemptRepository()
+	srv := NewLoginGuardService(rep, &loginGuardCfg)
+	ctx := context.Background()
+
+	for _, login := range []string{"first", "second", "third", "fourth", "fifth"} {
+		failLogin(ctx, t, srv, login, "10.0.0.1")
+	}
+	lockouts := rep.Lockouts()
+	require.Len(t, lockouts, 1)
+	require.Equal(t, ipSubject, lockouts[0].Subject)
+	require.Equal(t, "10.0.0.1", lockouts[0].Value)
+	require.Error(t, srv.Check(ctx, "sixth", "10.0.0.1"))
+	require.NoError(t, srv.Check(ctx, "sixth", "10.0.0.2"))
+}
+
+func TestLoginGuardConcurrentAttemptsDontExceedLimit(t *testing.T) {
+	rep := repository.NewMemoryLoginAttemptRepository()
+	srv := NewLoginGuardService(rep, &loginGuardCfg)
+	ctx := context.Background()
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		allowed int
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if srv.Check(ctx, "testLogin", "10.0.0.1") == nil {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, loginGuardCfg.LoginMaxAttempts, allowed)
+	attempts, err := rep.Attempts(ctx, "ip:10.0.0.1")
+	require.NoError(t, err)
+	require.Equal(t, loginGuardCfg.LoginMaxAttempts, attempts)
+}
+
+func TestLoginGuardReleaseGivesBackAttempt(t *testing.T) {
+	rep := repository.NewMemoryLoginAttemptRepository()
+	srv := NewLoginGuardService(rep, &loginGuardCfg)
+	ctx := context.Background()
+
+	for i := 0; i < 2*loginGuardCfg.LoginMaxAttempts; i++ {
+		require.NoError(t, srv.Check(ctx, "testLogin", "10.0.0.1"))
+		require.NoError(t, srv.Release(ctx, "testLogin", "10.0.0.1"))
+	}
+	attempts, err := rep.Attempts(ctx, "login:testlogin")
+	require.NoError(t, err)
+	require.Zero(t, attempts)
+}
+
+func TestLoginGuardBackoffNeverExceedsLockout(t *testing.T) {
+	srv := NewLoginGuardService(nil, &loginGuardCfg)
+	require.Equal(t, 10*time.Millisecond, srv.backoff(1))
+	require.Equal(t, 40*time.Millisecond, srv.backoff(3))
+	require.Equal(t, loginGuardCfg.LoginLockoutDuration, srv.backoff(100))
+}
@@ -0,0 +1,379 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/artnikel/APIService/internal/config"
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// OrderLimit is type of order which opens position when price becomes better than the trigger price
+	OrderLimit = "limit"
+	// OrderStop is type of order which opens position when price breaks through the trigger price
+	OrderStop = "stop"
+)
+
+const (
+	// OrderPending is status of order which waits for the trigger price
+	OrderPending = "pending"
+	// OrderTriggered is status of order whose position is being opened
+	OrderTriggered = "triggered"
+	// OrderFilled is status of order which opened position
+	OrderFilled = "filled"
+	// OrderCancelled is status of order cancelled by user
+	OrderCancelled = "cancelled"
+	// OrderExpired is status of order which wasn`t triggered before its expiry
+	OrderExpired = "expired"
+	// OrderFailed is status of order whose position was rejected
+	OrderFailed = "failed"
+)
+
+// OrderRepository is an interface that contains methods for storing entry orders
+type OrderRepository interface {
+	Create(ctx context.Context, order *model.Order) error
+	Update(ctx context.Context, profileID, orderID uuid.UUID, change func(order *model.Order) error) (*model.Order, error)
+	GetOrders(ctx context.Context, profileID uuid.UUID) ([]*model.Order, error)
+	GetOrdersWithStatus(ctx context.Context, statuses ...string) ([]*model.Order, error)
+}
+
+// OrderTrading is an interface that contains methods for opening positions of orders by current prices
+type OrderTrading interface {
+	CreatePosition(ctx context.Context, deal *model.Deal) error
+	GetUnclosedPositions(ctx context.Context, profileid uuid.UUID) ([]*model.Deal, error)
+	GetClosedPositions(ctx context.Context, profileid uuid.UUID) ([]*model.Deal, error)
+	GetPriceSnapshot(ctx context.Context) (*model.PriceSnapshot, error)
+}
+
+// OrderService keeps pending entry orders of users and opens their positions when prices reach trigger prices.
+// Direction of order is inferred as for positions: stop loss above take profit means short, otherwise long
+type OrderService struct {
+	oRep    OrderRepository
+	trading OrderTrading
+	leader  *Leader
+	cfg     config.Variables
+}
+
+// NewOrderService accepts OrderRepository, OrderTrading and Leader objects and returnes an object of type *OrderService,
+// nil leader means that engine runs in every instance
+func NewOrderService(oRep OrderRepository, trading OrderTrading, leader *Leader, cfg *config.Variables) *OrderService {
+	return &OrderService{oRep: oRep, trading: trading, leader: leader, cfg: *cfg}
+}
+
+// PlaceOrder is a method of OrderService that validates order and saves it as pending
+func (ors *OrderService) PlaceOrder(ctx context.Context, order *model.Order) error {
+	now := time.Now().UTC()
+	if err := validateOrder(order, now); err != nil {
+		return err
+	}
+	snapshot, err := ors.trading.GetPriceSnapshot(ctx)
+	if err != nil {
+		return fmt.Errorf("getPriceSnapshot %w", err)
+	}
+	if _, ok := sharePrice(snapshot.Shares, order.Company); !ok {
+		return berrors.New(berrors.UnknownCompany, "There is no such company")
+	}
+	if ors.cfg.MaxPendingOrders > 0 {
+		orders, errOrders := ors.oRep.GetOrders(ctx, order.ProfileID)
+		if errOrders != nil {
+			return fmt.Errorf("getOrders %w", errOrders)
+		}
+		pending := 0
+		for _, placed := range orders {
+			if placed.Status == OrderPending || placed.Status == OrderTriggered {
+				pending++
+			}
+		}
+		if pending >= ors.cfg.MaxPendingOrders {
+			return berrors.New(berrors.TooManyOrders,
+				fmt.Sprintf("No more than %d orders can be pending", ors.cfg.MaxPendingOrders))
+		}
+	}
+	order.OrderID = uuid.New()
+	order.Status = OrderPending
+	order.CreatedAt = now
+	order.DealID = uuid.Nil
+	if err = ors.oRep.Create(ctx, order); err != nil {
+		return fmt.Errorf("create %w", err)
+	}
+	return nil
+}
+
+// GetOrders is a method of OrderService that returns orders of user from the newest
+func (ors *OrderService) GetOrders(ctx context.Context, profileID uuid.UUID) ([]*model.Order, error) {
+	orders, err := ors.oRep.GetOrders(ctx, profileID)
+	if err != nil {
+		return nil, fmt.Errorf("getOrders %w", err)
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].CreatedAt.After(orders[j].CreatedAt)
+	})
+	return orders, nil
+}
+
+// CancelOrder is a method of OrderService that cancels pending order of user
+func (ors *OrderService) CancelOrder(ctx context.Context, profileID, orderID uuid.UUID) (*model.Order, error) {
+	order, err := ors.oRep.Update(ctx, profileID, orderID, func(order *model.Order) error {
+		if order.Status != OrderPending {
+			return berrors.New(berrors.OrderNotPending, "Order is already "+order.Status)
+		}
+		order.Status = OrderCancelled
+		order.ClosedAt = time.Now().UTC()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("update %w", err)
+	}
+	return order, nil
+}
+
+// Run is a method of OrderService that checks pending orders against prices until ctx is canceled.
+// Orders are checked only by the leading instance, orders which were interrupted while their positions
+// were being opened are resolved first when instance becomes the leader
+func (ors *OrderService) Run(ctx context.Context) {
+	ticker := time.NewTicker(ors.cfg.OrderCheckInterval)
+	defer ticker.Stop()
+	recovered := false
+	for {
+		select {
+		case <-ctx.Done():
+			if ors.leader != nil {
+				if err := ors.leader.Resign(context.Background()); err != nil {
+					logrus.Errorf("orderEngine: %v", err)
+				}
+			}
+			return
+		case <-ticker.C:
+			if !ors.lead(ctx) {
+				recovered = false
+				continue
+			}
+			if !recovered {
+				err := ors.Recover(ctx)
+				if err != nil && ctx.Err() == nil {
+					logrus.Errorf("orderEngine: %v", err)
+				}
+				recovered = err == nil
+			}
+			if err := ors.Execute(ctx, time.Now().UTC()); err != nil && ctx.Err() == nil {
+				logrus.Errorf("orderEngine: %v", err)
+			}
+		}
+	}
+}
+
+// lead reports if this instance runs engine now
+func (ors *OrderService) lead(ctx context.Context) bool {
+	if ors.leader == nil {
+		return true
+	}
+	lead, err := ors.leader.Lead(ctx)
+	if err != nil && ctx.Err() == nil {
+		logrus.Errorf("orderEngine: %v", err)
+	}
+	return lead
+}
+
+// Execute is a method of OrderService that expires pending orders and opens positions of triggered ones.
+// Orders aren`t triggered by stale prices, their check is skipped until prices are refreshed
+func (ors *OrderService) Execute(ctx context.Context, now time.Time) error {
+	orders, err := ors.oRep.GetOrdersWithStatus(ctx, OrderPending)
+	if err != nil {
+		return fmt.Errorf("getOrdersWithStatus %w", err)
+	}
+	if len(orders) == 0 {
+		return nil
+	}
+	snapshot, err := ors.trading.GetPriceSnapshot(ctx)
+	if err != nil {
+		return fmt.Errorf("getPriceSnapshot %w", err)
+	}
+	if snapshot.Stale {
+		logrus.Warnf("orderEngine: prices of %s are stale, orders aren't checked", snapshot.UpdatedAt.Format(time.RFC3339))
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].CreatedAt.Before(orders[j].CreatedAt)
+	})
+	for _, order := range orders {
+		if !order.ExpiresAt.IsZero() && !now.Before(order.ExpiresAt) {
+			if errExpire := ors.finish(ctx, order, OrderPending, OrderExpired, "", now); errExpire != nil {
+				logrus.Errorf("orderEngine: %v", errExpire)
+			}
+			continue
+		}
+		if snapshot.Stale {
+			continue
+		}
+		price, ok := sharePrice(snapshot.Shares, order.Company)
+		if !ok || !orderTriggered(order, price) {
+			continue
+		}
+		if errFill := ors.fill(ctx, order); errFill != nil {
+			logrus.Errorf("orderEngine: %v", errFill)
+		}
+	}
+	return nil
+}
+
+// Recover is a method of OrderService that resolves orders left triggered by restart. Order is filled if position
+// with its deal id exists, otherwise it fails, because it isn`t known if the backend got the position
+func (ors *OrderService) Recover(ctx context.Context) error {
+	orders, err := ors.oRep.GetOrdersWithStatus(ctx, OrderTriggered)
+	if err != nil {
+		return fmt.Errorf("getOrdersWithStatus %w", err)
+	}
+	for _, order := range orders {
+		opened, errFind := ors.positionExists(ctx, order)
+		if errFind != nil {
+			return fmt.Errorf("positionExists %w", errFind)
+		}
+		status, reason := OrderFilled, ""
+		if !opened {
+			status, reason = OrderFailed, "Order was interrupted and its position wasn't found"
+		}
+		if err = ors.finish(ctx, order, OrderTriggered, status, reason, time.Now().UTC()); err != nil {
+			return fmt.Errorf("finish %w", err)
+		}
+	}
+	return nil
+}
+
+// fill marks order as triggered and opens its position through TradingService. Order returns to pending
+// if backend is temporarily unavailable, rejected positions fail the order with the reason
+func (ors *OrderService) fill(ctx context.Context, order *model.Order) error {
+	claimed, err := ors.oRep.Update(ctx, order.ProfileID, order.OrderID, func(stored *model.Order) error {
+		if stored.Status != OrderPending {
+			return berrors.New(berrors.OrderNotPending, "Order is already "+stored.Status)
+		}
+		stored.Status = OrderTriggered
+		if stored.DealID == uuid.Nil {
+			stored.DealID = uuid.New()
+		}
+		return nil
+	})
+	if err != nil {
+		var e *berrors.BusinessError
+		if errors.As(err, &e) && e.Code == berrors.OrderNotPending {
+			return nil
+		}
+		return fmt.Errorf("update %w", err)
+	}
+	err = ors.trading.CreatePosition(ctx, &model.Deal{
+		DealID:      claimed.DealID,
+		ProfileID:   claimed.ProfileID,
+		Company:     claimed.Company,
+		SharesCount: claimed.SharesCount,
+		StopLoss:    claimed.StopLoss,
+		TakeProfit:  claimed.TakeProfit,
+	})
+	now := time.Now().UTC()
+	if err == nil {
+		return ors.finish(ctx, claimed, OrderTriggered, OrderFilled, "", now)
+	}
+	_, message, rule := berrors.Translate(err, "Failed to open position")
+	if rule.Retryable {
+		logrus.Warnf("orderEngine: order %s will be retried: %v", claimed.OrderID, err)
+		return ors.finish(ctx, claimed, OrderTriggered, OrderPending, "", time.Time{})
+	}
+	logrus.Infof("orderEngine: order %s failed: %v", claimed.OrderID, err)
+	return ors.finish(ctx, claimed, OrderTriggered, OrderFailed, message, now)
+}
+
+// finish moves order from one status to another if nobody has changed it
+func (ors *OrderService) finish(ctx context.Context, order *model.Order, from, to, reason string, at time.Time) error {
+	_, err := ors.oRep.Update(ctx, order.ProfileID, order.OrderID, func(stored *model.Order) error {
+		if stored.Status != from {
+			return berrors.New(berrors.OrderNotPending, "Order is already "+stored.Status)
+		}
+		stored.Status = to
+		stored.Reason = reason
+		stored.ClosedAt = at
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("update %w", err)
+	}
+	return nil
+}
+
+// positionExists reports if opened or closed positions of user contain deal of order
+func (ors *OrderService) positionExists(ctx context.Context, order *model.Order) (bool, error) {
+	opened, err := ors.trading.GetUnclosedPositions(ctx, order.ProfileID)
+	if err != nil {
+		return false, fmt.Errorf("getUnclosedPositions %w", err)
+	}
+	closed, err := ors.trading.GetClosedPositions(ctx, order.ProfileID)
+	if err != nil {
+		return false, fmt.Errorf("getClosedPositions %w", err)
+	}
+	for _, deal := range append(opened, closed...) {
+		if deal.DealID == order.DealID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// validateOrder checks fields of a new order. Stop loss and take profit must be on the right sides of the trigger
+// price, because position is opened close to it
+func validateOrder(order *model.Order, now time.Time) error {
+	if order.Type != OrderLimit && order.Type != OrderStop {
+		return berrors.New(berrors.InvalidRequest, "Type of order must be limit or stop")
+	}
+	if !order.SharesCount.IsPositive() || !order.TriggerPrice.IsPositive() ||
+		!order.StopLoss.IsPositive() || !order.TakeProfit.IsPositive() {
+		return berrors.New(berrors.InvalidRequest, "Shares count, trigger price, stop loss and take profit must be positive")
+	}
+	if !model.FitsPrecision(order.SharesCount, model.QuantityPlaces) {
+		return berrors.New(berrors.InvalidRequest,
+			fmt.Sprintf("Shares count can't have more than %d decimal places", model.QuantityPlaces))
+	}
+	if !model.FitsPrecision(order.TriggerPrice, model.PricePlaces) || !model.FitsPrecision(order.StopLoss, model.PricePlaces) ||
+		!model.FitsPrecision(order.TakeProfit, model.PricePlaces) {
+		return berrors.New(berrors.InvalidRequest,
+			fmt.Sprintf("Prices of order can't have more than %d decimal places", model.PricePlaces))
+	}
+	if !order.ExpiresAt.IsZero() && !order.ExpiresAt.After(now) {
+		return berrors.New(berrors.InvalidRequest, "Expiry of order must be in the future")
+	}
+	trigger := order.TriggerPrice.StringFixed(model.PricePlaces)
+	switch {
+	case order.StopLoss.Equal(order.TakeProfit):
+		return berrors.New(berrors.InvalidRequest, "Stop loss and take profit can't be equal")
+	case orderLong(order) && !order.StopLoss.LessThan(order.TriggerPrice):
+		return berrors.New(berrors.StopLossWrongSide, "Stop loss of long order must be below the trigger price "+trigger)
+	case orderLong(order) && !order.TakeProfit.GreaterThan(order.TriggerPrice):
+		return berrors.New(berrors.TakeProfitWrongSide, "Take profit of long order must be above the trigger price "+trigger)
+	case !orderLong(order) && !order.StopLoss.GreaterThan(order.TriggerPrice):
+		return berrors.New(berrors.StopLossWrongSide, "Stop loss of short order must be above the trigger price "+trigger)
+	case !orderLong(order) && !order.TakeProfit.LessThan(order.TriggerPrice):
+		return berrors.New(berrors.TakeProfitWrongSide, "Take profit of short order must be below the trigger price "+trigger)
+	}
+	return nil
+}
+
+// orderLong reports if order opens long position
+func orderLong(order *model.Order) bool {
+	return order.StopLoss.LessThan(order.TakeProfit)
+}
+
+// orderTriggered reports if price reached trigger price of order. Limit order waits for price which is
+// better for its direction, stop order waits for price which breaks through the trigger price
+func orderTriggered(order *model.Order, price decimal.Decimal) bool {
+	buyBelow := order.Type == OrderLimit
+	if !orderLong(order) {
+		buyBelow = !buyBelow
+	}
+	if buyBelow {
+		return price.LessThanOrEqual(order.TriggerPrice)
+	}
+	return price.GreaterThanOrEqual(order.TriggerPrice)
+}
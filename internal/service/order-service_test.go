@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/artnikel/APIService/internal/config"
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/artnikel/APIService/internal/repository"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+// orderBackend is fake of trading service which opens positions of orders by prices it was given
+type orderBackend struct {
+	prices    map[string]decimal.Decimal
+	stale     bool
+	positions []*model.Deal
+	createErr error
+}
+
+func (b *orderBackend) CreatePosition(_ context.Context, deal *model.Deal) error {
+	if b.createErr != nil {
+		return b.createErr
+	}
+	b.positions = append(b.positions, deal)
+	return nil
+}
+
+func (b *orderBackend) GetUnclosedPositions(_ context.Context, _ uuid.UUID) ([]*model.Deal, error) {
+	return b.positions, nil
+}
+
+func (b *orderBackend) GetClosedPositions(_ context.Context, _ uuid.UUID) ([]*model.Deal, error) {
+	return nil, nil
+}
+
+func (b *orderBackend) GetPriceSnapshot(_ context.Context) (*model.PriceSnapshot, error) {
+	shares := make([]model.Share, 0, len(b.prices))
+	for company, price := range b.prices {
+		shares = append(shares, model.Share{Company: company, Price: price})
+	}
+	return &model.PriceSnapshot{Shares: shares, Stale: b.stale}, nil
+}
+
+func newTestOrders(t *testing.T, path string) (*orderBackend, *OrderService, func()) {
+	db, err := repository.NewBoltDB(&config.Variables{DataPath: path})
+	require.NoError(t, err)
+	oRep, err := repository.NewOrderRepository(db)
+	require.NoError(t, err)
+	backend := &orderBackend{prices: map[string]decimal.Decimal{"Apple": decimal.NewFromInt(200)}}
+	return backend, NewOrderService(oRep, backend, nil, &config.Variables{MaxPendingOrders: 2}), func() {
+		require.NoError(t, db.Close())
+	}
+}
+
+func testOrder(profileID uuid.UUID, orderType string, trigger, stopLoss, takeProfit int64) *model.Order {
+	return &model.Order{
+		ProfileID:    profileID,
+		Type:         orderType,
+		Company:      "Apple",
+		SharesCount:  decimal.NewFromInt(10),
+		TriggerPrice: decimal.NewFromInt(trigger),
+		StopLoss:     decimal.NewFromInt(stopLoss),
+		TakeProfit:   decimal.NewFromInt(takeProfit),
+	}
+}
+
+func TestOrderTriggers(t *testing.T) {
+	testCases := []struct {
+		order     *model.Order
+		triggered []int64
+		waiting   []int64
+	}{
+		{testOrder(uuid.Nil, OrderLimit, 180, 170, 200), []int64{180, 175}, []int64{181}},
+		{testOrder(uuid.Nil, OrderLimit, 220, 230, 200), []int64{220, 225}, []int64{219}},
+		{testOrder(uuid.Nil, OrderStop, 220, 210, 240), []int64{220, 225}, []int64{219}},
+		{testOrder(uuid.Nil, OrderStop, 180, 190, 160), []int64{180, 175}, []int64{181}},
+	}
+	for _, tc := range testCases {
+		for _, price := range tc.triggered {
+			require.True(t, orderTriggered(tc.order, decimal.NewFromInt(price)), "%s at %d", tc.order.Type, price)
+		}
+		for _, price := range tc.waiting {
+			require.False(t, orderTriggered(tc.order, decimal.NewFromInt(price)), "%s at %d", tc.order.Type, price)
+		}
+	}
+}
+
+func TestPlaceOrderValidation(t *testing.T) {
+	_, srv, closeDB := newTestOrders(t, filepath.Join(t.TempDir(), "test.db"))
+	defer closeDB()
+	profileID := uuid.New()
+	expired := testOrder(profileID, OrderLimit, 180, 170, 200)
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	unknown := testOrder(profileID, OrderLimit, 180, 170, 200)
+	unknown.Company = "Aple"
+	testCases := []struct {
+		name  string
+		order *model.Order
+		code  string
+	}{
+		{"market type", testOrder(profileID, "market", 180, 170, 200), berrors.InvalidRequest},
+		{"expired", expired, berrors.InvalidRequest},
+		{"unknown company", unknown, berrors.UnknownCompany},
+		{"stop loss of long above trigger", testOrder(profileID, OrderLimit, 180, 185, 200), berrors.StopLossWrongSide},
+		{"take profit of short above trigger", testOrder(profileID, OrderStop, 180, 190, 185), berrors.TakeProfitWrongSide},
+	}
+	for _, tc := range testCases {
+		err := srv.PlaceOrder(context.Background(), tc.order)
+		var e *berrors.BusinessError
+		require.ErrorAs(t, err, &e, tc.name)
+		require.Equal(t, tc.code, e.Code, tc.name)
+	}
+	require.NoError(t, srv.PlaceOrder(context.Background(), testOrder(profileID, OrderLimit, 180, 170, 200)))
+	require.NoError(t, srv.PlaceOrder(context.Background(), testOrder(profileID, OrderLimit, 180, 170, 200)))
+	err := srv.PlaceOrder(context.Background(), testOrder(profileID, OrderLimit, 180, 170, 200))
+	var e *berrors.BusinessError
+	require.ErrorAs(t, err, &e)
+	require.Equal(t, berrors.TooManyOrders, e.Code)
+}
+
+func TestOrderEngineFillsOrderAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	_, srv, closeDB := newTestOrders(t, path)
+	profileID := uuid.New()
+	order := testOrder(profileID, OrderLimit, 180, 170, 200)
+	require.NoError(t, srv.PlaceOrder(context.Background(), order))
+	closeDB()
+
+	backend, srv, closeDB := newTestOrders(t, path)
+	defer closeDB()
+	require.NoError(t, srv.Execute(context.Background(), time.Now()))
+	require.Empty(t, backend.positions)
+	backend.prices["Apple"] = decimal.NewFromInt(179)
+	require.NoError(t, srv.Execute(context.Background(), time.Now()))
+	require.NoError(t, srv.Execute(context.Background(), time.Now()))
+	require.Len(t, backend.positions, 1)
+
+	orders, err := srv.GetOrders(context.Background(), profileID)
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	require.Equal(t, OrderFilled, orders[0].Status)
+	require.Equal(t, orders[0].DealID, backend.positions[0].DealID)
+	require.Equal(t, "10", backend.positions[0].SharesCount.String())
+	_, err = srv.CancelOrder(context.Background(), profileID, order.OrderID)
+	var e *berrors.BusinessError
+	require.ErrorAs(t, err, &e)
+	require.Equal(t, berrors.OrderNotPending, e.Code)
+}
+
+func TestOrderEngineExpiresAndCancels(t *testing.T) {
+	backend, srv, closeDB := newTestOrders(t, filepath.Join(t.TempDir(), "test.db"))
+	defer closeDB()
+	profileID := uuid.New()
+	expiring := testOrder(profileID, OrderLimit, 180, 170, 200)
+	expiring.ExpiresAt = time.Now().Add(time.Hour)
+	require.NoError(t, srv.PlaceOrder(context.Background(), expiring))
+	cancelled := testOrder(profileID, OrderLimit, 180, 170, 200)
+	require.NoError(t, srv.PlaceOrder(context.Background(), cancelled))
+	order, err := srv.CancelOrder(context.Background(), profileID, cancelled.OrderID)
+	require.NoError(t, err)
+	require.Equal(t, OrderCancelled, order.Status)
+
+	backend.prices["Apple"] = decimal.NewFromInt(170)
+	require.NoError(t, srv.Execute(context.Background(), time.Now().Add(2*time.Hour)))
+	require.Empty(t, backend.positions)
+	orders, err := srv.GetOrders(context.Background(), profileID)
+	require.NoError(t, err)
+	statuses := map[uuid.UUID]string{}
+	for _, order := range orders {
+		statuses[order.OrderID] = order.Status
+	}
+	require.Equal(t, map[uuid.UUID]string{expiring.OrderID: OrderExpired, cancelled.OrderID: OrderCancelled}, statuses)
+}
+
+func TestOrderEngineSkipsStalePrices(t *testing.T) {
+	backend, srv, closeDB := newTestOrders(t, filepath.Join(t.TempDir(), "test.db"))
+	defer closeDB()
+	profileID := uuid.New()
+	order := testOrder(profileID, OrderLimit, 180, 170, 200)
+	require.NoError(t, srv.PlaceOrder(context.Background(), order))
+	expiring := testOrder(profileID, OrderLimit, 180, 170, 200)
+	expiring.ExpiresAt = time.Now().Add(time.Minute)
+	require.NoError(t, srv.PlaceOrder(context.Background(), expiring))
+
+	backend.prices["Apple"] = decimal.NewFromInt(170)
+	backend.stale = true
+	require.NoError(t, srv.Execute(context.Background(), time.Now().Add(time.Hour)))
+	require.Empty(t, backend.positions)
+	orders, err := srv.GetOrders(context.Background(), profileID)
+	require.NoError(t, err)
+	statuses := map[uuid.UUID]string{}
+	for _, stored := range orders {
+		statuses[stored.OrderID] = stored.Status
+	}
+	require.Equal(t, map[uuid.UUID]string{order.OrderID: OrderPending, expiring.OrderID: OrderExpired}, statuses)
+
+	backend.stale = false
+	require.NoError(t, srv.Execute(context.Background(), time.Now()))
+	require.NoError(t, srv.Execute(context.Background(), time.Now()))
+	require.Len(t, backend.positions, 1)
+}
+
+func TestOrderEngineRejectedPositions(t *testing.T) {
+	backend, srv, closeDB := newTestOrders(t, filepath.Join(t.TempDir(), "test.db"))
+	defer closeDB()
+	profileID := uuid.New()
+	order := testOrder(profileID, OrderStop, 190, 180, 220)
+	require.NoError(t, srv.PlaceOrder(context.Background(), order))
+
+	backend.createErr = berrors.New(berrors.Unavailable, "Service is temporarily unavailable")
+	require.NoError(t, srv.Execute(context.Background(), time.Now()))
+	orders, err := srv.GetOrders(context.Background(), profileID)
+	require.NoError(t, err)
+	require.Equal(t, OrderPending, orders[0].Status)
+	dealID := orders[0].DealID
+	require.NotEqual(t, uuid.Nil, dealID)
+
+	backend.createErr = berrors.New(berrors.NotEnoughMoney, "Not enough money")
+	require.NoError(t, srv.Execute(context.Background(), time.Now()))
+	orders, err = srv.GetOrders(context.Background(), profileID)
+	require.NoError(t, err)
+	require.Equal(t, OrderFailed, orders[0].Status)
+	require.Equal(t, "Not enough money", orders[0].Reason)
+	require.Equal(t, dealID, orders[0].DealID)
+}
+
+func TestOrderRecover(t *testing.T) {
+	db, err := repository.NewBoltDB(&config.Variables{DataPath: filepath.Join(t.TempDir(), "test.db")})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, db.Close()) }()
+	oRep, err := repository.NewOrderRepository(db)
+	require.NoError(t, err)
+	profileID := uuid.New()
+	opened := &model.Order{OrderID: uuid.New(), ProfileID: profileID, Status: OrderTriggered, DealID: uuid.New()}
+	lost := &model.Order{OrderID: uuid.New(), ProfileID: profileID, Status: OrderTriggered, DealID: uuid.New()}
+	require.NoError(t, oRep.Create(context.Background(), opened))
+	require.NoError(t, oRep.Create(context.Background(), lost))
+	backend := &orderBackend{positions: []*model.Deal{{DealID: opened.DealID}}}
+	srv := NewOrderService(oRep, backend, nil, &config.Variables{})
+
+	require.NoError(t, srv.Recover(context.Background()))
+	orders, err := srv.GetOrders(context.Background(), profileID)
+	require.NoError(t, err)
+	statuses := map[uuid.UUID]string{}
+	for _, order := range orders {
+		statuses[order.OrderID] = order.Status
+	}
+	require.Equal(t, map[uuid.UUID]string{opened.OrderID: OrderFilled, lost.OrderID: OrderFailed}, statuses)
+}
+
+func TestOrderEngineRunsOnlyInLeader(t *testing.T) {
+	db, err := repository.NewBoltDB(&config.Variables{DataPath: filepath.Join(t.TempDir(), "test.db")})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, db.Close()) }()
+	oRep, err := repository.NewOrderRepository(db)
+	require.NoError(t, err)
+	locks := repository.NewMemoryLockRepository()
+	leader := NewLeader(locks, "orders", time.Minute)
+	lead, err := leader.Lead(context.Background())
+	require.NoError(t, err)
+	require.True(t, lead)
+	backend := &orderBackend{prices: map[string]decimal.Decimal{"Apple": decimal.NewFromInt(170)}}
+	srv := NewOrderService(oRep, backend, NewLeader(locks, "orders", time.Minute),
+		&config.Variables{OrderCheckInterval: 5 * time.Millisecond})
+	profileID := uuid.New()
+	order := testOrder(profileID, OrderLimit, 180, 160, 200)
+	require.NoError(t, srv.PlaceOrder(context.Background(), order))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		srv.Run(ctx)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	orders, err := srv.GetOrders(context.Background(), profileID)
+	require.NoError(t, err)
+	require.Equal(t, OrderPending, orders[0].Status)
+
+	require.NoError(t, leader.Resign(context.Background()))
+	require.Eventually(t, func() bool {
+		filled, errOrders := srv.GetOrders(context.Background(), profileID)
+		return errOrders == nil && filled[0].Status == OrderFilled
+	}, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+	require.Len(t, backend.positions, 1)
+	lead, err = leader.Lead(context.Background())
+	require.NoError(t, err)
+	require.True(t, lead, "engine resigns when it stops")
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	long  = "long"
+	short = "short"
+	// percentPlaces is a count of decimal places of percents in portfolio
+	percentPlaces = 2
+)
+
+// nolint gochecknoglobals
+var hundred = decimal.NewFromInt(100)
+
+// PositionSource is an interface that contains methods for getting open positions and cached prices
+type PositionSource interface {
+	GetUnclosedPositions(ctx context.Context, profileid uuid.UUID) ([]*model.Deal, error)
+	GetPriceSnapshot(ctx context.Context) (*model.PriceSnapshot, error)
+}
+
+// BalanceSource is an interface that contains method for getting balance of user
+type BalanceSource interface {
+	GetBalance(ctx context.Context, profileid uuid.UUID) (decimal.Decimal, error)
+}
+
+// PortfolioService values open positions of user with current prices
+type PortfolioService struct {
+	positions PositionSource
+	balance   BalanceSource
+}
+
+// NewPortfolioService accepts PositionSource and BalanceSource objects and returnes an object of type *PortfolioService
+func NewPortfolioService(positions PositionSource, balance BalanceSource) *PortfolioService {
+	return &PortfolioService{positions: positions, balance: balance}
+}
+
+// GetPortfolio is a method of PortfolioService that returns open positions with unrealized profit, totals per company
+// and equity. Trading service takes cost of position from balance when position is opened and returns cost with profit
+// when it is closed, so equity is balance with cost and unrealized profit of all positions
+func (ps *PortfolioService) GetPortfolio(ctx context.Context, profileid uuid.UUID) (*model.Portfolio, error) {
+	deals, err := ps.positions.GetUnclosedPositions(ctx, profileid)
+	if err != nil {
+		return nil, fmt.Errorf("getUnclosedPositions %w", err)
+	}
+	snapshot, err := ps.positions.GetPriceSnapshot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getPriceSnapshot %w", err)
+	}
+	money, err := ps.balance.GetBalance(ctx, profileid)
+	if err != nil {
+		return nil, fmt.Errorf("getBalance %w", err)
+	}
+	prices := make(map[string]decimal.Decimal, len(snapshot.Shares))
+	for _, share := range snapshot.Shares {
+		prices[share.Company] = share.Price
+	}
+	portfolio := &model.Portfolio{
+		Balance:   money,
+		Positions: make([]*model.Position, 0, len(deals)),
+		Companies: make([]*model.CompanyExposure, 0),
+		PricesAt:  snapshot.UpdatedAt,
+		Stale:     snapshot.Stale,
+	}
+	companies := make(map[string]*model.CompanyExposure)
+	for _, deal := range deals {
+		price, ok := prices[deal.Company]
+		if !ok {
+			return nil, berrors.New(berrors.Unavailable, fmt.Sprintf("Price of %s is unavailable", deal.Company))
+		}
+		position := valuePosition(deal, price)
+		portfolio.Positions = append(portfolio.Positions, position)
+		portfolio.Cost = portfolio.Cost.Add(position.Cost)
+		portfolio.MarketValue = portfolio.MarketValue.Add(position.MarketValue)
+		portfolio.UnrealizedPnL = portfolio.UnrealizedPnL.Add(position.UnrealizedPnL)
+		company, ok := companies[deal.Company]
+		if !ok {
+			company = &model.CompanyExposure{Company: deal.Company}
+			companies[deal.Company] = company
+			portfolio.Companies = append(portfolio.Companies, company)
+		}
+		company.Positions++
+		company.Cost = company.Cost.Add(position.Cost)
+		company.MarketValue = company.MarketValue.Add(position.MarketValue)
+		company.UnrealizedPnL = company.UnrealizedPnL.Add(position.UnrealizedPnL)
+	}
+	for _, company := range portfolio.Companies {
+		company.UnrealizedPnLPercent = percentOf(company.UnrealizedPnL, company.Cost)
+	}
+	sort.Slice(portfolio.Companies, func(i, j int) bool {
+		return portfolio.Companies[i].Company < portfolio.Companies[j].Company
+	})
+	portfolio.Equity = portfolio.Balance.Add(portfolio.Cost).Add(portfolio.UnrealizedPnL)
+	return portfolio, nil
+}
+
+// valuePosition calculates profit of deal and distances to its limits with current price of share.
+// Distance to limit is positive until price reaches it
+func valuePosition(deal *model.Deal, price decimal.Decimal) *model.Position {
+	position := &model.Position{
+		DealID:        deal.DealID,
+		Company:       deal.Company,
+		Direction:     dealDirection(deal),
+		SharesCount:   deal.SharesCount,
+		PurchasePrice: deal.PurchasePrice,
+		CurrentPrice:  price,
+		Cost:          deal.PurchasePrice.Mul(deal.SharesCount),
+		MarketValue:   price.Mul(deal.SharesCount),
+		StopLoss:      deal.StopLoss,
+		TakeProfit:    deal.TakeProfit,
+		DealTime:      deal.DealTime,
+	}
+	if position.Direction == short {
+		position.UnrealizedPnL = position.Cost.Sub(position.MarketValue)
+		position.StopLossDistance = percentOf(deal.StopLoss.Sub(price), price)
+		position.TakeProfitDistance = percentOf(price.Sub(deal.TakeProfit), price)
+	} else {
+		position.UnrealizedPnL = position.MarketValue.Sub(position.Cost)
+		position.StopLossDistance = percentOf(price.Sub(deal.StopLoss), price)
+		position.TakeProfitDistance = percentOf(deal.TakeProfit.Sub(price), price)
+	}
+	position.UnrealizedPnLPercent = percentOf(position.UnrealizedPnL, position.Cost)
+	return position
+}
+
+// dealDirection returns strategy of deal, stop loss above take profit means short
+func dealDirection(deal *model.Deal) string {
+	if deal.StopLoss.Cmp(deal.TakeProfit) == 1 {
+		return short
+	}
+	return long
+}
+
+// percentOf returns value in percent of base, zero base gives zero
+func percentOf(value, base decimal.Decimal) decimal.Decimal {
+	if base.IsZero() {
+		return decimal.Zero
+	}
+	return value.Mul(hundred).DivRound(base, percentPlaces)
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+// portfolioSource returns fixed positions, prices and balance
+type portfolioSource struct {
+	deals  []*model.Deal
+	shares []model.Share
+	money  decimal.Decimal
+}
+
+func (s *portfolioSource) GetUnclosedPositions(_ context.Context, _ uuid.UUID) ([]*model.Deal, error) {
+	return s.deals, nil
+}
+
+func (s *portfolioSource) GetPriceSnapshot(_ context.Context) (*model.PriceSnapshot, error) {
+	return &model.PriceSnapshot{Shares: s.shares, UpdatedAt: time.Date(2023, time.October, 1, 12, 0, 0, 0, time.UTC)}, nil
+}
+
+func (s *portfolioSource) GetBalance(_ context.Context, _ uuid.UUID) (decimal.Decimal, error) {
+	return s.money, nil
+}
+
+func TestGetPortfolio(t *testing.T) {
+	source := &portfolioSource{
+		deals: []*model.Deal{
+			{
+				DealID: uuid.New(), Company: "Apple", SharesCount: decimal.NewFromInt(2), PurchasePrice: decimal.NewFromInt(100),
+				StopLoss: decimal.NewFromInt(90), TakeProfit: decimal.NewFromInt(150),
+			},
+			{
+				DealID: uuid.New(), Company: "Apple", SharesCount: decimal.NewFromInt(1), PurchasePrice: decimal.NewFromInt(120),
+				StopLoss: decimal.NewFromInt(132), TakeProfit: decimal.NewFromInt(100),
+			},
+			{
+				DealID: uuid.New(), Company: "Tesla", SharesCount: decimal.NewFromInt(4), PurchasePrice: decimal.NewFromInt(50),
+				StopLoss: decimal.NewFromInt(40), TakeProfit: decimal.NewFromInt(60),
+			},
+		},
+		shares: []model.Share{{Company: "Tesla", Price: decimal.NewFromInt(45)}, {Company: "Apple", Price: decimal.NewFromInt(110)}},
+		money:  decimal.NewFromInt(500),
+	}
+	srv := NewPortfolioService(source, source)
+	portfolio, err := srv.GetPortfolio(context.Background(), uuid.New())
+	require.NoError(t, err)
+
+	require.Len(t, portfolio.Positions, 3)
+	longApple, shortApple := portfolio.Positions[0], portfolio.Positions[1]
+	require.Equal(t, long, longApple.Direction)
+	require.Equal(t, "20", longApple.UnrealizedPnL.String())
+	require.Equal(t, "10", longApple.UnrealizedPnLPercent.String())
+	require.Equal(t, "220", longApple.MarketValue.String())
+	require.Equal(t, "18.18", longApple.StopLossDistance.String())
+	require.Equal(t, "36.36", longApple.TakeProfitDistance.String())
+	require.Equal(t, short, shortApple.Direction)
+	require.Equal(t, "10", shortApple.UnrealizedPnL.String())
+	require.Equal(t, "8.33", shortApple.UnrealizedPnLPercent.String())
+	require.Equal(t, "20", shortApple.StopLossDistance.String())
+	require.Equal(t, "9.09", shortApple.TakeProfitDistance.String())
+
+	require.Len(t, portfolio.Companies, 2)
+	require.Equal(t, "Apple", portfolio.Companies[0].Company)
+	require.Equal(t, 2, portfolio.Companies[0].Positions)
+	require.Equal(t, "30", portfolio.Companies[0].UnrealizedPnL.String())
+	require.Equal(t, "Tesla", portfolio.Companies[1].Company)
+	require.Equal(t, "-20", portfolio.Companies[1].UnrealizedPnL.String())
+	require.Equal(t, "-10", portfolio.Companies[1].UnrealizedPnLPercent.String())
+
+	require.Equal(t, "520", portfolio.Cost.String())
+	require.Equal(t, "10", portfolio.UnrealizedPnL.String())
+	require.Equal(t, "1030", portfolio.Equity.String())
+}
+
+func TestGetPortfolioWithoutPrice(t *testing.T) {
+	source := &portfolioSource{
+		deals: []*model.Deal{{Company: "Apple", SharesCount: decimal.NewFromInt(1), PurchasePrice: decimal.NewFromInt(100)}},
+	}
+	_, err := NewPortfolioService(source, source).GetPortfolio(context.Background(), uuid.New())
+	var businessErr *berrors.BusinessError
+	require.ErrorAs(t, err, &businessErr)
+	require.Equal(t, berrors.Unavailable, businessErr.Code)
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/artnikel/APIService/internal/config"
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+)
+
+// etagLength is a count of bytes of hash of prices in ETag
+const etagLength = 16
+
+// PriceCache keeps snapshot of prices for TTL from config. Concurrent misses are coalesced into one request
+// to the source, snapshot which is older than TTL but younger than TTL plus stale TTL is returned at once
+// while it is refreshed in background, so errors of the source are hidden until stale TTL ends
+type PriceCache struct {
+	source     PriceSource
+	cfg        config.Variables
+	group      singleflight.Group
+	mu         sync.RWMutex
+	snapshot   *model.PriceSnapshot
+	fetchedAt  time.Time
+	refreshing atomic.Bool
+}
+
+// NewPriceCache accepts PriceSource object and returnes an object of type *PriceCache
+func NewPriceCache(source PriceSource, cfg *config.Variables) *PriceCache {
+	return &PriceCache{source: source, cfg: *cfg}
+}
+
+// Snapshot is a method of PriceCache that returns cached prices, getting them from the source when it is needed
+func (pc *PriceCache) Snapshot(ctx context.Context) (*model.PriceSnapshot, error) {
+	pc.mu.RLock()
+	snapshot, age := pc.snapshot, time.Since(pc.fetchedAt)
+	pc.mu.RUnlock()
+	if snapshot != nil && age < pc.cfg.PriceCacheTTL {
+		return snapshot, nil
+	}
+	if snapshot != nil && age < pc.cfg.PriceCacheTTL+pc.cfg.PriceCacheStaleTTL {
+		if pc.refreshing.CompareAndSwap(false, true) {
+			go func() {
+				defer pc.refreshing.Store(false)
+				if _, err := pc.refresh(); err != nil {
+					logrus.Errorf("priceCache: %v", err)
+				}
+			}()
+		}
+		stale := *snapshot
+		stale.Stale = true
+		return &stale, nil
+	}
+	return pc.wait(ctx)
+}
+
+// FreshSnapshot is a method of PriceCache that returns prices younger than TTL, stale snapshot is refreshed at once.
+// It is used for decisions which mustn`t be made by outdated prices, so failure of refresh is reported as Unavailable
+func (pc *PriceCache) FreshSnapshot(ctx context.Context) (*model.PriceSnapshot, error) {
+	snapshot, err := pc.Snapshot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot %w", err)
+	}
+	if !snapshot.Stale {
+		return snapshot, nil
+	}
+	snapshot, err = pc.wait(ctx)
+	if err != nil {
+		logrus.Errorf("priceCache: %v", err)
+		return nil, berrors.New(berrors.Unavailable, "Prices are out of date, try again later")
+	}
+	return snapshot, nil
+}
+
+// wait gets prices from the source together with other goroutines which need them until context is done
+func (pc *PriceCache) wait(ctx context.Context) (*model.PriceSnapshot, error) {
+	result := pc.group.DoChan("prices", func() (interface{}, error) {
+		return pc.fetch()
+	})
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("snapshot %w", ctx.Err())
+	case res := <-result:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*model.PriceSnapshot), nil
+	}
+}
+
+// refresh gets prices from the source unless another goroutine already does it
+func (pc *PriceCache) refresh() (*model.PriceSnapshot, error) {
+	snapshot, err, _ := pc.group.Do("prices", func() (interface{}, error) {
+		return pc.fetch()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.(*model.PriceSnapshot), nil
+}
+
+// fetch gets prices from the source and saves them as a new snapshot. Context of request isn`t used,
+// so a client which goes away doesn`t cancel the request which other clients wait for
+func (pc *PriceCache) fetch() (*model.PriceSnapshot, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), pc.cfg.PriceCacheFetchTimeout)
+	defer cancel()
+	shares, err := pc.source.GetPrices(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getPrices %w", err)
+	}
+	data, err := json.Marshal(shares)
+	if err != nil {
+		return nil, fmt.Errorf("marshal %w", err)
+	}
+	sum := sha256.Sum256(data)
+	now := time.Now().UTC()
+	snapshot := &model.PriceSnapshot{
+		Shares:    shares,
+		ETag:      `"` + hex.EncodeToString(sum[:etagLength]) + `"`,
+		UpdatedAt: now.Truncate(time.Second),
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if pc.snapshot != nil && pc.snapshot.ETag == snapshot.ETag {
+		snapshot.UpdatedAt = pc.snapshot.UpdatedAt
+	}
+	pc.snapshot = snapshot
+	pc.fetchedAt = now
+	return snapshot, nil
+}
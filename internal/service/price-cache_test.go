@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/artnikel/APIService/internal/config"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+var priceCacheCfg = config.Variables{
+	PriceCacheTTL:          50 * time.Millisecond,
+	PriceCacheStaleTTL:     time.Hour,
+	PriceCacheFetchTimeout: time.Second,
+}
+
+// slowSource blocks every call until release is closed and fails while failing is set
+type slowSource struct {
+	calls   atomic.Int64
+	failing atomic.Bool
+	release chan struct{}
+}
+
+func (s *slowSource) GetPrices(_ context.Context) ([]model.Share, error) {
+	s.calls.Add(1)
+	<-s.release
+	if s.failing.Load() {
+		return nil, errors.New("backend is down")
+	}
+	return []model.Share{testShare}, nil
+}
+
+var testShare = model.Share{Company: "Apple", Price: decimal.RequireFromString("195.5")}
+
+func TestPriceCacheCoalescesMisses(t *testing.T) {
+	source := &slowSource{release: make(chan struct{})}
+	cache := NewPriceCache(source, &priceCacheCfg)
+
+	var wg sync.WaitGroup
+	snapshots := make([]*model.PriceSnapshot, 10)
+	errs := make([]error, len(snapshots))
+	for i := range snapshots {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			snapshots[i], errs[i] = cache.Snapshot(context.Background())
+		}(i)
+	}
+	require.Eventually(t, func() bool { return source.calls.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(source.release)
+	wg.Wait()
+
+	require.Equal(t, int64(1), source.calls.Load())
+	for i, snapshot := range snapshots {
+		require.NoError(t, errs[i])
+		require.Equal(t, []model.Share{testShare}, snapshot.Shares)
+		require.Equal(t, snapshots[0].ETag, snapshot.ETag)
+	}
+	_, err := cache.Snapshot(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(1), source.calls.Load(), "fresh snapshot must be served from cache")
+}
+
+func TestPriceCacheServesStaleWhenSourceFails(t *testing.T) {
+	source := &slowSource{release: make(chan struct{})}
+	close(source.release)
+	cache := NewPriceCache(source, &priceCacheCfg)
+
+	fresh, err := cache.Snapshot(context.Background())
+	require.NoError(t, err)
+	require.False(t, fresh.Stale)
+
+	source.failing.Store(true)
+	time.Sleep(priceCacheCfg.PriceCacheTTL)
+	stale, err := cache.Snapshot(context.Background())
+	require.NoError(t, err)
+	require.True(t, stale.Stale)
+	require.Equal(t, fresh.ETag, stale.ETag)
+	require.Equal(t, fresh.UpdatedAt, stale.UpdatedAt)
+	require.Eventually(t, func() bool { return source.calls.Load() == 2 }, time.Second, time.Millisecond)
+
+	cache.mu.Lock()
+	cache.fetchedAt = time.Now().Add(-priceCacheCfg.PriceCacheTTL - priceCacheCfg.PriceCacheStaleTTL)
+	cache.mu.Unlock()
+	_, err = cache.Snapshot(context.Background())
+	require.Error(t, err)
+}
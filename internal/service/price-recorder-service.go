@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/artnikel/APIService/internal/config"
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultCandles is a count of candles which are returned when start of range isn't set
+	defaultCandles = 100
+	// maxCandles is the biggest count of candles which can be requested at once
+	maxCandles = 1000
+)
+
+// candleIntervals contains intervals of candles which can be requested
+var candleIntervals = map[string]time.Duration{ // nolint gochecknoglobals
+	"1m": time.Minute,
+	"5m": 5 * time.Minute,
+	"1h": time.Hour,
+	"1d": 24 * time.Hour,
+}
+
+// PriceHistoryRepository is an interface that contains methods for storing history of prices
+type PriceHistoryRepository interface {
+	AddSamples(ctx context.Context, at time.Time, shares []model.Share) error
+	GetCandles(ctx context.Context, company string, from, to time.Time, barInterval time.Duration) ([]model.Candle, error)
+	Downsample(ctx context.Context, before time.Time, interval time.Duration, expire time.Time) error
+}
+
+// PriceRecorderService samples prices with interval from config and builds OHLC candles from history.
+// Samples older than raw retention are downsampled into bars, bars older than retention are deleted
+type PriceRecorderService struct {
+	source PriceSource
+	pRep   PriceHistoryRepository
+	cfg    config.Variables
+}
+
+// NewPriceRecorderService accepts PriceSource and PriceHistoryRepository objects and returnes an object of type *PriceRecorderService
+func NewPriceRecorderService(source PriceSource, pRep PriceHistoryRepository, cfg *config.Variables) *PriceRecorderService {
+	return &PriceRecorderService{source: source, pRep: pRep, cfg: *cfg}
+}
+
+// Run is a method of PriceRecorderService that records prices and compacts history until ctx is canceled
+func (pr *PriceRecorderService) Run(ctx context.Context) {
+	record := time.NewTicker(pr.cfg.PriceRecordInterval)
+	defer record.Stop()
+	compact := time.NewTicker(pr.cfg.PriceCompactInterval)
+	defer compact.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-record.C:
+			if err := pr.Record(ctx); err != nil && ctx.Err() == nil {
+				logrus.Errorf("priceRecorder: %v", err)
+			}
+		case <-compact.C:
+			if err := pr.Compact(ctx); err != nil && ctx.Err() == nil {
+				logrus.Errorf("priceRecorder: %v", err)
+			}
+		}
+	}
+}
+
+// Record is a method of PriceRecorderService that saves current prices of all shares
+func (pr *PriceRecorderService) Record(ctx context.Context) error {
+	shares, err := pr.source.GetPrices(ctx)
+	if err != nil {
+		return fmt.Errorf("getPrices %w", err)
+	}
+	if err = pr.pRep.AddSamples(ctx, time.Now().UTC(), shares); err != nil {
+		return fmt.Errorf("addSamples %w", err)
+	}
+	return nil
+}
+
+// Compact is a method of PriceRecorderService that downsamples old samples and deletes expired bars
+func (pr *PriceRecorderService) Compact(ctx context.Context) error {
+	now := time.Now().UTC()
+	before := now.Add(-pr.cfg.PriceRawRetention).Truncate(pr.cfg.PriceDownsampleInterval)
+	err := pr.pRep.Downsample(ctx, before, pr.cfg.PriceDownsampleInterval, now.Add(-pr.cfg.PriceRetention))
+	if err != nil {
+		return fmt.Errorf("downsample %w", err)
+	}
+	return nil
+}
+
+// GetCandles is a method of PriceRecorderService that returns OHLC candles of company with given interval in [from, to).
+// Zero to means now, zero from means range of default count of candles
+func (pr *PriceRecorderService) GetCandles(ctx context.Context, company, interval string, from, to time.Time) ([]model.Candle, error) {
+	step, ok := candleIntervals[interval]
+	if !ok {
+		return nil, berrors.New(berrors.InvalidRequest, "Interval must be one of 1m, 5m, 1h, 1d")
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to.Add(-defaultCandles * step)
+	}
+	from, to = from.UTC().Truncate(step), to.UTC()
+	if !from.Before(to) {
+		return nil, berrors.New(berrors.InvalidRequest, "From must be before to")
+	}
+	if to.Sub(from) > maxCandles*step {
+		return nil, berrors.New(berrors.InvalidRequest, fmt.Sprintf("Range can't contain more than %d candles", maxCandles))
+	}
+	history, err := pr.pRep.GetCandles(ctx, company, from, to, pr.cfg.PriceDownsampleInterval)
+	if err != nil {
+		return nil, fmt.Errorf("getCandles %w", err)
+	}
+	return aggregateCandles(history, step), nil
+}
+
+// aggregateCandles merges candles of history into candles with given interval
+func aggregateCandles(history []model.Candle, step time.Duration) []model.Candle {
+	sort.SliceStable(history, func(i, j int) bool { return history[i].Time.Before(history[j].Time) })
+	candles := make([]model.Candle, 0)
+	for _, candle := range history {
+		start := candle.Time.Truncate(step)
+		if len(candles) == 0 || !candles[len(candles)-1].Time.Equal(start) {
+			candle.Time = start
+			candles = append(candles, candle)
+			continue
+		}
+		last := &candles[len(candles)-1]
+		last.High = decimal.Max(last.High, candle.High)
+		last.Low = decimal.Min(last.Low, candle.Low)
+		last.Close = candle.Close
+	}
+	return candles
+}
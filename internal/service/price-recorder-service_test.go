@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artnikel/APIService/internal/config"
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/artnikel/APIService/internal/repository"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
+)
+
+func newTestPriceHistory(t *testing.T) *repository.PriceHistoryRepository {
+	db, err := repository.NewBoltDB(&config.Variables{DataPath: filepath.Join(t.TempDir(), "test.db")})
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, db.Close()) })
+	pRep, err := repository.NewPriceHistoryRepository(db)
+	require.NoError(t, err)
+	return pRep
+}
+
+func TestPriceRecorderGetCandles(t *testing.T) {
+	pRep := newTestPriceHistory(t)
+	srv := NewPriceRecorderService(&staticSource{}, pRep, &config.Variables{})
+	start := time.Date(2023, time.October, 1, 12, 0, 0, 0, time.UTC)
+	for i, price := range []int64{10, 12, 8, 11, 9, 15} {
+		at := start.Add(time.Duration(i) * 2 * time.Minute)
+		require.NoError(t, pRep.AddSamples(context.Background(), at, []model.Share{{Company: "Apple", Price: decimal.NewFromInt(price)}}))
+	}
+
+	candles, err := srv.GetCandles(context.Background(), "Apple", "5m", start, start.Add(15*time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"12:00 10 12 8 8",
+		"12:05 11 11 9 9",
+		"12:10 15 15 15 15",
+	}, candleStrings(candles))
+
+	_, err = srv.GetCandles(context.Background(), "Apple", "2m", start, start.Add(time.Hour))
+	var businessErr *berrors.BusinessError
+	require.ErrorAs(t, err, &businessErr)
+	require.Equal(t, berrors.InvalidRequest, businessErr.Code)
+	_, err = srv.GetCandles(context.Background(), "Apple", "1m", start, start.Add(maxCandles*time.Minute+time.Minute))
+	require.ErrorAs(t, err, &businessErr)
+}
+
+func TestPriceRecorderDownsampleKeepsCandles(t *testing.T) {
+	pRep := newTestPriceHistory(t)
+	srv := NewPriceRecorderService(&staticSource{}, pRep, &config.Variables{})
+	start := time.Date(2023, time.October, 1, 12, 0, 0, 0, time.UTC)
+	for i, price := range []string{"10", "14.25", "7.0001", "11", "13.5"} {
+		at := start.Add(time.Duration(i) * 20 * time.Second)
+		require.NoError(t, pRep.AddSamples(context.Background(), at,
+			[]model.Share{{Company: "Apple", Price: decimal.RequireFromString(price)}}))
+	}
+	before, err := srv.GetCandles(context.Background(), "Apple", "1h", start, start.Add(time.Hour))
+	require.NoError(t, err)
+
+	require.NoError(t, pRep.Downsample(context.Background(), start.Add(time.Minute), time.Minute, start.Add(-time.Hour)))
+	after, err := srv.GetCandles(context.Background(), "Apple", "1h", start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, before, after)
+	require.Equal(t, []string{"12:00 10 14.25 7.0001 13.5"}, candleStrings(after))
+
+	require.NoError(t, pRep.Downsample(context.Background(), start.Add(time.Minute), time.Minute, start.Add(time.Hour)))
+	expired, err := srv.GetCandles(context.Background(), "Apple", "1m", start, start.Add(time.Minute))
+	require.NoError(t, err)
+	require.Empty(t, expired)
+}
+
+func TestPriceRecorderKeepsAllDigitsOfPrices(t *testing.T) {
+	pRep := newTestPriceHistory(t)
+	srv := NewPriceRecorderService(&staticSource{}, pRep, &config.Variables{})
+	start := time.Date(2023, time.October, 1, 12, 0, 0, 0, time.UTC)
+	for i, price := range []string{"12345678901234.5678", "0.0001", "12345678901234.5679"} {
+		at := start.Add(time.Duration(i) * 20 * time.Second)
+		require.NoError(t, pRep.AddSamples(context.Background(), at,
+			[]model.Share{{Company: "Apple", Price: decimal.RequireFromString(price)}}))
+	}
+	want := []string{"12:00 12345678901234.5678 12345678901234.5679 0.0001 12345678901234.5679"}
+	candles, err := srv.GetCandles(context.Background(), "Apple", "1m", start, start.Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, want, candleStrings(candles))
+
+	require.NoError(t, pRep.Downsample(context.Background(), start.Add(time.Minute), time.Minute, start.Add(-time.Hour)))
+	candles, err = srv.GetCandles(context.Background(), "Apple", "1m", start, start.Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, want, candleStrings(candles))
+}
+
+func TestPriceRecorderRecord(t *testing.T) {
+	pRep := newTestPriceHistory(t)
+	srv := NewPriceRecorderService(&staticSource{}, pRep, &config.Variables{})
+	require.NoError(t, srv.Record(context.Background()))
+	candles, err := srv.GetCandles(context.Background(), "Apple", "1m", time.Time{}, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, candles, 1)
+	require.Equal(t, "1", candles[0].Close.String())
+}
+
+func TestPriceRecorderGetCandlesIncludesOverlappingBar(t *testing.T) {
+	pRep := newTestPriceHistory(t)
+	srv := NewPriceRecorderService(&staticSource{}, pRep, &config.Variables{PriceDownsampleInterval: 5 * time.Minute})
+	start := time.Date(2023, time.October, 1, 12, 0, 0, 0, time.UTC)
+	for i, price := range []int64{10, 12, 8, 11, 9} {
+		at := start.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, pRep.AddSamples(context.Background(), at, []model.Share{{Company: "Apple", Price: decimal.NewFromInt(price)}}))
+	}
+	require.NoError(t, pRep.AddSamples(context.Background(), start.Add(5*time.Minute),
+		[]model.Share{{Company: "Apple", Price: decimal.NewFromInt(14)}}))
+	require.NoError(t, pRep.Downsample(context.Background(), start.Add(5*time.Minute), 5*time.Minute, start.Add(-time.Hour)))
+
+	candles, err := srv.GetCandles(context.Background(), "Apple", "1m", start.Add(2*time.Minute), start.Add(6*time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"12:02 10 12 8 9",
+		"12:05 14 14 14 14",
+	}, candleStrings(candles))
+
+	candles, err = srv.GetCandles(context.Background(), "Apple", "1m", start.Add(5*time.Minute), start.Add(6*time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, []string{"12:05 14 14 14 14"}, candleStrings(candles))
+}
+
+func TestPriceRecorderRecordSkipsInvalidCompanies(t *testing.T) {
+	pRep := newTestPriceHistory(t)
+	srv := NewPriceRecorderService(&staticSource{}, pRep, &config.Variables{})
+	at := time.Now().UTC()
+	shares := []model.Share{
+		{Company: "", Price: decimal.NewFromInt(5)},
+		{Company: "Apple", Price: decimal.NewFromInt(10)},
+		{Company: strings.Repeat("x", bbolt.MaxKeySize+1), Price: decimal.NewFromInt(7)},
+		{Company: "Tesla", Price: decimal.NewFromInt(20)},
+	}
+	require.NoError(t, pRep.AddSamples(context.Background(), at, shares))
+	for _, share := range []model.Share{shares[1], shares[3]} {
+		candles, err := srv.GetCandles(context.Background(), share.Company, "1m", time.Time{}, at.Add(time.Minute))
+		require.NoError(t, err)
+		require.Len(t, candles, 1)
+		require.Equal(t, share.Price.String(), candles[0].Close.String())
+	}
+}
+
+// candleStrings formats candles as "time open high low close" for comparison, decimals are compared by value
+func candleStrings(candles []model.Candle) []string {
+	formatted := make([]string, len(candles))
+	for i, candle := range candles {
+		formatted[i] = strings.Join([]string{candle.Time.Format("15:04"), candle.Open.String(), candle.High.String(),
+			candle.Low.String(), candle.Close.String()}, " ")
+	}
+	return formatted
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/artnikel/APIService/internal/config"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/sirupsen/logrus"
+)
+
+// PriceSource is an interface that contains method for getting current prices of shares
+type PriceSource interface {
+	GetPrices(ctx context.Context) ([]model.Share, error)
+}
+
+// PriceStreamService polls prices by one goroutine while there are subscribers and fans every change out to them
+type PriceStreamService struct {
+	source      PriceSource
+	cfg         config.Variables
+	mu          sync.Mutex
+	subscribers map[chan model.PriceEvent]struct{}
+	latest      *model.PriceEvent
+	stopPoller  context.CancelFunc
+}
+
+// NewPriceStreamService accepts PriceSource object and returnes an object of type *PriceStreamService
+func NewPriceStreamService(source PriceSource, cfg *config.Variables) *PriceStreamService {
+	return &PriceStreamService{
+		source:      source,
+		cfg:         *cfg,
+		subscribers: make(map[chan model.PriceEvent]struct{}),
+	}
+}
+
+// Subscribe is a method of PriceStreamService that returns channel of price updates and function for unsubscribing.
+// Subscriber which missed the latest update, judging by lastEventID, gets it at once.
+// Every update contains all prices, so a slow subscriber whose buffer is full loses older updates, not the newest
+func (ps *PriceStreamService) Subscribe(lastEventID uint64) (<-chan model.PriceEvent, func()) {
+	ch := make(chan model.PriceEvent, ps.cfg.PriceStreamBuffer+1)
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.latest != nil && ps.latest.ID != lastEventID {
+		ch <- *ps.latest
+	}
+	ps.subscribers[ch] = struct{}{}
+	if ps.stopPoller == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		ps.stopPoller = cancel
+		go ps.poll(ctx)
+	}
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() { ps.unsubscribe(ch) })
+	}
+}
+
+// unsubscribe removes subscriber and stops poller when there are no subscribers left
+func (ps *PriceStreamService) unsubscribe(ch chan model.PriceEvent) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	delete(ps.subscribers, ch)
+	if len(ps.subscribers) == 0 && ps.stopPoller != nil {
+		ps.stopPoller()
+		ps.stopPoller = nil
+	}
+}
+
+// poll gets prices with interval from config until ctx is canceled
+func (ps *PriceStreamService) poll(ctx context.Context) {
+	ticker := time.NewTicker(ps.cfg.PriceStreamInterval)
+	defer ticker.Stop()
+	for {
+		if err := ps.refresh(ctx); err != nil && ctx.Err() == nil {
+			logrus.Errorf("priceStream: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh gets current prices and publishes them if they have changed
+func (ps *PriceStreamService) refresh(ctx context.Context) error {
+	shares, err := ps.source.GetPrices(ctx)
+	if err != nil {
+		return fmt.Errorf("getPrices %w", err)
+	}
+	ps.publish(shares)
+	return nil
+}
+
+// publish sends update to all subscribers without waiting for slow ones
+func (ps *PriceStreamService) publish(shares []model.Share) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.latest != nil && sameShares(ps.latest.Shares, shares) {
+		return
+	}
+	event := model.PriceEvent{Shares: shares, Time: time.Now().UTC(), ID: 1}
+	if ps.latest != nil {
+		event.ID = ps.latest.ID + 1
+	}
+	ps.latest = &event
+	for ch := range ps.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// buffer of subscriber is full, the oldest update is dropped to free space for the newest
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- event
+		}
+	}
+}
+
+// sameShares reports if both lists have the same companies with equal prices, decimals are compared by value
+// because the same price can have different exponents
+func sameShares(a, b []model.Share) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Company != b[i].Company || !a[i].Price.Equal(b[i].Price) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/artnikel/APIService/internal/config"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+var priceStreamCfg = config.Variables{
+	PriceStreamInterval: 10 * time.Millisecond,
+	PriceStreamBuffer:   1,
+}
+
+// countingSource returns a new price on every call and counts calls
+type countingSource struct {
+	calls atomic.Int64
+}
+
+func (s *countingSource) GetPrices(_ context.Context) ([]model.Share, error) {
+	calls := s.calls.Add(1)
+	return []model.Share{{Company: "Apple", Price: decimal.NewFromInt(int64(calls))}}, nil
+}
+
+// staticSource always returns the same price
+type staticSource struct{}
+
+func (s *staticSource) GetPrices(_ context.Context) ([]model.Share, error) {
+	return []model.Share{{Company: "Apple", Price: decimal.NewFromInt(1)}}, nil
+}
+
+func TestPriceStreamFansOutOnePoller(t *testing.T) {
+	source := &countingSource{}
+	srv := NewPriceStreamService(source, &priceStreamCfg)
+	first, unsubscribeFirst := srv.Subscribe(0)
+	second, unsubscribeSecond := srv.Subscribe(0)
+
+	var firstEvent, secondEvent model.PriceEvent
+	require.Eventually(t, func() bool {
+		select {
+		case firstEvent = <-first:
+		default:
+		}
+		select {
+		case secondEvent = <-second:
+		default:
+		}
+		return firstEvent.ID > 0 && secondEvent.ID > 0
+	}, time.Second, time.Millisecond)
+	require.Equal(t, firstEvent.Shares, secondEvent.Shares)
+
+	unsubscribeFirst()
+	unsubscribeSecond()
+	unsubscribeSecond()
+	time.Sleep(3 * priceStreamCfg.PriceStreamInterval)
+	calls := source.calls.Load()
+	time.Sleep(3 * priceStreamCfg.PriceStreamInterval)
+	require.Equal(t, calls, source.calls.Load(), "poller must stop without subscribers")
+}
+
+func TestPriceStreamSlowSubscriberGetsNewest(t *testing.T) {
+	srv := NewPriceStreamService(&countingSource{}, &priceStreamCfg)
+	slow := make(chan model.PriceEvent, priceStreamCfg.PriceStreamBuffer+1)
+	srv.subscribers[slow] = struct{}{}
+	for price := 1; price <= 5; price++ {
+		srv.publish([]model.Share{{Company: "Apple", Price: decimal.NewFromInt(int64(price))}})
+	}
+	srv.publish([]model.Share{{Company: "Apple", Price: decimal.NewFromInt(5)}})
+
+	require.Len(t, slow, cap(slow))
+	var last model.PriceEvent
+	for len(slow) > 0 {
+		last = <-slow
+	}
+	require.Equal(t, uint64(5), last.ID)
+	require.Equal(t, "5", last.Shares[0].Price.String())
+}
+
+func TestPriceStreamResumesByLastEventID(t *testing.T) {
+	srv := NewPriceStreamService(&staticSource{}, &priceStreamCfg)
+	srv.publish([]model.Share{{Company: "Apple", Price: decimal.NewFromInt(1)}})
+
+	missed, unsubscribeMissed := srv.Subscribe(0)
+	defer unsubscribeMissed()
+	event := <-missed
+	require.Equal(t, uint64(1), event.ID)
+
+	current, unsubscribeCurrent := srv.Subscribe(event.ID)
+	defer unsubscribeCurrent()
+	select {
+	case <-current:
+		t.Fatal("subscriber with the latest event must not get it again")
+	default:
+	}
+}
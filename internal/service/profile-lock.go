@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/artnikel/APIService/internal/config"
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// profileLockPrefix is a prefix of keys of profile locks in LockRepository
+	profileLockPrefix = "profile:"
+	// profileLockPollInterval is how often operation tries to take lock which is held by another operation
+	profileLockPollInterval = 20 * time.Millisecond
+)
+
+// ProfileLock serializes operations which change balance of profile. Lock is kept in LockRepository,
+// so operations are serialized across all instances of APIService when the repository is shared
+type ProfileLock struct {
+	locks LockRepository
+	cfg   config.Variables
+}
+
+// NewProfileLock accepts LockRepository object and returnes an object of type *ProfileLock
+func NewProfileLock(locks LockRepository, cfg *config.Variables) *ProfileLock {
+	return &ProfileLock{locks: locks, cfg: *cfg}
+}
+
+// Do is a method of ProfileLock that runs operation while lock of profile is held. It waits for operation
+// of another caller to finish and returns business error if lock isn`t released in time. Lock is extended while
+// operation runs and ProfileLockTTL only limits how long lock of a crashed instance blocks profile. If lock is lost,
+// context of operation is canceled, so it doesn`t go on unserialized
+func (pl *ProfileLock) Do(ctx context.Context, profileID uuid.UUID, operation func(ctx context.Context) error) error {
+	key := profileLockPrefix + profileID.String()
+	token := uuid.New().String()
+	deadline := time.Now().Add(pl.cfg.ProfileLockWaitTimeout)
+	for {
+		acquired, err := pl.locks.Acquire(ctx, key, token, pl.cfg.ProfileLockTTL)
+		if err != nil {
+			return fmt.Errorf("acquire %w", err)
+		}
+		if acquired {
+			break
+		}
+		if time.Now().After(deadline) {
+			return berrors.New(berrors.ProfileBusy, "Another operation with balance is in progress, try again later")
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("do %w", ctx.Err())
+		case <-time.After(profileLockPollInterval):
+		}
+	}
+	defer func() {
+		// lock is released even if client has gone, otherwise profile is blocked until lock expires
+		if errRelease := pl.locks.Release(context.Background(), key, token); errRelease != nil {
+			logrus.WithField("ProfileId", profileID).Errorf("profileLock: %v", errRelease)
+		}
+	}()
+	lockedCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	var lost bool
+	go func() {
+		defer close(stopped)
+		lost = pl.keep(key, token, stop, cancel)
+	}()
+	err := operation(lockedCtx)
+	close(stop)
+	<-stopped
+	if lost && err != nil {
+		logrus.WithField("ProfileId", profileID).Errorf("profileLock: %v", err)
+		return berrors.New(berrors.Unavailable, "Operation with balance was interrupted, try again later")
+	}
+	return err
+}
+
+// keep extends lock of profile every third of its ttl until stop is closed. When lock is lost or can`t be extended
+// until it expires, operation is canceled and keep returns true
+func (pl *ProfileLock) keep(key, token string, stop <-chan struct{}, cancel context.CancelFunc) bool {
+	ticker := time.NewTicker(pl.cfg.ProfileLockTTL / 3)
+	defer ticker.Stop()
+	extendedAt := time.Now()
+	for {
+		select {
+		case <-stop:
+			return false
+		case <-ticker.C:
+			extended, err := pl.locks.Extend(context.Background(), key, token, pl.cfg.ProfileLockTTL)
+			if err != nil {
+				logrus.Errorf("keep: %v", err)
+				if time.Since(extendedAt) < pl.cfg.ProfileLockTTL {
+					continue
+				}
+			}
+			if !extended {
+				logrus.Errorf("keep: lock %s is lost", key)
+				cancel()
+				return true
+			}
+			extendedAt = time.Now()
+		}
+	}
+}
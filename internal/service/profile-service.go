@@ -50,7 +50,8 @@ func (us *UserService) SignUp(ctx context.Context, user *model.User) error {
 	return nil
 }
 
-// GetByLogin is a method of UserService that getting password and id, then checked password hash, generating tokens and added refresh token to database.
+// GetByLogin is a method of UserService that getting password and id, then checked password hash.
+// Tokens for the user are issued by TokenService after successful check.
 func (us *UserService) GetByLogin(ctx context.Context, user *model.User) (uuid.UUID, error) {
 	hash, id, err := us.uRep.GetByLogin(ctx, user.Login)
 	user.ID = id
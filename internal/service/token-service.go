@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/artnikel/APIService/internal/config"
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+)
+
+const (
+	accessTokenType  = "access"
+	refreshTokenType = "refresh"
+)
+
+// TokenRepository is an interface that contains methods for storing refresh tokens and revoked access tokens
+type TokenRepository interface {
+	AddRefreshToken(ctx context.Context, tokenID string, profileID uuid.UUID, ttl time.Duration) error
+	GetRefreshToken(ctx context.Context, tokenID string) (uuid.UUID, error)
+	DeleteRefreshToken(ctx context.Context, tokenID string) (bool, error)
+	RevokeAccessToken(ctx context.Context, tokenID string, ttl time.Duration) error
+	IsAccessTokenRevoked(ctx context.Context, tokenID string) (bool, error)
+}
+
+// tokenClaims is a payload of access and refresh tokens
+type tokenClaims struct {
+	jwt.StandardClaims
+	Type string `json:"typ"`
+}
+
+// TokenService contains TokenRepository interface
+type TokenService struct {
+	tRep TokenRepository
+	cfg  config.Variables
+}
+
+// NewTokenService accepts TokenRepository object and returnes an object of type *TokenService
+func NewTokenService(tRep TokenRepository, cfg *config.Variables) *TokenService {
+	return &TokenService{tRep: tRep, cfg: *cfg}
+}
+
+// errInvalidToken returns business error for every token which can`t be accepted
+func errInvalidToken() error {
+	return berrors.New(berrors.Unauthorized, "Invalid or expired token")
+}
+
+// GenerateTokens is a method of TokenService that signs a new pair of tokens and saves refresh token
+func (ts *TokenService) GenerateTokens(ctx context.Context, profileID uuid.UUID) (*model.TokenPair, error) {
+	now := time.Now().UTC()
+	accessExpiresAt := now.Add(ts.cfg.AccessTokenTTL)
+	accessToken, err := ts.sign(profileID, uuid.NewString(), accessTokenType, now, accessExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("sign %w", err)
+	}
+	refreshID := uuid.NewString()
+	refreshToken, err := ts.sign(profileID, refreshID, refreshTokenType, now, now.Add(ts.cfg.RefreshTokenTTL))
+	if err != nil {
+		return nil, fmt.Errorf("sign %w", err)
+	}
+	err = ts.tRep.AddRefreshToken(ctx, refreshID, profileID, ts.cfg.RefreshTokenTTL)
+	if err != nil {
+		return nil, fmt.Errorf("addRefreshToken %w", err)
+	}
+	return &model.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    accessExpiresAt,
+	}, nil
+}
+
+// Refresh is a method of TokenService that exchanges refresh token to a new pair of tokens.
+// Every refresh token can be used only once.
+func (ts *TokenService) Refresh(ctx context.Context, refreshToken string) (*model.TokenPair, error) {
+	claims, err := ts.parse(refreshToken, refreshTokenType)
+	if err != nil {
+		return nil, fmt.Errorf("parse %w", err)
+	}
+	profileID, err := ts.tRep.GetRefreshToken(ctx, claims.Id)
+	if err != nil {
+		return nil, fmt.Errorf("getRefreshToken %w", err)
+	}
+	if profileID.String() != claims.Subject {
+		return nil, errInvalidToken()
+	}
+	deleted, err := ts.tRep.DeleteRefreshToken(ctx, claims.Id)
+	if err != nil {
+		return nil, fmt.Errorf("deleteRefreshToken %w", err)
+	}
+	if !deleted {
+		return nil, errInvalidToken()
+	}
+	tokens, err := ts.GenerateTokens(ctx, profileID)
+	if err != nil {
+		return nil, fmt.Errorf("generateTokens %w", err)
+	}
+	return tokens, nil
+}
+
+// ParseAccessToken is a method of TokenService that checks access token and returns id of its profile
+func (ts *TokenService) ParseAccessToken(ctx context.Context, accessToken string) (uuid.UUID, error) {
+	claims, err := ts.parse(accessToken, accessTokenType)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("parse %w", err)
+	}
+	revoked, err := ts.tRep.IsAccessTokenRevoked(ctx, claims.Id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("isAccessTokenRevoked %w", err)
+	}
+	if revoked {
+		return uuid.Nil, errInvalidToken()
+	}
+	profileID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, errInvalidToken()
+	}
+	return profileID, nil
+}
+
+// Revoke is a method of TokenService that puts access token to the revocation list and deletes refresh token
+func (ts *TokenService) Revoke(ctx context.Context, accessToken, refreshToken string) error {
+	if accessToken != "" {
+		claims, err := ts.parse(accessToken, accessTokenType)
+		if err != nil {
+			return fmt.Errorf("parse %w", err)
+		}
+		ttl := time.Until(time.Unix(claims.ExpiresAt, 0))
+		if ttl > 0 {
+			if err = ts.tRep.RevokeAccessToken(ctx, claims.Id, ttl); err != nil {
+				return fmt.Errorf("revokeAccessToken %w", err)
+			}
+		}
+	}
+	if refreshToken != "" {
+		claims, err := ts.parse(refreshToken, refreshTokenType)
+		if err != nil {
+			return fmt.Errorf("parse %w", err)
+		}
+		if _, err = ts.tRep.DeleteRefreshToken(ctx, claims.Id); err != nil {
+			return fmt.Errorf("deleteRefreshToken %w", err)
+		}
+	}
+	return nil
+}
+
+// sign creates token of the given type signed by HMAC key from config
+func (ts *TokenService) sign(profileID uuid.UUID, tokenID, tokenType string, issuedAt, expiresAt time.Time) (string, error) {
+	if ts.cfg.TokenSignKey == "" {
+		return "", errors.New("token sign key is not configured")
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, tokenClaims{
+		StandardClaims: jwt.StandardClaims{
+			Id:        tokenID,
+			Subject:   profileID.String(),
+			IssuedAt:  issuedAt.Unix(),
+			ExpiresAt: expiresAt.Unix(),
+		},
+		Type: tokenType,
+	})
+	signed, err := token.SignedString([]byte(ts.cfg.TokenSignKey))
+	if err != nil {
+		return "", fmt.Errorf("signedString %w", err)
+	}
+	return signed, nil
+}
+
+// parse checks signature, expiration time and type of token and returns its claims
+func (ts *TokenService) parse(tokenString, tokenType string) (*tokenClaims, error) {
+	claims := &tokenClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		if ts.cfg.TokenSignKey == "" {
+			return nil, errors.New("token sign key is not configured")
+		}
+		return []byte(ts.cfg.TokenSignKey), nil
+	})
+	if err != nil || !token.Valid || claims.Type != tokenType || claims.Id == "" {
+		return nil, errInvalidToken()
+	}
+	return claims, nil
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/artnikel/APIService/internal/config"
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/service/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var tokenCfg = config.Variables{
+	TokenSignKey:    "testSignKey",
+	AccessTokenTTL:  time.Minute,
+	RefreshTokenTTL: time.Hour,
+}
+
+func TestGenerateAndParseTokens(t *testing.T) {
+	rep := new(mocks.TokenRepository)
+	srv := NewTokenService(rep, &tokenCfg)
+	profileID := uuid.New()
+	rep.On("AddRefreshToken", mock.Anything, mock.AnythingOfType("string"), profileID, tokenCfg.RefreshTokenTTL).Return(nil).Once()
+	rep.On("IsAccessTokenRevoked", mock.Anything, mock.AnythingOfType("string")).Return(false, nil).Once()
+
+	tokens, err := srv.GenerateTokens(context.Background(), profileID)
+	require.NoError(t, err)
+	parsedID, err := srv.ParseAccessToken(context.Background(), tokens.AccessToken)
+	require.NoError(t, err)
+	require.Equal(t, profileID, parsedID)
+
+	_, err = srv.ParseAccessToken(context.Background(), tokens.RefreshToken)
+	var e *berrors.BusinessError
+	require.ErrorAs(t, err, &e)
+	require.Equal(t, berrors.Unauthorized, e.Code)
+	rep.AssertExpectations(t)
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	rep := new(mocks.TokenRepository)
+	srv := NewTokenService(rep, &tokenCfg)
+	profileID := uuid.New()
+	rep.On("AddRefreshToken", mock.Anything, mock.AnythingOfType("string"), profileID, tokenCfg.RefreshTokenTTL).Return(nil).Twice()
+	rep.On("GetRefreshToken", mock.Anything, mock.AnythingOfType("string")).Return(profileID, nil).Twice()
+	rep.On("DeleteRefreshToken", mock.Anything, mock.AnythingOfType("string")).Return(true, nil).Once()
+	rep.On("DeleteRefreshToken", mock.Anything, mock.AnythingOfType("string")).Return(false, nil).Once()
+
+	tokens, err := srv.GenerateTokens(context.Background(), profileID)
+	require.NoError(t, err)
+	refreshed, err := srv.Refresh(context.Background(), tokens.RefreshToken)
+	require.NoError(t, err)
+	require.NotEqual(t, tokens.RefreshToken, refreshed.RefreshToken)
+
+	_, err = srv.Refresh(context.Background(), tokens.RefreshToken)
+	require.Error(t, err)
+	rep.AssertExpectations(t)
+}
+
+func TestRevokedAccessToken(t *testing.T) {
+	rep := new(mocks.TokenRepository)
+	srv := NewTokenService(rep, &tokenCfg)
+	profileID := uuid.New()
+	rep.On("AddRefreshToken", mock.Anything, mock.AnythingOfType("string"), profileID, tokenCfg.RefreshTokenTTL).Return(nil).Once()
+	rep.On("RevokeAccessToken", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("time.Duration")).Return(nil).Once()
+	rep.On("DeleteRefreshToken", mock.Anything, mock.AnythingOfType("string")).Return(true, nil).Once()
+	rep.On("IsAccessTokenRevoked", mock.Anything, mock.AnythingOfType("string")).Return(true, nil).Once()
+
+	tokens, err := srv.GenerateTokens(context.Background(), profileID)
+	require.NoError(t, err)
+	err = srv.Revoke(context.Background(), tokens.AccessToken, tokens.RefreshToken)
+	require.NoError(t, err)
+	_, err = srv.ParseAccessToken(context.Background(), tokens.AccessToken)
+	require.Error(t, err)
+	rep.AssertExpectations(t)
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/shopspring/decimal"
+)
+
+// checkRisk is a method of TradingService that returns business error with code of the first failed check of deal.
+// Direction of deal is inferred as in handlers: stop loss above take profit means short, otherwise long.
+// Cost of position is counted by the current price from the cache, which is refreshed if it is stale,
+// opened positions count by purchase price
+func (ts *TradingService) checkRisk(ctx context.Context, deal *model.Deal) error {
+	if !deal.SharesCount.IsPositive() || !deal.StopLoss.IsPositive() || !deal.TakeProfit.IsPositive() {
+		return berrors.New(berrors.InvalidRequest, "Shares count, stop loss and take profit must be positive")
+	}
+	if deal.StopLoss.Equal(deal.TakeProfit) {
+		return berrors.New(berrors.InvalidRequest, "Stop loss and take profit can't be equal")
+	}
+	snapshot, err := ts.prices.FreshSnapshot(ctx)
+	if err != nil {
+		return fmt.Errorf("freshSnapshot %w", err)
+	}
+	price, ok := sharePrice(snapshot.Shares, deal.Company)
+	if !ok {
+		return berrors.New(berrors.UnknownCompany, "There is no such company")
+	}
+	current := price.StringFixed(model.PricePlaces)
+	if deal.StopLoss.GreaterThan(deal.TakeProfit) {
+		if !deal.StopLoss.GreaterThan(price) {
+			return berrors.New(berrors.StopLossWrongSide, "Stop loss of short position must be above the price "+current)
+		}
+		if !deal.TakeProfit.LessThan(price) {
+			return berrors.New(berrors.TakeProfitWrongSide, "Take profit of short position must be below the price "+current)
+		}
+	} else {
+		if !deal.StopLoss.LessThan(price) {
+			return berrors.New(berrors.StopLossWrongSide, "Stop loss of long position must be below the price "+current)
+		}
+		if !deal.TakeProfit.GreaterThan(price) {
+			return berrors.New(berrors.TakeProfitWrongSide, "Take profit of long position must be above the price "+current)
+		}
+	}
+	notional := deal.SharesCount.Mul(price)
+	if ts.cfg.MaxPositionNotional.IsPositive() && notional.GreaterThan(ts.cfg.MaxPositionNotional) {
+		return berrors.New(berrors.PositionSizeLimit, "Position can't cost more than "+
+			ts.cfg.MaxPositionNotional.StringFixed(model.USD.Places)+"$, this one costs "+
+			model.USD.Round(notional).StringFixed(model.USD.Places)+"$")
+	}
+	if ts.cfg.MaxCompanyExposure.IsPositive() {
+		opened, errOpened := ts.tRep.GetUnclosedPositions(ctx, deal.ProfileID)
+		if errOpened != nil {
+			return fmt.Errorf("getUnclosedPositions %w", errOpened)
+		}
+		exposure := notional
+		for _, openedDeal := range opened {
+			if openedDeal.Company == deal.Company {
+				exposure = exposure.Add(openedDeal.SharesCount.Mul(openedDeal.PurchasePrice))
+			}
+		}
+		if exposure.GreaterThan(ts.cfg.MaxCompanyExposure) {
+			return berrors.New(berrors.CompanyExposureLimit, "Positions of one company can't cost more than "+
+				ts.cfg.MaxCompanyExposure.StringFixed(model.USD.Places)+"$ in total")
+		}
+	}
+	if ts.balances == nil {
+		return nil
+	}
+	money, err := ts.balances.GetBalance(ctx, deal.ProfileID)
+	if err != nil {
+		return fmt.Errorf("getBalance %w", err)
+	}
+	if money.LessThan(notional) {
+		return berrors.New(berrors.NotEnoughMoney, "Position costs "+model.USD.Round(notional).StringFixed(model.USD.Places)+
+			"$, but only "+money.StringFixed(model.USD.Places)+"$ is available")
+	}
+	return nil
+}
+
+// sharePrice returns price of company from the list of shares
+func sharePrice(shares []model.Share, company string) (decimal.Decimal, bool) {
+	for _, share := range shares {
+		if share.Company == company {
+			return share.Price, true
+		}
+	}
+	return decimal.Zero, false
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/artnikel/APIService/internal/config"
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+var tradeRiskCfg = config.Variables{
+	PriceCacheTTL:          time.Minute,
+	PriceCacheFetchTimeout: time.Second,
+	MaxPositionNotional:    decimal.NewFromInt(1000),
+	MaxCompanyExposure:     decimal.NewFromInt(1500),
+}
+
+// tradingBackend is fake of trading backend which counts created positions
+type tradingBackend struct {
+	TradingRepository
+	opened    []*model.Deal
+	created   int
+	prices    []model.Share
+	pricesErr error
+}
+
+func (b *tradingBackend) CreatePosition(_ context.Context, _ *model.Deal) error {
+	b.created++
+	return nil
+}
+
+func (b *tradingBackend) GetUnclosedPositions(_ context.Context, _ uuid.UUID) ([]*model.Deal, error) {
+	return b.opened, nil
+}
+
+func (b *tradingBackend) GetPrices(_ context.Context) ([]model.Share, error) {
+	if b.pricesErr != nil {
+		return nil, b.pricesErr
+	}
+	if b.prices != nil {
+		return b.prices, nil
+	}
+	return []model.Share{{Company: "Apple", Price: decimal.NewFromInt(100)}, {Company: "Tesla", Price: decimal.NewFromInt(200)}}, nil
+}
+
+func TestCreatePositionRiskChecks(t *testing.T) {
+	testCases := []struct {
+		name                     string
+		company                  string
+		shares, stopLoss, profit string
+		code                     string
+	}{
+		{"long", "Apple", "5", "90", "120", ""},
+		{"short", "Apple", "5", "110", "80", ""},
+		{"unknown company", "Aple", "5", "90", "120", berrors.UnknownCompany},
+		{"negative shares", "Apple", "-5", "90", "120", berrors.InvalidRequest},
+		{"equal stop loss and take profit", "Apple", "5", "90", "90", berrors.InvalidRequest},
+		{"stop loss of long above price", "Apple", "5", "101", "120", berrors.StopLossWrongSide},
+		{"take profit of long below price", "Apple", "5", "50", "99", berrors.TakeProfitWrongSide},
+		{"stop loss of short below price", "Apple", "5", "99", "80", berrors.StopLossWrongSide},
+		{"take profit of short above price", "Apple", "5", "150", "100", berrors.TakeProfitWrongSide},
+		{"position size", "Apple", "10.01", "90", "120", berrors.PositionSizeLimit},
+		{"company exposure", "Tesla", "4", "190", "220", berrors.CompanyExposureLimit},
+		{"balance", "Apple", "8", "90", "120", berrors.NotEnoughMoney},
+	}
+	for _, tc := range testCases {
+		backend := &tradingBackend{opened: []*model.Deal{
+			{Company: "Tesla", SharesCount: decimal.NewFromInt(5), PurchasePrice: decimal.NewFromInt(150)},
+			{Company: "Apple", SharesCount: decimal.NewFromInt(5), PurchasePrice: decimal.NewFromInt(100)},
+		}}
+		wallet := &walletRepository{money: decimal.NewFromInt(700)}
+		srv := NewTradingService(backend, nil, nil, wallet, &tradeRiskCfg)
+		deal := &model.Deal{
+			ProfileID:   uuid.New(),
+			Company:     tc.company,
+			SharesCount: decimal.RequireFromString(tc.shares),
+			StopLoss:    decimal.RequireFromString(tc.stopLoss),
+			TakeProfit:  decimal.RequireFromString(tc.profit),
+		}
+		err := srv.CreatePosition(context.Background(), deal)
+		if tc.code == "" {
+			require.NoError(t, err, tc.name)
+			require.Equal(t, 1, backend.created, tc.name)
+			continue
+		}
+		var e *berrors.BusinessError
+		require.ErrorAs(t, err, &e, tc.name)
+		require.Equal(t, tc.code, e.Code, tc.name)
+		require.Zero(t, backend.created, tc.name)
+	}
+}
+
+func TestCreatePositionRefreshesStalePrices(t *testing.T) {
+	cfg := tradeRiskCfg
+	cfg.PriceCacheStaleTTL = time.Hour
+	backend := &tradingBackend{}
+	srv := NewTradingService(backend, nil, nil, &walletRepository{money: decimal.NewFromInt(1000)}, &cfg)
+	_, err := srv.GetPrices(context.Background())
+	require.NoError(t, err)
+	srv.prices.fetchedAt = time.Now().Add(-2 * cfg.PriceCacheTTL)
+	backend.prices = []model.Share{{Company: "Apple", Price: decimal.NewFromInt(150)}}
+
+	deal := &model.Deal{
+		ProfileID:   uuid.New(),
+		Company:     "Apple",
+		SharesCount: decimal.NewFromInt(2),
+		StopLoss:    decimal.NewFromInt(140),
+		TakeProfit:  decimal.NewFromInt(160),
+	}
+	require.NoError(t, srv.CreatePosition(context.Background(), deal))
+	require.Equal(t, 1, backend.created)
+}
+
+func TestCreatePositionRejectsStalePrices(t *testing.T) {
+	cfg := tradeRiskCfg
+	cfg.PriceCacheStaleTTL = time.Hour
+	backend := &tradingBackend{}
+	srv := NewTradingService(backend, nil, nil, &walletRepository{money: decimal.NewFromInt(1000)}, &cfg)
+	_, err := srv.GetPrices(context.Background())
+	require.NoError(t, err)
+	srv.prices.fetchedAt = time.Now().Add(-2 * cfg.PriceCacheTTL)
+	backend.pricesErr = errors.New("trading service is unavailable")
+
+	snapshot, err := srv.GetPriceSnapshot(context.Background())
+	require.NoError(t, err)
+	require.True(t, snapshot.Stale)
+	deal := &model.Deal{
+		ProfileID:   uuid.New(),
+		Company:     "Apple",
+		SharesCount: decimal.NewFromInt(2),
+		StopLoss:    decimal.NewFromInt(90),
+		TakeProfit:  decimal.NewFromInt(120),
+	}
+	err = srv.CreatePosition(context.Background(), deal)
+	var e *berrors.BusinessError
+	require.ErrorAs(t, err, &e)
+	require.Equal(t, berrors.Unavailable, e.Code)
+	require.Zero(t, backend.created)
+}
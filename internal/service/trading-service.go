@@ -4,14 +4,17 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/artnikel/APIService/internal/config"
+	berrors "github.com/artnikel/APIService/internal/errors"
 	"github.com/artnikel/APIService/internal/model"
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 )
 
 // TradingRepository is an interface that contains methods for long or short strategies
 type TradingRepository interface {
 	CreatePosition(ctx context.Context, deal *model.Deal) error
-	ClosePositionManually(ctx context.Context, dealid, profileid uuid.UUID) (float64, error)
+	ClosePositionManually(ctx context.Context, dealid, profileid uuid.UUID) (decimal.Decimal, error)
 	GetUnclosedPositions(ctx context.Context, profileid uuid.UUID) ([]*model.Deal, error)
 	GetClosedPositions(ctx context.Context, profileid uuid.UUID) ([]*model.Deal, error)
 	GetPrices(ctx context.Context) ([]model.Share, error)
@@ -19,17 +22,44 @@ type TradingRepository interface {
 
 // TradingService contains BalanceRepository interface
 type TradingService struct {
-	tRep TradingRepository
+	tRep     TradingRepository
+	ledger   *LedgerService
+	lock     *ProfileLock
+	prices   *PriceCache
+	balances BalanceSource
+	cfg      config.Variables
 }
 
-// NewTradingService accepts TradingRepository object and returnes an object of type *TradingService
-func NewTradingService(tRep TradingRepository) *TradingService {
-	return &TradingService{tRep: tRep}
+// NewTradingService accepts TradingRepository, LedgerService, ProfileLock and BalanceSource objects and returnes
+// an object of type *TradingService, nil ledger disables recording of payments of trades, nil lock disables
+// their serialization with other operations with balance and nil balances disables check of available money
+func NewTradingService(tRep TradingRepository, ledger *LedgerService, lock *ProfileLock, balances BalanceSource,
+	cfg *config.Variables) *TradingService {
+	return &TradingService{tRep: tRep, ledger: ledger, lock: lock, prices: NewPriceCache(tRep, cfg), balances: balances, cfg: *cfg}
 }
 
-// CreatePosition is a method of TradingService calls method of Repository
+// CreatePosition is a method of TradingService calls method of Repository. Count of shares and prices of deal
+// must fit their precision, they are never rounded silently. Deal passes risk checks under lock of profile,
+// so concurrent positions can`t spend the same money
 func (ts *TradingService) CreatePosition(ctx context.Context, deal *model.Deal) error {
-	err := ts.tRep.CreatePosition(ctx, deal)
+	if !model.FitsPrecision(deal.SharesCount, model.QuantityPlaces) {
+		return berrors.New(berrors.InvalidRequest,
+			fmt.Sprintf("Shares count can't have more than %d decimal places", model.QuantityPlaces))
+	}
+	if !model.FitsPrecision(deal.StopLoss, model.PricePlaces) || !model.FitsPrecision(deal.TakeProfit, model.PricePlaces) {
+		return berrors.New(berrors.InvalidRequest,
+			fmt.Sprintf("Stop loss and take profit can't have more than %d decimal places", model.PricePlaces))
+	}
+	var dealID *uuid.UUID
+	if deal.DealID != uuid.Nil {
+		dealID = &deal.DealID
+	}
+	err := ts.track(ctx, deal.ProfileID, OriginTradeOpen, dealID, func(ctx context.Context) error {
+		if errRisk := ts.checkRisk(ctx, deal); errRisk != nil {
+			return errRisk
+		}
+		return ts.tRep.CreatePosition(ctx, deal)
+	})
 	if err != nil {
 		return fmt.Errorf("createPosition %w", err)
 	}
@@ -37,10 +67,15 @@ func (ts *TradingService) CreatePosition(ctx context.Context, deal *model.Deal)
 }
 
 // ClosePositionManually is a method of TradingService calls method of Repository
-func (ts *TradingService) ClosePositionManually(ctx context.Context, dealid, profileid uuid.UUID) (float64, error) {
-	profit, err := ts.tRep.ClosePositionManually(ctx, dealid, profileid)
+func (ts *TradingService) ClosePositionManually(ctx context.Context, dealid, profileid uuid.UUID) (decimal.Decimal, error) {
+	var profit decimal.Decimal
+	err := ts.track(ctx, profileid, OriginTradeClose, &dealid, func(ctx context.Context) error {
+		var errClose error
+		profit, errClose = ts.tRep.ClosePositionManually(ctx, dealid, profileid)
+		return errClose
+	})
 	if err != nil {
-		return 0, fmt.Errorf("closePositionManually %w", err)
+		return decimal.Zero, fmt.Errorf("closePositionManually %w", err)
 	}
 	return profit, nil
 }
@@ -63,11 +98,56 @@ func (ts *TradingService) GetClosedPositions(ctx context.Context, profileid uuid
 	return closedDeals, nil
 }
 
-// GetPrices is a method of TradingService calls method of Repository
+// FindUnclosedPositions is a method of TradingService that returns page of opened positions which match filter
+func (ts *TradingService) FindUnclosedPositions(ctx context.Context, profileid uuid.UUID, filter *model.DealFilter) (*model.DealPage, error) {
+	deals, err := ts.GetUnclosedPositions(ctx, profileid)
+	if err != nil {
+		return nil, fmt.Errorf("findUnclosedPositions %w", err)
+	}
+	return queryDeals(deals, filter)
+}
+
+// FindClosedPositions is a method of TradingService that returns page of closed positions which match filter
+func (ts *TradingService) FindClosedPositions(ctx context.Context, profileid uuid.UUID, filter *model.DealFilter) (*model.DealPage, error) {
+	deals, err := ts.GetClosedPositions(ctx, profileid)
+	if err != nil {
+		return nil, fmt.Errorf("findClosedPositions %w", err)
+	}
+	return queryDeals(deals, filter)
+}
+
+// GetPrices is a method of TradingService that returns prices from the cache
 func (ts *TradingService) GetPrices(ctx context.Context) ([]model.Share, error) {
-	shares, err := ts.tRep.GetPrices(ctx)
+	snapshot, err := ts.prices.Snapshot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot %w", err)
+	}
+	return snapshot.Shares, nil
+}
+
+// GetPriceSnapshot is a method of TradingService that returns prices from the cache with their validators
+func (ts *TradingService) GetPriceSnapshot(ctx context.Context) (*model.PriceSnapshot, error) {
+	snapshot, err := ts.prices.Snapshot(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("getPrices %w", err)
+		return nil, fmt.Errorf("snapshot %w", err)
+	}
+	return snapshot, nil
+}
+
+// track runs operation of trade under lock of profile and records change of balance which it made in ledger,
+// lock keeps other operations with balance from getting into the change
+func (ts *TradingService) track(ctx context.Context, profileID uuid.UUID, origin string, dealID *uuid.UUID,
+	operation func(ctx context.Context) error) error {
+	tracked := operation
+	if ts.ledger != nil {
+		tracked = func(ctx context.Context) error {
+			return ts.ledger.Track(ctx, profileID, origin, dealID, func() error {
+				return operation(ctx)
+			})
+		}
+	}
+	if ts.lock == nil {
+		return tracked(ctx)
 	}
-	return shares, nil
+	return ts.lock.Do(ctx, profileID, tracked)
 }
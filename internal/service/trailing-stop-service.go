@@ -0,0 +1,382 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/artnikel/APIService/internal/config"
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// TrailingAbsolute is type of trailing stop whose distance is set in dollars
+	TrailingAbsolute = "absolute"
+	// TrailingPercent is type of trailing stop whose distance is set in percents of the best price
+	TrailingPercent = "percent"
+)
+
+const (
+	// TrailingActive is status of trailing stop which follows price
+	TrailingActive = "active"
+	// TrailingClosing is status of trailing stop which was breached and is closing its position
+	TrailingClosing = "closing"
+	// TrailingClosed is status of trailing stop whose position is closed
+	TrailingClosed = "closed"
+	// TrailingCancelled is status of trailing stop removed by user
+	TrailingCancelled = "cancelled"
+	// TrailingFailed is status of trailing stop whose position couldn`t be closed
+	TrailingFailed = "failed"
+)
+
+// TrailingStopRepository is an interface that contains methods for storing trailing stops of positions
+type TrailingStopRepository interface {
+	Save(ctx context.Context, profileID, dealID uuid.UUID, change func(stop *model.TrailingStop) error) (*model.TrailingStop, error)
+	GetTrailingStops(ctx context.Context, profileID uuid.UUID) ([]*model.TrailingStop, error)
+	GetTrailingStopsWithStatus(ctx context.Context, statuses ...string) ([]*model.TrailingStop, error)
+}
+
+// TrailingTrading is an interface that contains methods for closing positions by current prices
+type TrailingTrading interface {
+	ClosePositionManually(ctx context.Context, dealid, profileid uuid.UUID) (decimal.Decimal, error)
+	GetUnclosedPositions(ctx context.Context, profileid uuid.UUID) ([]*model.Deal, error)
+	GetPriceSnapshot(ctx context.Context) (*model.PriceSnapshot, error)
+}
+
+// TrailingStopService attaches trailing stops to open positions and closes positions when price falls back
+// from the best price by the distance of stop. Fixed stop loss of position keeps working on the backend,
+// so effective stop is the one of them which is closer to price
+type TrailingStopService struct {
+	tsRep   TrailingStopRepository
+	trading TrailingTrading
+	leader  *Leader
+	cfg     config.Variables
+}
+
+// NewTrailingStopService accepts TrailingStopRepository, TrailingTrading and Leader objects and returnes an object
+// of type *TrailingStopService, nil leader means that monitor runs in every instance
+func NewTrailingStopService(tsRep TrailingStopRepository, trading TrailingTrading, leader *Leader,
+	cfg *config.Variables) *TrailingStopService {
+	return &TrailingStopService{tsRep: tsRep, trading: trading, leader: leader, cfg: *cfg}
+}
+
+// Attach is a method of TrailingStopService that attaches stop to open position of user or changes distance
+// of its active stop. The best price starts from the current one and is kept when distance is changed,
+// stop isn`t attached while prices are stale
+func (tss *TrailingStopService) Attach(ctx context.Context, stop *model.TrailingStop) error {
+	if stop.Type != TrailingAbsolute && stop.Type != TrailingPercent {
+		return berrors.New(berrors.InvalidRequest, "Type of trailing stop must be absolute or percent")
+	}
+	if !stop.Distance.IsPositive() || !model.FitsPrecision(stop.Distance, model.PricePlaces) {
+		return berrors.New(berrors.InvalidRequest,
+			fmt.Sprintf("Distance must be positive and can't have more than %d decimal places", model.PricePlaces))
+	}
+	if stop.Type == TrailingPercent && !stop.Distance.LessThan(decimal.NewFromInt(100)) {
+		return berrors.New(berrors.InvalidRequest, "Distance in percents must be less than 100")
+	}
+	deals, err := tss.trading.GetUnclosedPositions(ctx, stop.ProfileID)
+	if err != nil {
+		return fmt.Errorf("getUnclosedPositions %w", err)
+	}
+	deal := findDeal(deals, stop.DealID)
+	if deal == nil {
+		return berrors.New(berrors.NotFound, "There is no open position "+stop.DealID.String())
+	}
+	snapshot, err := tss.trading.GetPriceSnapshot(ctx)
+	if err != nil {
+		return fmt.Errorf("getPriceSnapshot %w", err)
+	}
+	if snapshot.Stale {
+		return berrors.New(berrors.Unavailable, "Prices are out of date, try again later")
+	}
+	price, ok := sharePrice(snapshot.Shares, deal.Company)
+	if !ok {
+		return berrors.New(berrors.UnknownCompany, "There is no price of shares of the position")
+	}
+	saved, err := tss.tsRep.Save(ctx, stop.ProfileID, stop.DealID, func(stored *model.TrailingStop) error {
+		if stored.Status == TrailingClosing {
+			return berrors.New(berrors.TrailingStopClosing, "Trailing stop is already closing the position")
+		}
+		if stored.Status != TrailingActive {
+			stored.BestPrice = price
+		}
+		stored.Company = deal.Company
+		stored.Direction = "long"
+		if deal.StopLoss.GreaterThan(deal.TakeProfit) {
+			stored.Direction = "short"
+		}
+		stored.Type = stop.Type
+		stored.Distance = stop.Distance
+		stored.StopLoss = deal.StopLoss
+		stored.Status = TrailingActive
+		stored.ClosedAt = time.Time{}
+		stored.Profit = decimal.Zero
+		stored.Reason = ""
+		follow(stored, price, time.Now().UTC())
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("save %w", err)
+	}
+	*stop = *saved
+	return nil
+}
+
+// Detach is a method of TrailingStopService that removes active stop from position of user
+func (tss *TrailingStopService) Detach(ctx context.Context, profileID, dealID uuid.UUID) (*model.TrailingStop, error) {
+	stop, err := tss.tsRep.Save(ctx, profileID, dealID, func(stored *model.TrailingStop) error {
+		if stored.Status == TrailingClosing {
+			return berrors.New(berrors.TrailingStopClosing, "Trailing stop is already closing the position")
+		}
+		if stored.Status != TrailingActive {
+			return berrors.New(berrors.NotFound, "Position has no active trailing stop")
+		}
+		stored.Status = TrailingCancelled
+		stored.ClosedAt = time.Now().UTC()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("save %w", err)
+	}
+	return stop, nil
+}
+
+// GetTrailingStops is a method of TrailingStopService that returns stops of user
+func (tss *TrailingStopService) GetTrailingStops(ctx context.Context, profileID uuid.UUID) ([]*model.TrailingStop, error) {
+	stops, err := tss.tsRep.GetTrailingStops(ctx, profileID)
+	if err != nil {
+		return nil, fmt.Errorf("getTrailingStops %w", err)
+	}
+	return stops, nil
+}
+
+// Run is a method of TrailingStopService that follows prices by active stops until ctx is canceled.
+// Stops are followed only by the leading instance, stops which were interrupted while closing their positions
+// are resolved first when instance becomes the leader
+func (tss *TrailingStopService) Run(ctx context.Context) {
+	ticker := time.NewTicker(tss.cfg.TrailingStopInterval)
+	defer ticker.Stop()
+	recovered := false
+	for {
+		select {
+		case <-ctx.Done():
+			if tss.leader != nil {
+				if err := tss.leader.Resign(context.Background()); err != nil {
+					logrus.Errorf("trailingStopMonitor: %v", err)
+				}
+			}
+			return
+		case <-ticker.C:
+			if !tss.lead(ctx) {
+				recovered = false
+				continue
+			}
+			if !recovered {
+				err := tss.Recover(ctx)
+				if err != nil && ctx.Err() == nil {
+					logrus.Errorf("trailingStopMonitor: %v", err)
+				}
+				recovered = err == nil
+			}
+			if err := tss.Monitor(ctx); err != nil && ctx.Err() == nil {
+				logrus.Errorf("trailingStopMonitor: %v", err)
+			}
+		}
+	}
+}
+
+// lead reports if this instance runs monitor now
+func (tss *TrailingStopService) lead(ctx context.Context) bool {
+	if tss.leader == nil {
+		return true
+	}
+	lead, err := tss.leader.Lead(ctx)
+	if err != nil && ctx.Err() == nil {
+		logrus.Errorf("trailingStopMonitor: %v", err)
+	}
+	return lead
+}
+
+// Monitor is a method of TrailingStopService that moves active stops after the best prices
+// and closes positions whose stops are breached. Stale prices are skipped, so stops are never moved
+// or breached by outdated prices
+func (tss *TrailingStopService) Monitor(ctx context.Context) error {
+	stops, err := tss.tsRep.GetTrailingStopsWithStatus(ctx, TrailingActive)
+	if err != nil {
+		return fmt.Errorf("getTrailingStopsWithStatus %w", err)
+	}
+	if len(stops) == 0 {
+		return nil
+	}
+	snapshot, err := tss.trading.GetPriceSnapshot(ctx)
+	if err != nil {
+		return fmt.Errorf("getPriceSnapshot %w", err)
+	}
+	if snapshot.Stale {
+		logrus.Warnf("trailingStopMonitor: prices of %s are stale, stops aren't checked",
+			snapshot.UpdatedAt.Format(time.RFC3339))
+		return nil
+	}
+	for _, stop := range stops {
+		price, ok := sharePrice(snapshot.Shares, stop.Company)
+		if !ok {
+			continue
+		}
+		if breached(stop, price) {
+			if errClose := tss.close(ctx, stop); errClose != nil {
+				logrus.Errorf("trailingStopMonitor: %v", errClose)
+			}
+			continue
+		}
+		if !improves(stop, price) {
+			continue
+		}
+		_, errSave := tss.tsRep.Save(ctx, stop.ProfileID, stop.DealID, func(stored *model.TrailingStop) error {
+			if stored.Status != TrailingActive {
+				return berrors.New(berrors.TrailingStopClosing, "Trailing stop is "+stored.Status)
+			}
+			follow(stored, price, time.Now().UTC())
+			return nil
+		})
+		if errSave != nil {
+			logrus.Errorf("trailingStopMonitor: %v", errSave)
+		}
+	}
+	return nil
+}
+
+// Recover is a method of TrailingStopService that resolves stops left closing by restart. Stop whose position
+// is still open becomes active again, otherwise the position is considered closed
+func (tss *TrailingStopService) Recover(ctx context.Context) error {
+	stops, err := tss.tsRep.GetTrailingStopsWithStatus(ctx, TrailingClosing)
+	if err != nil {
+		return fmt.Errorf("getTrailingStopsWithStatus %w", err)
+	}
+	for _, stop := range stops {
+		opened, errOpened := tss.positionOpened(ctx, stop)
+		if errOpened != nil {
+			return fmt.Errorf("positionOpened %w", errOpened)
+		}
+		status, reason := TrailingActive, ""
+		if !opened {
+			status, reason = TrailingClosed, "Position was closed while trailing stop was interrupted"
+		}
+		if err = tss.finish(ctx, stop, status, reason, decimal.Zero); err != nil {
+			return fmt.Errorf("finish %w", err)
+		}
+	}
+	return nil
+}
+
+// close marks stop as closing and closes its position through TradingService. Stop becomes active again
+// if backend is temporarily unavailable, if position was closed by somebody else stop is closed too
+func (tss *TrailingStopService) close(ctx context.Context, stop *model.TrailingStop) error {
+	_, err := tss.tsRep.Save(ctx, stop.ProfileID, stop.DealID, func(stored *model.TrailingStop) error {
+		if stored.Status != TrailingActive {
+			return berrors.New(berrors.TrailingStopClosing, "Trailing stop is "+stored.Status)
+		}
+		stored.Status = TrailingClosing
+		stored.UpdatedAt = time.Now().UTC()
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("save %w", err)
+	}
+	profit, err := tss.trading.ClosePositionManually(ctx, stop.DealID, stop.ProfileID)
+	if err == nil {
+		return tss.finish(ctx, stop, TrailingClosed, "", profit)
+	}
+	_, message, rule := berrors.Translate(err, "Failed to close position")
+	if rule.Retryable {
+		logrus.Warnf("trailingStopMonitor: closing of deal %s will be retried: %v", stop.DealID, err)
+		return tss.finish(ctx, stop, TrailingActive, "", decimal.Zero)
+	}
+	opened, errOpened := tss.positionOpened(ctx, stop)
+	if errOpened != nil {
+		logrus.Errorf("trailingStopMonitor: %v", errOpened)
+		return tss.finish(ctx, stop, TrailingActive, "", decimal.Zero)
+	}
+	if !opened {
+		return tss.finish(ctx, stop, TrailingClosed, "Position was closed before trailing stop", decimal.Zero)
+	}
+	logrus.Infof("trailingStopMonitor: deal %s wasn't closed: %v", stop.DealID, err)
+	return tss.finish(ctx, stop, TrailingFailed, message, decimal.Zero)
+}
+
+// finish moves closing stop to the given status
+func (tss *TrailingStopService) finish(ctx context.Context, stop *model.TrailingStop, status, reason string, profit decimal.Decimal) error {
+	_, err := tss.tsRep.Save(ctx, stop.ProfileID, stop.DealID, func(stored *model.TrailingStop) error {
+		if stored.Status != TrailingClosing {
+			return berrors.New(berrors.TrailingStopClosing, "Trailing stop is "+stored.Status)
+		}
+		now := time.Now().UTC()
+		stored.Status = status
+		stored.Reason = reason
+		stored.Profit = profit
+		stored.UpdatedAt = now
+		if status != TrailingActive {
+			stored.ClosedAt = now
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("save %w", err)
+	}
+	return nil
+}
+
+// positionOpened reports if position of stop is still open
+func (tss *TrailingStopService) positionOpened(ctx context.Context, stop *model.TrailingStop) (bool, error) {
+	deals, err := tss.trading.GetUnclosedPositions(ctx, stop.ProfileID)
+	if err != nil {
+		return false, fmt.Errorf("getUnclosedPositions %w", err)
+	}
+	return findDeal(deals, stop.DealID) != nil, nil
+}
+
+// follow moves the best price of stop if price is better and recalculates effective stop
+func follow(stop *model.TrailingStop, price decimal.Decimal, now time.Time) {
+	if improves(stop, price) {
+		stop.BestPrice = price
+	}
+	distance := stop.Distance
+	if stop.Type == TrailingPercent {
+		distance = stop.BestPrice.Mul(stop.Distance).Div(decimal.NewFromInt(100))
+	}
+	if stop.Direction == "short" {
+		stop.EffectiveStop = decimal.Min(stop.BestPrice.Add(distance).RoundFloor(model.PricePlaces), stop.StopLoss)
+	} else {
+		stop.EffectiveStop = decimal.Max(stop.BestPrice.Sub(distance).RoundCeil(model.PricePlaces), stop.StopLoss)
+	}
+	stop.UpdatedAt = now
+}
+
+// improves reports if price is better than the best price of stop
+func improves(stop *model.TrailingStop, price decimal.Decimal) bool {
+	if stop.Direction == "short" {
+		return price.LessThan(stop.BestPrice)
+	}
+	return price.GreaterThan(stop.BestPrice)
+}
+
+// breached reports if price reached effective stop
+func breached(stop *model.TrailingStop, price decimal.Decimal) bool {
+	if stop.Direction == "short" {
+		return price.GreaterThanOrEqual(stop.EffectiveStop)
+	}
+	return price.LessThanOrEqual(stop.EffectiveStop)
+}
+
+// findDeal returns deal with the given id or nil if there is no such deal
+func findDeal(deals []*model.Deal, dealID uuid.UUID) *model.Deal {
+	for _, deal := range deals {
+		if deal.DealID == dealID {
+			return deal
+		}
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/artnikel/APIService/internal/config"
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/artnikel/APIService/internal/repository"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+// trailingBackend is fake of trading service with open positions which can be closed by current prices
+type trailingBackend struct {
+	prices   map[string]decimal.Decimal
+	stale    bool
+	opened   []*model.Deal
+	closed   []uuid.UUID
+	closeErr error
+}
+
+func (b *trailingBackend) ClosePositionManually(_ context.Context, dealid, _ uuid.UUID) (decimal.Decimal, error) {
+	if b.closeErr != nil {
+		return decimal.Zero, b.closeErr
+	}
+	b.closed = append(b.closed, dealid)
+	return decimal.NewFromInt(42), nil
+}
+
+func (b *trailingBackend) GetUnclosedPositions(_ context.Context, _ uuid.UUID) ([]*model.Deal, error) {
+	return b.opened, nil
+}
+
+func (b *trailingBackend) GetPriceSnapshot(_ context.Context) (*model.PriceSnapshot, error) {
+	shares := make([]model.Share, 0, len(b.prices))
+	for company, price := range b.prices {
+		shares = append(shares, model.Share{Company: company, Price: price})
+	}
+	return &model.PriceSnapshot{Shares: shares, Stale: b.stale}, nil
+}
+
+func newTestTrailingStops(t *testing.T, path string, backend *trailingBackend) (*TrailingStopService, func()) {
+	db, err := repository.NewBoltDB(&config.Variables{DataPath: path})
+	require.NoError(t, err)
+	tsRep, err := repository.NewTrailingStopRepository(db)
+	require.NoError(t, err)
+	return NewTrailingStopService(tsRep, backend, nil, &config.Variables{}), func() {
+		require.NoError(t, db.Close())
+	}
+}
+
+func testTrailingStop(t *testing.T, srv *TrailingStopService, profileID, dealID uuid.UUID) *model.TrailingStop {
+	stops, err := srv.GetTrailingStops(context.Background(), profileID)
+	require.NoError(t, err)
+	for _, stop := range stops {
+		if stop.DealID == dealID {
+			return stop
+		}
+	}
+	t.Fatalf("no trailing stop of deal %s", dealID)
+	return nil
+}
+
+func TestTrailingStopFollowsPriceAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	profileID := uuid.New()
+	deal := &model.Deal{DealID: uuid.New(), Company: "Apple", StopLoss: decimal.NewFromInt(80), TakeProfit: decimal.NewFromInt(200)}
+	backend := &trailingBackend{prices: map[string]decimal.Decimal{"Apple": decimal.NewFromInt(100)}, opened: []*model.Deal{deal}}
+	srv, closeDB := newTestTrailingStops(t, path, backend)
+	stop := &model.TrailingStop{ProfileID: profileID, DealID: deal.DealID, Type: TrailingAbsolute, Distance: decimal.NewFromInt(5)}
+	require.NoError(t, srv.Attach(context.Background(), stop))
+	require.Equal(t, "long", stop.Direction)
+	require.Equal(t, "95", stop.EffectiveStop.String())
+
+	backend.prices["Apple"] = decimal.NewFromInt(120)
+	require.NoError(t, srv.Monitor(context.Background()))
+	backend.prices["Apple"] = decimal.NewFromInt(116)
+	require.NoError(t, srv.Monitor(context.Background()))
+	require.Equal(t, "115", testTrailingStop(t, srv, profileID, deal.DealID).EffectiveStop.String())
+	closeDB()
+
+	srv, closeDB = newTestTrailingStops(t, path, backend)
+	defer closeDB()
+	require.NoError(t, srv.Recover(context.Background()))
+	require.NoError(t, srv.Monitor(context.Background()))
+	require.Empty(t, backend.closed)
+	backend.prices["Apple"] = decimal.NewFromInt(115)
+	require.NoError(t, srv.Monitor(context.Background()))
+	require.Equal(t, []uuid.UUID{deal.DealID}, backend.closed)
+	stop = testTrailingStop(t, srv, profileID, deal.DealID)
+	require.Equal(t, TrailingClosed, stop.Status)
+	require.Equal(t, "120", stop.BestPrice.String())
+	require.Equal(t, "42", stop.Profit.String())
+}
+
+func TestTrailingStopInPercentsOfShort(t *testing.T) {
+	profileID := uuid.New()
+	deal := &model.Deal{DealID: uuid.New(), Company: "Apple", StopLoss: decimal.NewFromInt(250), TakeProfit: decimal.NewFromInt(100)}
+	backend := &trailingBackend{prices: map[string]decimal.Decimal{"Apple": decimal.NewFromInt(200)}, opened: []*model.Deal{deal}}
+	srv, closeDB := newTestTrailingStops(t, filepath.Join(t.TempDir(), "test.db"), backend)
+	defer closeDB()
+	stop := &model.TrailingStop{ProfileID: profileID, DealID: deal.DealID, Type: TrailingPercent, Distance: decimal.NewFromInt(10)}
+	require.NoError(t, srv.Attach(context.Background(), stop))
+	require.Equal(t, "short", stop.Direction)
+	require.Equal(t, "220", stop.EffectiveStop.String())
+
+	backend.prices["Apple"] = decimal.NewFromInt(150)
+	require.NoError(t, srv.Monitor(context.Background()))
+	require.Equal(t, "165", testTrailingStop(t, srv, profileID, deal.DealID).EffectiveStop.String())
+	backend.prices["Apple"] = decimal.NewFromInt(166)
+	require.NoError(t, srv.Monitor(context.Background()))
+	require.Equal(t, []uuid.UUID{deal.DealID}, backend.closed)
+}
+
+func TestTrailingStopKeepsFixedStopLoss(t *testing.T) {
+	deal := &model.Deal{DealID: uuid.New(), Company: "Apple", StopLoss: decimal.NewFromInt(98), TakeProfit: decimal.NewFromInt(200)}
+	backend := &trailingBackend{prices: map[string]decimal.Decimal{"Apple": decimal.NewFromInt(100)}, opened: []*model.Deal{deal}}
+	srv, closeDB := newTestTrailingStops(t, filepath.Join(t.TempDir(), "test.db"), backend)
+	defer closeDB()
+	stop := &model.TrailingStop{ProfileID: uuid.New(), DealID: deal.DealID, Type: TrailingAbsolute, Distance: decimal.NewFromInt(5)}
+	require.NoError(t, srv.Attach(context.Background(), stop))
+	require.Equal(t, "98", stop.EffectiveStop.String())
+}
+
+func TestTrailingStopSkipsStalePrices(t *testing.T) {
+	profileID := uuid.New()
+	deal := &model.Deal{DealID: uuid.New(), Company: "Apple", StopLoss: decimal.NewFromInt(80), TakeProfit: decimal.NewFromInt(200)}
+	backend := &trailingBackend{prices: map[string]decimal.Decimal{"Apple": decimal.NewFromInt(100)}, opened: []*model.Deal{deal}}
+	srv, closeDB := newTestTrailingStops(t, filepath.Join(t.TempDir(), "test.db"), backend)
+	defer closeDB()
+	stop := &model.TrailingStop{ProfileID: profileID, DealID: deal.DealID, Type: TrailingAbsolute, Distance: decimal.NewFromInt(5)}
+	require.NoError(t, srv.Attach(context.Background(), stop))
+
+	backend.stale = true
+	backend.prices["Apple"] = decimal.NewFromInt(90)
+	require.NoError(t, srv.Monitor(context.Background()))
+	require.Empty(t, backend.closed)
+	backend.prices["Apple"] = decimal.NewFromInt(120)
+	require.NoError(t, srv.Monitor(context.Background()))
+	require.Equal(t, "95", testTrailingStop(t, srv, profileID, deal.DealID).EffectiveStop.String())
+	err := srv.Attach(context.Background(), &model.TrailingStop{ProfileID: profileID, DealID: deal.DealID,
+		Type: TrailingAbsolute, Distance: decimal.NewFromInt(3)})
+	var e *berrors.BusinessError
+	require.ErrorAs(t, err, &e)
+	require.Equal(t, berrors.Unavailable, e.Code)
+
+	backend.stale = false
+	require.NoError(t, srv.Monitor(context.Background()))
+	require.Equal(t, "115", testTrailingStop(t, srv, profileID, deal.DealID).EffectiveStop.String())
+}
+
+func TestTrailingStopAttachValidation(t *testing.T) {
+	profileID := uuid.New()
+	deal := &model.Deal{DealID: uuid.New(), Company: "Apple", StopLoss: decimal.NewFromInt(80), TakeProfit: decimal.NewFromInt(200)}
+	backend := &trailingBackend{prices: map[string]decimal.Decimal{"Apple": decimal.NewFromInt(100)}, opened: []*model.Deal{deal}}
+	srv, closeDB := newTestTrailingStops(t, filepath.Join(t.TempDir(), "test.db"), backend)
+	defer closeDB()
+	testCases := []struct {
+		name string
+		stop *model.TrailingStop
+		code string
+	}{
+		{"unknown type", &model.TrailingStop{DealID: deal.DealID, Type: "points", Distance: decimal.NewFromInt(5)}, berrors.InvalidRequest},
+		{"zero distance", &model.TrailingStop{DealID: deal.DealID, Type: TrailingAbsolute}, berrors.InvalidRequest},
+		{"whole price", &model.TrailingStop{DealID: deal.DealID, Type: TrailingPercent, Distance: decimal.NewFromInt(100)}, berrors.InvalidRequest},
+		{"closed position", &model.TrailingStop{DealID: uuid.New(), Type: TrailingAbsolute, Distance: decimal.NewFromInt(5)}, berrors.NotFound},
+	}
+	for _, tc := range testCases {
+		tc.stop.ProfileID = profileID
+		err := srv.Attach(context.Background(), tc.stop)
+		var e *berrors.BusinessError
+		require.ErrorAs(t, err, &e, tc.name)
+		require.Equal(t, tc.code, e.Code, tc.name)
+	}
+	_, err := srv.Detach(context.Background(), profileID, deal.DealID)
+	var e *berrors.BusinessError
+	require.ErrorAs(t, err, &e)
+	require.Equal(t, berrors.NotFound, e.Code)
+}
+
+func TestTrailingStopOfPositionClosedElsewhere(t *testing.T) {
+	profileID := uuid.New()
+	deal := &model.Deal{DealID: uuid.New(), Company: "Apple", StopLoss: decimal.NewFromInt(80), TakeProfit: decimal.NewFromInt(200)}
+	backend := &trailingBackend{prices: map[string]decimal.Decimal{"Apple": decimal.NewFromInt(100)}, opened: []*model.Deal{deal}}
+	srv, closeDB := newTestTrailingStops(t, filepath.Join(t.TempDir(), "test.db"), backend)
+	defer closeDB()
+	stop := &model.TrailingStop{ProfileID: profileID, DealID: deal.DealID, Type: TrailingAbsolute, Distance: decimal.NewFromInt(5)}
+	require.NoError(t, srv.Attach(context.Background(), stop))
+
+	backend.prices["Apple"] = decimal.NewFromInt(90)
+	backend.closeErr = berrors.New(berrors.Unavailable, "Service is temporarily unavailable")
+	require.NoError(t, srv.Monitor(context.Background()))
+	require.Equal(t, TrailingActive, testTrailingStop(t, srv, profileID, deal.DealID).Status)
+
+	backend.opened = nil
+	backend.closeErr = berrors.New(berrors.NotFound, "Not found")
+	require.NoError(t, srv.Monitor(context.Background()))
+	stop = testTrailingStop(t, srv, profileID, deal.DealID)
+	require.Equal(t, TrailingClosed, stop.Status)
+	require.Equal(t, "Position was closed before trailing stop", stop.Reason)
+}
+
+func TestTrailingStopMonitorRunsOnlyInLeader(t *testing.T) {
+	profileID := uuid.New()
+	deal := &model.Deal{DealID: uuid.New(), Company: "Apple", StopLoss: decimal.NewFromInt(80), TakeProfit: decimal.NewFromInt(200)}
+	backend := &trailingBackend{prices: map[string]decimal.Decimal{"Apple": decimal.NewFromInt(100)}, opened: []*model.Deal{deal}}
+	db, err := repository.NewBoltDB(&config.Variables{DataPath: filepath.Join(t.TempDir(), "test.db")})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, db.Close()) }()
+	tsRep, err := repository.NewTrailingStopRepository(db)
+	require.NoError(t, err)
+	locks := repository.NewMemoryLockRepository()
+	leader := NewLeader(locks, "trailing_stops", time.Minute)
+	lead, err := leader.Lead(context.Background())
+	require.NoError(t, err)
+	require.True(t, lead)
+	srv := NewTrailingStopService(tsRep, backend, NewLeader(locks, "trailing_stops", time.Minute),
+		&config.Variables{TrailingStopInterval: 5 * time.Millisecond})
+	stop := &model.TrailingStop{ProfileID: profileID, DealID: deal.DealID, Type: TrailingAbsolute, Distance: decimal.NewFromInt(5)}
+	require.NoError(t, srv.Attach(context.Background(), stop))
+	backend.prices["Apple"] = decimal.NewFromInt(90)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		srv.Run(ctx)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, TrailingActive, testTrailingStop(t, srv, profileID, deal.DealID).Status)
+
+	require.NoError(t, leader.Resign(context.Background()))
+	require.Eventually(t, func() bool {
+		stops, errStops := srv.GetTrailingStops(context.Background(), profileID)
+		return errStops == nil && stops[0].Status == TrailingClosed
+	}, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+	require.Equal(t, []uuid.UUID{deal.DealID}, backend.closed)
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/artnikel/APIService/internal/config"
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	day   = 24 * time.Hour
+	week  = 7 * day
+	month = 30 * day
+)
+
+// UserOperationRepository is an interface that contains methods for keeping recent deposits and withdrawals of profile
+type UserOperationRepository interface {
+	AddOperation(ctx context.Context, profileID uuid.UUID, operation *model.UserOperation, keep time.Duration) error
+	RemoveOperation(ctx context.Context, profileID uuid.UUID, operation *model.UserOperation) error
+	GetOperations(ctx context.Context, profileID uuid.UUID, since time.Time) ([]*model.UserOperation, error)
+}
+
+// WithdrawalRules checks operations with balance of user against limits configured for environment.
+// Deposits and withdrawals are kept in the store shared by all instances, caps are counted over rolling windows
+// which end at the moment of operation, so they can`t be bypassed by waiting for midnight.
+// Operation is rejected when the store can`t be read or written
+type WithdrawalRules struct {
+	uRep UserOperationRepository
+	cfg  config.Variables
+}
+
+// withdrawalCap is a limit of sum of withdrawals over the window
+type withdrawalCap struct {
+	window time.Duration
+	limit  decimal.Decimal
+	code   string
+	name   string
+}
+
+// NewWithdrawalRules accepts UserOperationRepository object and returnes an object of type *WithdrawalRules
+func NewWithdrawalRules(uRep UserOperationRepository, cfg *config.Variables) *WithdrawalRules {
+	return &WithdrawalRules{uRep: uRep, cfg: *cfg}
+}
+
+// Check is a method of WithdrawalRules that returns business error with code of the first broken rule.
+// Operation is negative for withdrawals, limits of sum, caps and cool-off after deposit apply only to them.
+// Count of operations per hour includes deposits and withdrawals
+func (wr *WithdrawalRules) Check(ctx context.Context, profileID uuid.UUID, operation decimal.Decimal, now time.Time) error {
+	withdrawal := operation.IsNegative()
+	amount := operation.Abs()
+	if withdrawal {
+		if wr.cfg.WithdrawalMin.IsPositive() && amount.LessThan(wr.cfg.WithdrawalMin) {
+			return berrors.New(berrors.WithdrawalBelowMinimum,
+				"Minimum sum of withdrawal is "+wr.cfg.WithdrawalMin.StringFixed(model.USD.Places)+"$")
+		}
+		if wr.cfg.WithdrawalMax.IsPositive() && amount.GreaterThan(wr.cfg.WithdrawalMax) {
+			return berrors.New(berrors.WithdrawalAboveMaximum,
+				"Maximum sum of withdrawal is "+wr.cfg.WithdrawalMax.StringFixed(model.USD.Places)+"$")
+		}
+	}
+	saved, err := wr.uRep.GetOperations(ctx, profileID, now.Add(-wr.keep()))
+	if err != nil {
+		return fmt.Errorf("getOperations %w", err)
+	}
+	caps := wr.caps()
+	var operations int
+	var lastDeposit time.Time
+	withdrawn := make([]decimal.Decimal, len(caps))
+	for _, userOperation := range saved {
+		age := now.Sub(userOperation.Time)
+		if age < time.Hour {
+			operations++
+		}
+		if !userOperation.Amount.IsNegative() {
+			if userOperation.Time.After(lastDeposit) {
+				lastDeposit = userOperation.Time
+			}
+			continue
+		}
+		for i, withdrawalCap := range caps {
+			if age < withdrawalCap.window {
+				withdrawn[i] = withdrawn[i].Add(userOperation.Amount.Abs())
+			}
+		}
+	}
+	if wr.cfg.BalanceOperationsPerHour > 0 && operations >= wr.cfg.BalanceOperationsPerHour {
+		return berrors.New(berrors.TooManyBalanceOperations,
+			fmt.Sprintf("No more than %d operations with balance are allowed per hour", wr.cfg.BalanceOperationsPerHour))
+	}
+	if !withdrawal {
+		return nil
+	}
+	if left := lastDeposit.Add(wr.cfg.DepositCoolOff).Sub(now); wr.cfg.DepositCoolOff > 0 && left > 0 {
+		return berrors.New(berrors.DepositCoolOff,
+			fmt.Sprintf("Withdrawal is allowed in %d minutes after the last deposit", int(math.Ceil(left.Minutes()))))
+	}
+	for i, withdrawalCap := range caps {
+		if withdrawalCap.limit.IsPositive() && withdrawn[i].Add(amount).GreaterThan(withdrawalCap.limit) {
+			left := decimal.Max(withdrawalCap.limit.Sub(withdrawn[i]), decimal.Zero)
+			return berrors.New(withdrawalCap.code, fmt.Sprintf("%s withdrawal cap is %s$, %s$ is left",
+				withdrawalCap.name, withdrawalCap.limit.StringFixed(model.USD.Places), left.StringFixed(model.USD.Places)))
+		}
+	}
+	return nil
+}
+
+// Record is a method of WithdrawalRules that saves operation before it is made, so concurrent operations
+// on other instances count it
+func (wr *WithdrawalRules) Record(ctx context.Context, profileID uuid.UUID, operation *model.UserOperation) error {
+	if err := wr.uRep.AddOperation(ctx, profileID, operation, wr.keep()); err != nil {
+		return fmt.Errorf("addOperation %w", err)
+	}
+	return nil
+}
+
+// Forget is a method of WithdrawalRules that removes operation which wasn`t made
+func (wr *WithdrawalRules) Forget(ctx context.Context, profileID uuid.UUID, operation *model.UserOperation) error {
+	if err := wr.uRep.RemoveOperation(ctx, profileID, operation); err != nil {
+		return fmt.Errorf("removeOperation %w", err)
+	}
+	return nil
+}
+
+// keep returns period for which operations are needed by rules
+func (wr *WithdrawalRules) keep() time.Duration {
+	if wr.cfg.DepositCoolOff > month {
+		return wr.cfg.DepositCoolOff
+	}
+	return month
+}
+
+// caps returns caps of withdrawals from the shortest window
+func (wr *WithdrawalRules) caps() []withdrawalCap {
+	return []withdrawalCap{
+		{window: day, limit: wr.cfg.WithdrawalDailyCap, code: berrors.DailyWithdrawalCap, name: "Daily"},
+		{window: week, limit: wr.cfg.WithdrawalWeeklyCap, code: berrors.WeeklyWithdrawalCap, name: "Weekly"},
+		{window: month, limit: wr.cfg.WithdrawalMonthlyCap, code: berrors.MonthlyWithdrawalCap, name: "Monthly"},
+	}
+}
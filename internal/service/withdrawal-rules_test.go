@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/artnikel/APIService/internal/config"
+	berrors "github.com/artnikel/APIService/internal/errors"
+	"github.com/artnikel/APIService/internal/model"
+	"github.com/artnikel/APIService/internal/repository"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+var withdrawalRulesCfg = config.Variables{
+	WithdrawalMin:            decimal.NewFromInt(10),
+	WithdrawalMax:            decimal.NewFromInt(1000),
+	WithdrawalDailyCap:       decimal.NewFromInt(1500),
+	WithdrawalWeeklyCap:      decimal.NewFromInt(3000),
+	WithdrawalMonthlyCap:     decimal.NewFromInt(5000),
+	BalanceOperationsPerHour: 3,
+	DepositCoolOff:           30 * time.Minute,
+}
+
+// failingOperations is a store of user operations which is unavailable
+type failingOperations struct{}
+
+func (failingOperations) AddOperation(_ context.Context, _ uuid.UUID, _ *model.UserOperation, _ time.Duration) error {
+	return errors.New("store is unavailable")
+}
+
+func (failingOperations) RemoveOperation(_ context.Context, _ uuid.UUID, _ *model.UserOperation) error {
+	return errors.New("store is unavailable")
+}
+
+func (failingOperations) GetOperations(_ context.Context, _ uuid.UUID, _ time.Time) ([]*model.UserOperation, error) {
+	return nil, errors.New("store is unavailable")
+}
+
+// userOperation creates deposit or withdrawal made by user the given time ago
+func userOperation(now time.Time, ago time.Duration, amount int64) *model.UserOperation {
+	return &model.UserOperation{ID: uuid.New(), Time: now.Add(-ago), Amount: decimal.NewFromInt(amount)}
+}
+
+func TestWithdrawalRules(t *testing.T) {
+	now := time.Date(2023, time.October, 10, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name      string
+		history   []*model.UserOperation
+		operation int64
+		code      string
+	}{
+		{"allowed", []*model.UserOperation{userOperation(now, 2*time.Hour, 2000)}, -500, ""},
+		{"below minimum", nil, -5, berrors.WithdrawalBelowMinimum},
+		{"above maximum", nil, -1001, berrors.WithdrawalAboveMaximum},
+		{"deposit isn't limited by maximum", nil, 5000, ""},
+		{"daily cap", []*model.UserOperation{userOperation(now, 2*time.Hour, -1000)}, -600, berrors.DailyWithdrawalCap},
+		{"daily cap boundary", []*model.UserOperation{userOperation(now, 2*time.Hour, -1000)}, -500, ""},
+		{"weekly cap", []*model.UserOperation{userOperation(now, 2*day, -1000), userOperation(now, 3*day, -1000),
+			userOperation(now, 4*day, -900)}, -200, berrors.WeeklyWithdrawalCap},
+		{"monthly cap", []*model.UserOperation{userOperation(now, 10*day, -1000), userOperation(now, 11*day, -1000),
+			userOperation(now, 20*day, -1000), userOperation(now, 21*day, -1000), userOperation(now, 25*day, -900),
+			userOperation(now, 40*day, -1000)}, -200,
+			berrors.MonthlyWithdrawalCap},
+		{"operations per hour", []*model.UserOperation{userOperation(now, 40*time.Minute, 100),
+			userOperation(now, 50*time.Minute, -20), userOperation(now, 55*time.Minute, -20)}, 100,
+			berrors.TooManyBalanceOperations},
+		{"cool-off after deposit", []*model.UserOperation{userOperation(now, 10*time.Minute, 100)}, -50,
+			berrors.DepositCoolOff},
+		{"deposit after deposit", []*model.UserOperation{userOperation(now, 10*time.Minute, 100)}, 50, ""},
+	}
+	for _, tc := range testCases {
+		uRep := repository.NewMemoryUserOperationRepository()
+		profileID := uuid.New()
+		for _, saved := range tc.history {
+			require.NoError(t, uRep.AddOperation(context.Background(), profileID, saved, 365*day), tc.name)
+		}
+		rules := NewWithdrawalRules(uRep, &withdrawalRulesCfg)
+		err := rules.Check(context.Background(), profileID, decimal.NewFromInt(tc.operation), now)
+		if tc.code == "" {
+			require.NoError(t, err, tc.name)
+			continue
+		}
+		var e *berrors.BusinessError
+		require.ErrorAs(t, err, &e, tc.name)
+		require.Equal(t, tc.code, e.Code, tc.name)
+	}
+}
+
+func TestBalanceOperationChecksRulesBeforeRepository(t *testing.T) {
+	lRep, wallet, ledger := newTestLedger(t)
+	rules := NewWithdrawalRules(repository.NewMemoryUserOperationRepository(), &withdrawalRulesCfg)
+	srv := NewBalanceService(wallet, ledger, nil, rules, &config.Variables{})
+	profileID := uuid.New()
+
+	_, err := srv.BalanceOperation(context.Background(), &model.Balance{ProfileID: profileID, Operation: decimal.NewFromInt(100)})
+	require.NoError(t, err)
+	_, err = srv.BalanceOperation(context.Background(), &model.Balance{ProfileID: profileID, Operation: decimal.NewFromInt(-50)})
+	var e *berrors.BusinessError
+	require.ErrorAs(t, err, &e)
+	require.Equal(t, berrors.DepositCoolOff, e.Code)
+	require.Equal(t, "100", wallet.money.String())
+	entries, err := lRep.GetEntries(context.Background(), profileID)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestBalanceOperationFailsClosedWithoutOperationStore(t *testing.T) {
+	_, wallet, ledger := newTestLedger(t)
+	rules := NewWithdrawalRules(failingOperations{}, &withdrawalRulesCfg)
+	srv := NewBalanceService(wallet, ledger, nil, rules, &config.Variables{})
+
+	_, err := srv.BalanceOperation(context.Background(), &model.Balance{ProfileID: uuid.New(), Operation: decimal.NewFromInt(100)})
+	require.Error(t, err)
+	require.True(t, wallet.money.IsZero())
+}
+
+func TestBalanceOperationIsCountedByAllInstances(t *testing.T) {
+	uRep := repository.NewMemoryUserOperationRepository()
+	_, firstWallet, firstLedger := newTestLedger(t)
+	_, secondWallet, secondLedger := newTestLedger(t)
+	first := NewBalanceService(firstWallet, firstLedger, nil, NewWithdrawalRules(uRep, &withdrawalRulesCfg),
+		&config.Variables{})
+	second := NewBalanceService(secondWallet, secondLedger, nil, NewWithdrawalRules(uRep, &withdrawalRulesCfg),
+		&config.Variables{})
+	profileID := uuid.New()
+	firstWallet.money = decimal.NewFromInt(1000)
+	secondWallet.money = decimal.NewFromInt(1000)
+
+	_, err := first.BalanceOperation(context.Background(), &model.Balance{ProfileID: profileID, Operation: decimal.NewFromInt(-1000)})
+	require.NoError(t, err)
+	_, err = second.BalanceOperation(context.Background(), &model.Balance{ProfileID: profileID, Operation: decimal.NewFromInt(-600)})
+	var e *berrors.BusinessError
+	require.ErrorAs(t, err, &e)
+	require.Equal(t, berrors.DailyWithdrawalCap, e.Code)
+	require.Equal(t, "1000", secondWallet.money.String())
+}
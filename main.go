@@ -2,20 +2,22 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 
 	"github.com/artnikel/APIService/internal/config"
 	"github.com/artnikel/APIService/internal/handler"
+	"github.com/artnikel/APIService/internal/logging"
 	"github.com/artnikel/APIService/internal/repository"
 	"github.com/artnikel/APIService/internal/service"
 	bproto "github.com/artnikel/BalanceService/proto"
 	uproto "github.com/artnikel/ProfileService/proto"
 	tproto "github.com/artnikel/TradingService/proto"
 	"github.com/go-playground/validator/v10"
-	"github.com/labstack/echo-contrib/session"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 )
@@ -26,6 +28,7 @@ func main() {
 	if err != nil {
 		log.Fatalf("could not parse config: %v", err)
 	}
+	logrus.AddHook(logging.NewRedactHook())
 	v := validator.New()
 	uconn, err := grpc.Dial(cfg.ProfileAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
@@ -59,32 +62,154 @@ func main() {
 	urep := repository.NewProfileRepository(uclient)
 	brep := repository.NewBalanceRepository(bclient)
 	trep := repository.NewTradingRepository(tclient)
+	db, err := repository.NewBoltDB(cfg)
+	if err != nil {
+		log.Fatalf("could not open database: %v", err)
+	}
+	defer func() {
+		if errDBClose := db.Close(); errDBClose != nil {
+			log.Fatalf("could not close database: %v", errDBClose)
+		}
+	}()
+	ledgerRep, err := repository.NewLedgerRepository(db)
+	if err != nil {
+		log.Fatalf("could not create ledger: %v", err)
+	}
+	ledgerSrv := service.NewLedgerService(ledgerRep, brep)
+	pool := repository.NewRedisPool(cfg)
+	var lockRep service.LockRepository = repository.NewLockRepository(pool)
+	if cfg.LockStore == "memory" {
+		lockRep = repository.NewMemoryLockRepository()
+	}
+	profileLock := service.NewProfileLock(lockRep, cfg)
 	usrv := service.NewUserService(urep, cfg)
-	bsrv := service.NewBalanceService(brep, cfg)
-	tsrv := service.NewTradingService(trep)
-	hndl := handler.NewHandler(usrv, bsrv, tsrv, v, cfg)
+	var userOperationRep service.UserOperationRepository = repository.NewUserOperationRepository(pool)
+	if cfg.UserOperationStore == "memory" {
+		userOperationRep = repository.NewMemoryUserOperationRepository()
+	}
+	withdrawalRules := service.NewWithdrawalRules(userOperationRep, cfg)
+	bsrv := service.NewBalanceService(brep, ledgerSrv, profileLock, withdrawalRules, cfg)
+	tsrv := service.NewTradingService(trep, ledgerSrv, profileLock, brep, cfg)
+	tokenRep := repository.NewTokenRepository(pool)
+	tokenSrv := service.NewTokenService(tokenRep, cfg)
+	var sessionStore handler.SessionStore = repository.NewSessionRepository(pool)
+	if cfg.SessionStore == "memory" {
+		sessionStore = repository.NewMemorySessionRepository()
+	}
+	var loginAttemptRep service.LoginAttemptRepository = repository.NewLoginAttemptRepository(pool)
+	if cfg.LoginAttemptStore == "memory" {
+		loginAttemptRep = repository.NewMemoryLoginAttemptRepository()
+	}
+	loginGuard := service.NewLoginGuardService(loginAttemptRep, cfg)
+	priceStream := service.NewPriceStreamService(tsrv, cfg)
+	priceHistoryRep, err := repository.NewPriceHistoryRepository(db)
+	if err != nil {
+		log.Fatalf("could not create price history: %v", err)
+	}
+	priceRecorder := service.NewPriceRecorderService(tsrv, priceHistoryRep, cfg)
+	go priceRecorder.Run(context.Background())
+	portfolioSrv := service.NewPortfolioService(tsrv, bsrv)
+	analyticsSrv := service.NewAnalyticsService(tsrv)
+	exportSrv := service.NewExportService(tsrv)
+	var idempotencyRep service.IdempotencyRepository = repository.NewIdempotencyRepository(pool)
+	if cfg.IdempotencyStore == "memory" {
+		idempotencyRep = repository.NewMemoryIdempotencyRepository()
+	}
+	idempotencySrv := service.NewIdempotencyService(idempotencyRep, lockRep, cfg)
+	var orderRep service.OrderRepository = repository.NewRedisOrderRepository(pool)
+	if cfg.OrderStore == "bolt" {
+		orderRep, err = repository.NewOrderRepository(db)
+		if err != nil {
+			log.Fatalf("could not create order book: %v", err)
+		}
+	}
+	orderSrv := service.NewOrderService(orderRep, tsrv, service.NewLeader(lockRep, "orders", cfg.EngineLeaderTTL), cfg)
+	go orderSrv.Run(context.Background())
+	var trailingStopRep service.TrailingStopRepository = repository.NewRedisTrailingStopRepository(pool)
+	if cfg.TrailingStopStore == "bolt" {
+		trailingStopRep, err = repository.NewTrailingStopRepository(db)
+		if err != nil {
+			log.Fatalf("could not create trailing stops: %v", err)
+		}
+	}
+	trailingStopSrv := service.NewTrailingStopService(trailingStopRep, tsrv,
+		service.NewLeader(lockRep, "trailing_stops", cfg.EngineLeaderTTL), cfg)
+	go trailingStopSrv.Run(context.Background())
+	hndl := handler.NewHandler(handler.Dependencies{
+		UserService:    usrv,
+		BalanceService: bsrv,
+		TradingService: tsrv,
+		TokenService:   tokenSrv,
+		SessionStore:   sessionStore,
+		LoginGuard:     loginGuard,
+		PriceStream:    priceStream,
+		PriceHistory:   priceRecorder,
+		Portfolio:      portfolioSrv,
+		Analytics:      analyticsSrv,
+		Exports:        exportSrv,
+		Ledger:         ledgerSrv,
+		Idempotency:    idempotencySrv,
+		Orders:         orderSrv,
+		TrailingStops:  trailingStopSrv,
+	}, v, cfg)
 	fmt.Println("API Service started")
 	e := echo.New()
+	e.HTTPErrorHandler = handler.HTTPErrorHandler
+	e.IPExtractor, err = handler.NewIPExtractor(cfg)
+	if err != nil {
+		log.Fatalf("could not parse trusted proxies: %v", err)
+	}
 	e.Static("/static", "static")
+	e.Use(middleware.RequestID())
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
-	store := handler.NewRedisStore(cfg)
-	store.SetMaxAge(10 * 24 * 3600)
-	e.Use(session.Middleware(store))
+	e.Use(hndl.CSRF)
 	e.GET("/", hndl.Auth)
-	e.GET("/index", hndl.Index)
 	e.POST("/signup", hndl.SignUp)
 	e.POST("/login", hndl.Login)
-	e.POST("/delete", hndl.DeleteAccount)
-	e.POST("/deposit", hndl.Deposit)
-	e.POST("/withdraw", hndl.Withdraw)
-	e.POST("/long", hndl.CreatePosition)
-	e.POST("/short", hndl.CreatePosition)
-	e.POST("/closeposition", hndl.ClosePositionManually)
-	e.GET("/getunclosed", hndl.GetUnclosedPositions)
-	e.GET("/getclosed", hndl.GetClosedPositions)
 	e.GET("/getprices", hndl.GetPrices)
+	e.GET("/stream/prices", hndl.StreamPrices)
 	e.POST("/logout", hndl.Logout)
+	protected := e.Group("", hndl.Authenticate)
+	protected.GET("/index", hndl.Index)
+	protected.POST("/delete", hndl.DeleteAccount)
+	protected.POST("/deposit", hndl.Deposit, hndl.Idempotent)
+	protected.POST("/withdraw", hndl.Withdraw, hndl.Idempotent)
+	protected.POST("/long", hndl.CreatePosition, hndl.Idempotent)
+	protected.POST("/short", hndl.CreatePosition, hndl.Idempotent)
+	protected.POST("/closeposition", hndl.ClosePositionManually, hndl.Idempotent)
+	protected.GET("/getunclosed", hndl.GetUnclosedPositions)
+	protected.GET("/getclosed", hndl.GetClosedPositions)
+	protected.POST("/trailingstop", hndl.AttachTrailingStop, hndl.Idempotent)
+	protected.GET("/gettrailingstops", hndl.GetTrailingStops)
+	api := e.Group("/api/v1")
+	api.POST("/auth/signup", hndl.APISignUp)
+	api.POST("/auth/login", hndl.APILogin)
+	api.POST("/auth/refresh", hndl.APIRefreshTokens)
+	api.POST("/auth/revoke", hndl.APIRevokeTokens)
+	api.GET("/prices", hndl.APIGetPrices)
+	api.GET("/prices/:company/candles", hndl.APIGetCandles)
+	apiProtected := api.Group("", hndl.Authenticate)
+	apiProtected.GET("/balance", hndl.APIGetBalance)
+	apiProtected.POST("/deposits", hndl.APIDeposit, hndl.Idempotent)
+	apiProtected.POST("/withdrawals", hndl.APIWithdraw, hndl.Idempotent)
+	apiProtected.POST("/positions", hndl.APICreatePosition, hndl.Idempotent)
+	apiProtected.GET("/positions/open", hndl.APIGetUnclosedPositions)
+	apiProtected.GET("/positions/closed", hndl.APIGetClosedPositions)
+	apiProtected.GET("/portfolio", hndl.APIGetPortfolio)
+	apiProtected.GET("/analytics", hndl.APIGetAnalytics)
+	apiProtected.GET("/exports/trades", hndl.APIExportTrades)
+	apiProtected.GET("/statement", hndl.APIGetStatement)
+	apiProtected.DELETE("/positions/:id", hndl.APIClosePosition, hndl.Idempotent)
+	apiProtected.POST("/orders", hndl.APIPlaceOrder, hndl.Idempotent)
+	apiProtected.GET("/orders", hndl.APIGetOrders)
+	apiProtected.DELETE("/orders/:id", hndl.APICancelOrder, hndl.Idempotent)
+	apiProtected.PUT("/positions/:id/trailing-stop", hndl.APIAttachTrailingStop, hndl.Idempotent)
+	apiProtected.DELETE("/positions/:id/trailing-stop", hndl.APIDetachTrailingStop, hndl.Idempotent)
+	apiProtected.GET("/trailing-stops", hndl.APIGetTrailingStops)
+	apiProtected.GET("/sessions", hndl.APIGetSessions)
+	apiProtected.DELETE("/sessions", hndl.APIRevokeOtherSessions)
+	apiProtected.DELETE("/sessions/:id", hndl.APIRevokeSession)
 	address := fmt.Sprintf(":%d", cfg.APIPort)
 	e.Logger.Fatal(e.Start(address))
 }
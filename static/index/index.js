@@ -58,6 +58,28 @@ function fetchDataAndLog(tableBodies) {
     });
 }
 
+function startPriceStream(tableBodies) {
+  if (!window.EventSource) {
+    fetchDataAndLog(tableBodies);
+    setInterval(function () {
+      fetchDataAndLog(tableBodies);
+    }, 1500);
+    return;
+  }
+  // EventSource reconnects by itself and sends Last-Event-ID, so the missed update comes at once
+  var source = new EventSource('/stream/prices');
+  source.addEventListener('prices', function (event) {
+    var data = JSON.parse(event.data);
+    companyData = data;
+    tableBodies.forEach(tableBody => {
+      updateShares(tableBody, data);
+    });
+  });
+  source.onerror = function () {
+    console.error('Price stream is interrupted, reconnecting');
+  };
+}
+
 function updateCompanyList() {
   const companyList = document.getElementById('companyList');
   if (companyList && companyList.children.length === 0) {
@@ -94,14 +116,14 @@ document.addEventListener("DOMContentLoaded", function () {
   modalLong._element.addEventListener('shown.bs.modal', function () {
     if (shouldShowModalTable) {
       modalTable.classList.remove('d-none');
-      fetchDataAndLog([longTable, modalTable, shortTable]);
+      [longTable, modalTable, shortTable].forEach(table => updateShares(table, companyData));
     }
   });
 
   modalShort._element.addEventListener('shown.bs.modal', function () {
     if (shouldShowModalTable) {
       modalTable.classList.remove('d-none');
-      fetchDataAndLog([shortTable, modalTable, longTable]);
+      [shortTable, modalTable, longTable].forEach(table => updateShares(table, companyData));
     }
   });
 
@@ -119,7 +141,7 @@ document.addEventListener("DOMContentLoaded", function () {
   });
 
   var tableBody = document.getElementById('shares-table-body');
-  fetchDataAndLog([tableBody, longTable, shortTable, modalTable]);
+  startPriceStream([tableBody, longTable, shortTable, modalTable]);
 
   document.getElementById('companyLong').addEventListener('click', function() {
     updateCompanyList();
@@ -142,10 +164,6 @@ document.addEventListener("DOMContentLoaded", function () {
       event.target.value = '';
     }
   });
-
-  setInterval(function () {
-    fetchDataAndLog([tableBody, longTable, shortTable, modalTable]);
-  }, 1500);
 });
 
 document.getElementById('openOrdersModal').addEventListener('click', function() {
@@ -156,7 +174,19 @@ document.getElementById('openHistoryModal').addEventListener('click', function()
     fetchClosedPositions(); 
   });
 
-function updateUnclosedPositions(positions) {
+document.getElementById('historyFilterForm').addEventListener('submit', function(event) {
+  event.preventDefault();
+  fetchClosedPositions();
+});
+
+document.getElementById('historyLoadMore').addEventListener('click', function() {
+  fetchClosedPositions(historyCursor);
+});
+
+var historyPageSize = 50;
+var historyCursor = '';
+
+function updateUnclosedPositions(positions, stops) {
     var tableBody = document.getElementById('unclosed-positions-table-body');
     if (positions.length > 0) {
         var newHTML = positions.map(function (position) {
@@ -166,6 +196,7 @@ function updateUnclosedPositions(positions) {
                 '<td>' + (position.company || '') + '</td>' +
                 '<td>' + (position.purchaseprice ? position.purchaseprice + '$' : '') + '</td>' +
                 '<td>' + (position.stoploss ? position.stoploss + '$' : '') + '</td>' +
+                '<td>' + effectiveStop(position, stops) + '</td>' +
                 '<td>' + (position.takeprofit ? position.takeprofit + '$' : '') + '</td>' +
                 '<td>' + (position.dealtime ? formatTimeString(position.dealtime) : '') + '</td>' +
                 '<td><button class="copy-btn" data-dealid="' + (position.dealid || '') + '">Copy ID</button></td>' +
@@ -184,6 +215,32 @@ function updateUnclosedPositions(positions) {
     }
 }
 
+function effectiveStop(position, stops) {
+    var stop = stops[position.dealid];
+    if (stop && (stop.status === 'active' || stop.status === 'closing')) {
+        return stop.effectivestop + '$ (trailing ' + stop.distance + (stop.type === 'percent' ? '%' : '$') + ')';
+    }
+    return position.stoploss ? position.stoploss + '$' : '';
+}
+
+function fetchTrailingStops() {
+  return fetch('/gettrailingstops')
+  .then(response => {
+      if (!response.ok) {
+          console.error('Server returned an error. Status:', response.status);
+          return [];
+      }
+      return response.json();
+  })
+  .then(data => {
+      var stops = {};
+      (data || []).forEach(function (stop) {
+          stops[stop.dealid] = stop;
+      });
+      return stops;
+  });
+}
+
 async function copyToClipboard(text) {
   try {
       await navigator.clipboard.writeText(text);
@@ -206,52 +263,80 @@ function fetchUnclosedPositions() {
       }
       return response.json();
       })
-      .then(data => {
+      .then(data => fetchTrailingStops().then(stops => {
           console.log('Received unclosed positions at', new Date(), ':', data);
-          updateUnclosedPositions(data);
-      })
+          updateUnclosedPositions(data, stops);
+      }))
       .catch(error => {
           console.error('Error updating unclosed positions at', new Date(), ':', error);
       });
 }
 
-function updateClosedPositions(positions) {
+function updateClosedPositions(positions, append) {
     var tableBody = document.getElementById('closed-positions-table-body');
+    if (append) {
+        tableBody.insertAdjacentHTML('beforeend', closedPositionsHTML(positions));
+        return;
+    }
     if (positions.length > 0) {
-        var newHTML = positions.map(function (position) {
-            return '<tr>' +
-                '<td>' + (position.dealid || '') + '</td>' +
-                '<td>' + (position.sharescount || '') + '</td>' +
-                '<td>' + (position.company || '') + '</td>' +
-                '<td>' + (position.purchaseprice ? position.purchaseprice + '$' : '') + '</td>' +
-                '<td>' + (position.stoploss ? position.stoploss + '$' : '') + '</td>' +
-                '<td>' + (position.takeprofit ? position.takeprofit + '$' : '') + '</td>' +
-                '<td>' + (position.dealtime ? formatTimeString(position.dealtime) : '') + '</td>' +
-                '<td>' + (position.profit ? position.profit + '$' : '') + '</td>' +
-                '<td>' + (position.enddealtime ? formatTimeString(position.enddealtime) : '') + '</td>' +
-                '</tr>';
-        }).join('');
-        tableBody.innerHTML = newHTML;
+        tableBody.innerHTML = closedPositionsHTML(positions);
     } else {
         tableBody.innerHTML = '<br><p>History is clear</p>';
     }
 }
 
+function closedPositionsHTML(positions) {
+    return positions.map(function (position) {
+        return '<tr>' +
+            '<td>' + (position.dealid || '') + '</td>' +
+            '<td>' + (position.sharescount || '') + '</td>' +
+            '<td>' + (position.company || '') + '</td>' +
+            '<td>' + (position.purchaseprice ? position.purchaseprice + '$' : '') + '</td>' +
+            '<td>' + (position.stoploss ? position.stoploss + '$' : '') + '</td>' +
+            '<td>' + (position.takeprofit ? position.takeprofit + '$' : '') + '</td>' +
+            '<td>' + (position.dealtime ? formatTimeString(position.dealtime) : '') + '</td>' +
+            '<td>' + (position.profit ? position.profit + '$' : '') + '</td>' +
+            '<td>' + (position.enddealtime ? formatTimeString(position.enddealtime) : '') + '</td>' +
+            '</tr>';
+    }).join('');
+}
+
+
+function closedPositionsQuery(cursor) {
+  var params = new URLSearchParams({
+    sort: document.getElementById('historySort').value,
+    limit: historyPageSize
+  });
+  var company = document.getElementById('historyCompany').value;
+  if (company) {
+    params.set('company', company);
+  }
+  var profit = document.getElementById('historyProfit').value;
+  if (profit) {
+    params.set('profit', profit);
+  }
+  if (cursor) {
+    params.set('cursor', cursor);
+  }
+  return params.toString();
+}
 
-function fetchClosedPositions() {
+function fetchClosedPositions(cursor) {
   var currentTime = new Date();
   console.log('Fetching closed positions at', currentTime);
-  fetch('/getclosed')
+  fetch('/getclosed?' + closedPositionsQuery(cursor))
   .then(response => {
       if (!response.ok) {
           console.error('Server returned an error. Status:', response.status);
           throw new Error('Network response was not ok');
       }
+      historyCursor = response.headers.get('X-Next-Cursor') || '';
+      document.getElementById('historyLoadMore').classList.toggle('d-none', !historyCursor);
       return response.json();
       })
       .then(data => {
           console.log('Received closed positions at', new Date(), ':', data);
-          updateClosedPositions(data);
+          updateClosedPositions(data, Boolean(cursor));
       })
       .catch(error => {
           console.error('Error updating closed positions at', new Date(), ':', error);